@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/playback"
+	"backend/internal/storage"
+)
+
+// runCast writes the replay of a question as an asciinema cast file
+func runCast(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("cast", flag.ExitOnError)
+	driver := fs.String("driver", cfg.DB.Driver, "Storage driver: sqlite or postgres")
+	dsn := fs.String("db", cfg.DB.DSN(), "SQLite database file path or PostgreSQL URL")
+	revision := fs.Int("revision", 0, "Revision to replay (default the current one)")
+	output := fs.String("o", "", "Output file, - for stdout (default <examId>_<studentId>_<questionKey>.cast)")
+	cols := fs.Int("cols", playback.DefaultCols, "Terminal width in columns")
+	rows := fs.Int("rows", playback.DefaultRows, "Terminal height in rows")
+	maxPause := fs.Duration("max-pause", 0, "Shorten longer pauses to this, such as 3s (default keep them)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: drkka cast [flags] <examId> <studentId> <questionKey>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 3 {
+		fs.Usage()
+		return fmt.Errorf("needs an exam id, a student id and a question key")
+	}
+	examID, studentID, key := fs.Arg(0), fs.Arg(1), fs.Arg(2)
+	if err := playback.CheckSize(*cols, *rows); err != nil {
+		return err
+	}
+
+	store, err := storage.Open(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	sub, question, err := loadQuestion(store, examID, studentID, key, *revision)
+	if err != nil {
+		return err
+	}
+
+	path := *output
+	if path == "" {
+		path = playback.FileName(examID, studentID, question.Key, "cast")
+	}
+	var out io.Writer = os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	buffered := bufio.NewWriter(out)
+
+	err = playback.WriteCast(buffered, question.EventLog, playback.CastOptions{
+		Title:      fmt.Sprintf("%s / %s (%s) / %s", examID, studentID, sub.StudentName(), question.Key),
+		Width:      *cols,
+		Height:     *rows,
+		MaxPauseMs: float64(*maxPause) / float64(time.Millisecond),
+		Timestamp:  sub.SubmissionTime,
+	})
+	if err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	if path != "-" {
+		fmt.Printf("✅ Wrote %s; play it with: asciinema play %s\n", path, path)
+	}
+	return nil
+}
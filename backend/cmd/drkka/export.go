@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"backend/internal/config"
+	"backend/internal/export"
+	"backend/internal/storage"
+)
+
+// runExport writes the current submissions of an exam to a file or stdout
+func runExport(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	driver := fs.String("driver", cfg.DB.Driver, "Storage driver: sqlite or postgres")
+	dsn := fs.String("db", cfg.DB.DSN(), "SQLite database file path or PostgreSQL URL")
+	formatName := fs.String("format", string(export.FormatJSON), "Output format: json, ndjson or csv (one row per answer, with marks)")
+	output := fs.String("o", "", "Output file (default stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: drkka export [flags] <examId>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("needs exactly one exam id")
+	}
+	examID := fs.Arg(0)
+	format, err := export.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	store, err := storage.Open(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	exam, err := export.OpenExam(store, examID)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	destination := "stdout"
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out, destination = file, *output
+	}
+	buffered := bufio.NewWriter(out)
+	exported, err := exam.Write(buffered, format)
+	if err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "✅ Exported %d submissions of exam %s to %s (%s)\n", exported, examID, destination, format)
+	return nil
+}
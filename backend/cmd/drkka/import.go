@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/ingest"
+	"backend/internal/storage"
+	"backend/internal/suspicion"
+)
+
+// runImport stores submission payloads from files as new revisions, after
+// the checks POST /submit applies
+func runImport(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	driver := fs.String("driver", cfg.DB.Driver, "Storage driver: sqlite or postgres")
+	dsn := fs.String("db", cfg.DB.DSN(), "SQLite database file path or PostgreSQL URL")
+	integrityMode := fs.String("integrity", cfg.Integrity.Mode, "flag to store integrity verdicts, reject to skip payloads whose event logs do not reproduce their answers")
+	rulesFile := fs.String("rules", cfg.Suspicion.RulesFile, "Suspicion rules file; answers are not scored when it does not exist")
+	dryRun := fs.Bool("dry-run", false, "Check the payloads without storing them")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: drkka import [flags] <file>...")
+		fmt.Fprintln(os.Stderr, "Each file holds one payload, a JSON array of payloads or one payload per line, as written by drkka export.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("needs at least one file")
+	}
+	if *integrityMode != "flag" && *integrityMode != ingest.IntegrityReject {
+		return fmt.Errorf("integrity must be flag or %s", ingest.IntegrityReject)
+	}
+
+	rules, err := suspicion.Load(*rulesFile)
+	if os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "⚠️  %s not found: imported answers are not scored for suspicion\n", *rulesFile)
+	} else if err != nil {
+		return err
+	}
+
+	store, err := storage.Open(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	imported, rejected := 0, 0
+	for _, path := range fs.Args() {
+		err := readPayloads(path, func(n int, submission *storage.Submission, err error) error {
+			if err == nil {
+				err = ingestPayload(store, submission, *integrityMode, rules, *dryRun)
+			}
+			if err != nil {
+				fmt.Printf("❌ %s #%d: %v\n", path, n, err)
+				rejected++
+				return nil
+			}
+			if *dryRun {
+				fmt.Printf("✅ %s #%d: exam=%s, student=%s\n", path, n, submission.ExamID, submission.StudentID)
+			} else {
+				fmt.Printf("✅ %s #%d: exam=%s, student=%s, revision=%d\n", path, n, submission.ExamID, submission.StudentID, submission.Revision)
+			}
+			imported++
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	verb := "Imported"
+	if *dryRun {
+		verb = "Checked"
+	}
+	fmt.Printf("%s %d submissions, rejected %d\n", verb, imported, rejected)
+	if rejected > 0 {
+		return fmt.Errorf("%d payloads rejected", rejected)
+	}
+	return nil
+}
+
+// ingestPayload checks a decoded payload like POST /submit and, unless
+// dryRun, stores it. Imports are not tied to an exam session, and the
+// exam's time window and attempt limits are not applied.
+func ingestPayload(store storage.Store, submission *storage.Submission, integrityMode string, rules *suspicion.Rules, dryRun bool) error {
+	if err := ingest.Validate(submission); err != nil {
+		return err
+	}
+	if err := ingest.Check(submission, integrityMode, rules); err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+	submission.ReceivedAt = time.Now().UTC()
+	return store.SaveSubmission(submission)
+}
+
+// readPayloads decodes the payloads of a file one at a time, numbered
+// from 1. A payload that does not decode is passed on with its error; a
+// file that is not JSON at all fails.
+func readPayloads(path string, fn func(n int, submission *storage.Submission, err error) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	array, err := startsArray(reader)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(reader)
+	if array {
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+	}
+
+	for n := 1; !array || decoder.More(); n++ {
+		var submission storage.Submission
+		err := decoder.Decode(&submission)
+		if err == io.EOF {
+			return nil
+		}
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("payload #%d: %w", n, err)
+		}
+		if err := fn(n, &submission, err); err != nil {
+			return err
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return fmt.Errorf("unterminated JSON array: %w", err)
+	}
+	return nil
+}
+
+// startsArray reports whether the first value of reader is a JSON array
+func startsArray(reader *bufio.Reader) (bool, error) {
+	for {
+		b, err := reader.Peek(1)
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			reader.ReadByte()
+		default:
+			return b[0] == '[', nil
+		}
+	}
+}
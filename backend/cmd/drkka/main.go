@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"backend/internal/config"
+)
+
+// command is a drkka subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(cfg *config.Config, args []string) error
+}
+
+// commands lists the available subcommands in the order shown by help
+var commands = []command{
+	{name: "migrate", summary: "Apply pending database migrations (-status to list them)", run: runMigrate},
+	{name: "user", summary: "Add, list, update or delete the accounts that may sign in", run: runUser},
+	{name: "questions", summary: "Import, list, retire or restore questions of the question bank", run: runQuestions},
+	{name: "export", summary: "Write the submissions of an exam as JSON, NDJSON or CSV", run: runExport},
+	{name: "import", summary: "Store submission payloads from files, checked like POST /submit", run: runImport},
+	{name: "replay", summary: "Print an answer as it was at any point of its event log", run: runReplay},
+	{name: "cast", summary: "Write the replay of an answer as an asciinema cast file", run: runCast},
+	{name: "render", summary: "Draw the replay of an answer as an animated GIF or PNG frames", run: runRender},
+	{name: "stats", summary: "Count submissions, verdicts, pastes, marks and flags per exam", run: runStats},
+	{name: "purge", summary: "Delete the submissions of an exam or made before a date", run: runPurge},
+	{name: "similarity", summary: "Rank the students of an exam whose answers or pastes look alike", run: runSimilarity},
+	{name: "rescore", summary: "Score current submissions again with the suspicion rules", run: runRescore},
+}
+
+func main() {
+	// Load configuration (same environment variables as the server)
+	cfg := config.Load()
+
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		usage()
+		if len(os.Args) < 2 {
+			os.Exit(2)
+		}
+		return
+	}
+
+	name := os.Args[1]
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		if err := cmd.run(cfg, os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "❌ Unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+// usage prints the list of subcommands
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: drkka <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'drkka <command> -h' for the flags of a command.")
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"backend/internal/config"
+	"backend/internal/storage"
+)
+
+// runMigrate applies pending migrations or, with -status, lists them
+func runMigrate(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	driver := fs.String("driver", cfg.DB.Driver, "Storage driver: sqlite or postgres")
+	dsn := fs.String("db", cfg.DB.DSN(), "SQLite database file path or PostgreSQL URL")
+	status := fs.Bool("status", false, "List migrations without applying them")
+	fs.Parse(args)
+
+	store, err := storage.Open(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if *status {
+		statuses, err := store.Migrations()
+		if err != nil {
+			return err
+		}
+		for _, m := range statuses {
+			if m.Applied {
+				fmt.Printf("✅ %3d  %-45s applied %s\n", m.Version, m.Name, m.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Printf("⏳ %3d  %-45s pending\n", m.Version, m.Name)
+			}
+		}
+		return nil
+	}
+
+	applied, err := store.Migrate()
+	for _, m := range applied {
+		fmt.Printf("✅ Applied migration %d: %s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		fmt.Println("✅ Database is up to date")
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/storage"
+)
+
+// runPurge deletes the submissions of an exam or submitted before a date,
+// with all their revisions and marks
+func runPurge(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ExitOnError)
+	driver := fs.String("driver", cfg.DB.Driver, "Storage driver: sqlite or postgres")
+	dsn := fs.String("db", cfg.DB.DSN(), "SQLite database file path or PostgreSQL URL")
+	examID := fs.String("exam", "", "Delete the submissions of this exam")
+	studentID := fs.String("student", "", "Only delete the submissions of this student")
+	before := fs.String("before", "", "Delete the submissions made before this RFC 3339 time or YYYY-MM-DD date (UTC)")
+	yes := fs.Bool("yes", false, "Delete; without it the submissions are only listed")
+	fs.Parse(args)
+
+	if *examID == "" && *before == "" {
+		return fmt.Errorf("needs -exam or -before")
+	}
+	filter := storage.SubmissionFilter{ExamID: *examID, StudentID: *studentID}
+	if *before != "" {
+		t, err := parseTime(*before)
+		if err != nil {
+			return fmt.Errorf("before must be an RFC 3339 time or a YYYY-MM-DD date")
+		}
+		filter.SubmittedTo = t
+	}
+
+	store, err := storage.Open(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	summaries, _, err := store.ListSummaries(filter, storage.Page{Sort: storage.SortExamID})
+	if err != nil {
+		return err
+	}
+
+	if !*yes {
+		for _, summary := range summaries {
+			fmt.Printf("%-24s %-36s %d revisions, submitted %s\n", summary.ExamID, summary.StudentID,
+				summary.Revision, summary.SubmissionTime.UTC().Format("2006-01-02 15:04:05"))
+		}
+		fmt.Printf("⚠️  %d submissions would be deleted; run again with -yes to delete them\n", len(summaries))
+		return nil
+	}
+
+	for _, summary := range summaries {
+		if err := store.DeleteSubmission(summary.ExamID, summary.StudentID); err != nil {
+			return fmt.Errorf("%s/%s: %w", summary.ExamID, summary.StudentID, err)
+		}
+	}
+	fmt.Printf("✅ Deleted %d submissions\n", len(summaries))
+	return nil
+}
+
+// parseTime reads an RFC 3339 timestamp or a YYYY-MM-DD date
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
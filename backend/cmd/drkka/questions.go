@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"backend/internal/config"
+	"backend/internal/questionbank"
+	"backend/internal/storage"
+)
+
+// runQuestions manages the question bank exam sessions are assigned from
+func runQuestions(cfg *config.Config, args []string) error {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: drkka questions <action> [flags] [file or id]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Actions:")
+		fmt.Fprintln(os.Stderr, "  import   Import a questions.json file; changed questions get a new version")
+		fmt.Fprintln(os.Stderr, "  list     List the current version of every question")
+		fmt.Fprintln(os.Stderr, "  show     Print every version of a question")
+		fmt.Fprintln(os.Stderr, "  retire   Stop assigning a question to new exam sessions")
+		fmt.Fprintln(os.Stderr, "  restore  Assign a retired question again")
+	}
+	if len(args) == 0 {
+		usage()
+		return errors.New("missing action")
+	}
+
+	action := args[0]
+	fs := flag.NewFlagSet("questions "+action, flag.ExitOnError)
+	driver := fs.String("driver", cfg.DB.Driver, "Storage driver: sqlite or postgres")
+	dsn := fs.String("db", cfg.DB.DSN(), "SQLite database file path or PostgreSQL URL")
+	fs.Parse(args[1:])
+
+	if action != "list" && fs.NArg() != 1 {
+		return fmt.Errorf("%s needs exactly one argument", action)
+	}
+
+	// Every action but import takes a question ID
+	var id int
+	if action != "list" && action != "import" {
+		n, err := strconv.Atoi(fs.Arg(0))
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid question id %q", fs.Arg(0))
+		}
+		id = n
+	}
+
+	store, err := storage.Open(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	switch action {
+	case "import":
+		data, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		questions, err := questionbank.Parse(data)
+		if err != nil {
+			return err
+		}
+		result, err := questionbank.Import(store, questions)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ Imported %s: %d added, %d updated, %d unchanged\n", fs.Arg(0), result.Added, result.Updated, result.Unchanged)
+
+	case "list":
+		questions, err := store.ListQuestions()
+		if err != nil {
+			return err
+		}
+		for _, q := range questions {
+			state := "active"
+			if !q.Active {
+				state = "retired"
+			}
+			fmt.Printf("%4d  v%-3d %-8s %s\n", q.ID, q.Version, state, q.Title)
+		}
+
+	case "show":
+		versions, err := store.QuestionVersions(id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("no question %d", id)
+		}
+		if err != nil {
+			return err
+		}
+		for _, q := range versions {
+			fmt.Printf("v%d  %s  %s\n%s\n\n", q.Version, q.CreatedAt.Format("2006-01-02 15:04:05"), q.Title, q.Text)
+		}
+
+	case "retire", "restore":
+		if err := store.SetQuestionActive(id, action == "restore"); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return fmt.Errorf("no question %d", id)
+			}
+			return err
+		}
+		if action == "retire" {
+			fmt.Printf("✅ Retired question %d\n", id)
+		} else {
+			fmt.Printf("✅ Restored question %d\n", id)
+		}
+
+	default:
+		usage()
+		return fmt.Errorf("unknown action %q", action)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/playback"
+	"backend/internal/render"
+	"backend/internal/storage"
+)
+
+// runRender draws the replay of a question as an animated GIF, a PNG still
+// or a directory of numbered PNG frames
+func runRender(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	driver := fs.String("driver", cfg.DB.Driver, "Storage driver: sqlite or postgres")
+	dsn := fs.String("db", cfg.DB.DSN(), "SQLite database file path or PostgreSQL URL")
+	revision := fs.Int("revision", 0, "Revision to render (default the current one)")
+	format := fs.String("format", "gif", "Output format: gif, or png for a still at -at")
+	output := fs.String("o", "", "Output file, - for stdout (default <examId>_<studentId>_<questionKey>.<format>)")
+	frames := fs.String("frames", "", "Write every frame as a numbered PNG into this directory instead")
+	width := fs.Int("width", render.DefaultWidth, "Image width in pixels")
+	height := fs.Int("height", render.DefaultHeight, "Image height in pixels")
+	speed := fs.Float64("speed", 1, "Animation speed; 1 is real time")
+	maxPause := fs.Duration("max-pause", 0, "Shorten longer pauses to this, such as 3s (default keep them)")
+	at := fs.String("at", "", "Time of the png still since the first event, such as 90s (default the end)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: drkka render [flags] <examId> <studentId> <questionKey>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 3 {
+		fs.Usage()
+		return fmt.Errorf("needs an exam id, a student id and a question key")
+	}
+	examID, studentID, key := fs.Arg(0), fs.Arg(1), fs.Arg(2)
+	if *format != "gif" && *format != "png" {
+		return fmt.Errorf("unknown format %q, expected gif or png", *format)
+	}
+	if err := render.CheckSize(*width, *height); err != nil {
+		return err
+	}
+	if *speed <= 0 {
+		return fmt.Errorf("speed must be positive")
+	}
+	atMs := math.Inf(1)
+	if *at != "" {
+		d, err := time.ParseDuration(*at)
+		if err != nil || d < 0 {
+			return fmt.Errorf("at must be a non-negative duration such as 90s")
+		}
+		atMs = float64(d) / float64(time.Millisecond)
+	}
+
+	store, err := storage.Open(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	sub, question, err := loadQuestion(store, examID, studentID, key, *revision)
+	if err != nil {
+		return err
+	}
+	opts := render.Options{
+		Title:      fmt.Sprintf("%s / %s (%s) / %s", examID, studentID, sub.StudentName(), question.Key),
+		Width:      *width,
+		Height:     *height,
+		MaxPauseMs: float64(*maxPause) / float64(time.Millisecond),
+		Speed:      *speed,
+	}
+
+	if *frames != "" {
+		return writeFrames(*frames, question, opts)
+	}
+
+	path := *output
+	if path == "" {
+		path = playback.FileName(examID, studentID, question.Key, *format)
+	}
+	var out io.Writer = os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	buffered := bufio.NewWriter(out)
+
+	if *format == "png" {
+		err = render.PNG(buffered, question.EventLog, atMs, opts)
+	} else {
+		err = render.GIF(buffered, question.EventLog, opts)
+	}
+	if err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	if path != "-" {
+		fmt.Printf("✅ Wrote %s\n", path)
+	}
+	return nil
+}
+
+// writeFrames writes the frames of the animation of a question as
+// frame-00001.png and so on, listing their durations in frames.txt
+func writeFrames(dir string, question *storage.Question, opts render.Options) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	index, err := os.Create(filepath.Join(dir, "frames.txt"))
+	if err != nil {
+		return err
+	}
+	defer index.Close()
+
+	count := 0
+	err = render.Frames(question.EventLog, opts, func(frame render.Frame) error {
+		count++
+		name := fmt.Sprintf("frame-%05d.png", count)
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		if err := png.Encode(file, frame.Image); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(index, "%s %.0f\n", name, frame.DelayMs)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Wrote %d frames to %s, with their durations in milliseconds in frames.txt\n", count, dir)
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/eventlog"
+	"backend/internal/playback"
+	"backend/internal/storage"
+
+	"golang.org/x/term"
+)
+
+// runReplay prints an answer as it was at a point in time of its event
+// log, plays it back in the terminal or prints a transcript of its edits
+func runReplay(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	driver := fs.String("driver", cfg.DB.Driver, "Storage driver: sqlite or postgres")
+	dsn := fs.String("db", cfg.DB.DSN(), "SQLite database file path or PostgreSQL URL")
+	at := fs.String("at", "", "Time since the first event, such as 90s or 2m15.5s (default the end)")
+	revision := fs.Int("revision", 0, "Revision to replay (default the current one)")
+	play := fs.Bool("play", false, "Play the answer back in the terminal as it was typed, from -at or the start")
+	speed := fs.Float64("speed", 1, "Playback speed of -play; 2 plays twice as fast")
+	maxPause := fs.Duration("max-pause", 0, "Shorten longer pauses to this during -play, such as 3s (default keep them)")
+	transcript := fs.Bool("transcript", false, "Print a timestamped transcript of the edits instead of the text")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: drkka replay [flags] <examId> <studentId> <questionKey>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 3 {
+		fs.Usage()
+		return fmt.Errorf("needs an exam id, a student id and a question key")
+	}
+	examID, studentID, key := fs.Arg(0), fs.Arg(1), fs.Arg(2)
+	if *play && *transcript {
+		return fmt.Errorf("-play and -transcript cannot be combined")
+	}
+	if *speed <= 0 {
+		return fmt.Errorf("speed must be above 0")
+	}
+
+	store, err := storage.Open(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	_, question, err := loadQuestion(store, examID, studentID, key, *revision)
+	if err != nil {
+		return err
+	}
+
+	if *transcript {
+		return playback.WriteTranscript(os.Stdout, question.EventLog)
+	}
+
+	replayer := eventlog.NewReplayer(question.EventLog)
+	actions := replayer.Actions()
+	endMs := 0.0
+	if len(actions) > 0 {
+		endMs = actions[len(actions)-1].At
+	}
+	atMs := endMs
+	if *play {
+		atMs = 0
+	}
+	if *at != "" {
+		d, err := time.ParseDuration(*at)
+		if err != nil || d < 0 {
+			return fmt.Errorf("at must be a non-negative duration such as 90s")
+		}
+		atMs = float64(d) / float64(time.Millisecond)
+	}
+
+	if *play {
+		return playInTerminal(question.EventLog, playback.Options{
+			Title:      fmt.Sprintf("%s/%s %s", examID, studentID, question.Key),
+			Speed:      *speed,
+			MaxPauseMs: float64(*maxPause) / float64(time.Millisecond),
+			StartMs:    atMs,
+		})
+	}
+
+	replayer.SeekTime(atMs)
+
+	fmt.Fprintf(os.Stderr, "⏱️  %s/%s %s at %s of %s: %d of %d actions\n", examID, studentID, question.Key,
+		formatMs(atMs), formatMs(endMs), replayer.Position(), len(actions))
+	text := replayer.Buffer().Text()
+	fmt.Print(text)
+	if !strings.HasSuffix(text, "\n") {
+		fmt.Println()
+	}
+	return nil
+}
+
+// playInTerminal plays events back with the terminal in raw mode, so that
+// keys act without Enter
+func playInTerminal(events []eventlog.Event, opts playback.Options) error {
+	in, out := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(in) || !term.IsTerminal(out) {
+		return fmt.Errorf("-play needs a terminal; use -transcript to print the edits")
+	}
+	if _, height, err := term.GetSize(out); err == nil {
+		opts.Height = height
+	}
+
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(in, state)
+
+	return playback.NewPlayer(events, os.Stdout, opts).Run(playback.ReadKeys(os.Stdin))
+}
+
+// loadQuestion reads a question of a student's current submission, or of
+// one of its revisions when revision is not 0, and the submission
+func loadQuestion(store storage.Store, examID, studentID, key string, revision int) (*storage.Submission, *storage.Question, error) {
+	var sub *storage.Submission
+	var err error
+	if revision > 0 {
+		_, sub, err = store.GetRevision(examID, studentID, revision)
+	} else {
+		sub, err = store.GetSubmission(examID, studentID)
+	}
+	if errors.Is(err, storage.ErrNotFound) && revision > 0 {
+		return nil, nil, fmt.Errorf("no revision %d of the submission of student %s for exam %s", revision, studentID, examID)
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, fmt.Errorf("no submission of student %s for exam %s", studentID, examID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	question := sub.Question(key)
+	if question == nil {
+		keys := make([]string, len(sub.Questions))
+		for i, q := range sub.Questions {
+			keys[i] = q.Key
+		}
+		return nil, nil, fmt.Errorf("no question %s, expected one of %s", key, strings.Join(keys, ", "))
+	}
+	return sub, question, nil
+}
+
+// formatMs writes milliseconds as a duration rounded to the tenth of a second
+func formatMs(ms float64) string {
+	return time.Duration(ms * float64(time.Millisecond)).Round(100 * time.Millisecond).String()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"backend/internal/config"
+	"backend/internal/storage"
+	"backend/internal/suspicion"
+)
+
+// runRescore scores the current submissions again, after the suspicion
+// rules changed
+func runRescore(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("rescore", flag.ExitOnError)
+	driver := fs.String("driver", cfg.DB.Driver, "Storage driver: sqlite or postgres")
+	dsn := fs.String("db", cfg.DB.DSN(), "SQLite database file path or PostgreSQL URL")
+	rulesFile := fs.String("rules", cfg.Suspicion.RulesFile, "Suspicion rules file")
+	examID := fs.String("exam", "", "Only rescore the submissions of this exam")
+	fs.Parse(args)
+
+	rules, err := suspicion.Load(*rulesFile)
+	if err != nil {
+		return err
+	}
+
+	store, err := storage.Open(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	subs, _, err := store.ListSubmissions(storage.SubmissionFilter{ExamID: *examID}, storage.Page{})
+	if err != nil {
+		return err
+	}
+
+	flagged := 0
+	for _, sub := range subs {
+		suspicions := make(map[string]storage.Suspicion, len(sub.Questions))
+		for i := range sub.Questions {
+			question := &sub.Questions[i]
+			suspicions[question.Key] = rules.Score(question)
+			if len(suspicions[question.Key].Rules) > 0 {
+				flagged++
+			}
+		}
+		if err := store.SaveSuspicion(sub.ExamID, sub.StudentID, suspicions); err != nil {
+			return fmt.Errorf("%s/%s: %w", sub.ExamID, sub.StudentID, err)
+		}
+	}
+	fmt.Printf("✅ Rescored %d submissions with %d rules: %d answers flagged\n", len(subs), len(rules.Rules), flagged)
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"backend/internal/config"
+	"backend/internal/similarity"
+	"backend/internal/storage"
+)
+
+// runSimilarity ranks the pairs of students of an exam whose answers or
+// pastes look alike
+func runSimilarity(cfg *config.Config, args []string) error {
+	opts := similarity.DefaultOptions()
+	fs := flag.NewFlagSet("similarity", flag.ExitOnError)
+	driver := fs.String("driver", cfg.DB.Driver, "Storage driver: sqlite or postgres")
+	dsn := fs.String("db", cfg.DB.DSN(), "SQLite database file path or PostgreSQL URL")
+	threshold := fs.Float64("threshold", opts.Threshold, "Lowest score (0 to 1) of a reported pair")
+	limit := fs.Int("limit", 20, "Number of pairs to print, 0 for all")
+	asJSON := fs.Bool("json", false, "Print the report as JSON, as served by GET /exams/{examId}/similarity")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: drkka similarity [flags] <examId>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("needs exactly one exam id")
+	}
+	if *threshold <= 0 || *threshold > 1 {
+		return fmt.Errorf("threshold must be above 0 and at most 1")
+	}
+	opts.Threshold = *threshold
+	examID := fs.Arg(0)
+
+	store, err := storage.Open(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	subs, _, err := store.ListSubmissions(storage.SubmissionFilter{ExamID: examID}, storage.Page{})
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return fmt.Errorf("no submissions for exam %s", examID)
+	}
+
+	report := similarity.Analyze(examID, subs, opts)
+	total := len(report.Pairs)
+	if *limit > 0 && total > *limit {
+		report.Pairs = report.Pairs[:*limit]
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	fmt.Printf("🔍 Exam %s: %d submissions compared, %d pairs scored %.2f or more\n", examID, report.Submissions, total, opts.Threshold)
+	for i, pair := range report.Pairs {
+		fmt.Printf("\n%3d. %s ↔ %s  score %.2f\n", i+1, pair.StudentA, pair.StudentB, pair.Score)
+		for _, match := range pair.Answers {
+			fmt.Printf("     answer %s / %s (question %d): %.0f%% similar\n", match.KeyA, match.KeyB, match.QuestionIndex, match.Similarity*100)
+			for _, fragment := range match.Fragments {
+				fmt.Printf("       A: %s\n", oneLine(fragment.A))
+				fmt.Printf("       B: %s\n", oneLine(fragment.B))
+			}
+		}
+		for _, match := range pair.Pastes {
+			fmt.Printf("     paste in %s / %s: %.0f%% shared\n", match.KeyA, match.KeyB, match.Containment*100)
+			fmt.Printf("       A: %s\n", oneLine(match.ContentA))
+			fmt.Printf("       B: %s\n", oneLine(match.ContentB))
+		}
+	}
+	if len(report.Pairs) < total {
+		fmt.Printf("\n… %d more pairs; use -limit 0 to print all of them\n", total-len(report.Pairs))
+	}
+	return nil
+}
+
+// oneLine collapses a passage onto one line for the terminal
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+
+	"backend/internal/config"
+	"backend/internal/eventlog"
+	"backend/internal/storage"
+)
+
+// examStats counts the current submissions of one exam
+type examStats struct {
+	examID       string
+	submissions  int
+	attempts     int
+	answers      int
+	mismatch     int
+	unreplayable int
+	pasted       int
+	marked       int
+	flagged      int
+	timing       int
+}
+
+// add counts a submission summary
+func (e *examStats) add(summary *storage.Summary) {
+	e.submissions++
+	e.attempts += summary.Revision
+	e.answers += len(summary.Stats)
+	for _, integrity := range summary.Integrity {
+		switch integrity.Verdict {
+		case eventlog.VerdictMismatch:
+			e.mismatch++
+		case eventlog.VerdictUnreplayable:
+			e.unreplayable++
+		}
+	}
+	for _, stats := range summary.Stats {
+		if stats.PasteUsed() {
+			e.pasted++
+		}
+	}
+	e.marked += len(summary.Marks)
+	for _, suspicion := range summary.Suspicion {
+		if suspicion.Score > 0 {
+			e.flagged++
+		}
+	}
+	if len(summary.TimingFlags) > 0 {
+		e.timing++
+	}
+}
+
+// runStats prints the submission counts of each exam
+func runStats(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	driver := fs.String("driver", cfg.DB.Driver, "Storage driver: sqlite or postgres")
+	dsn := fs.String("db", cfg.DB.DSN(), "SQLite database file path or PostgreSQL URL")
+	examID := fs.String("exam", "", "Only count the submissions of this exam")
+	fs.Parse(args)
+
+	store, err := storage.Open(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	summaries, _, err := store.ListSummaries(storage.SubmissionFilter{ExamID: *examID}, storage.Page{})
+	if err != nil {
+		return err
+	}
+
+	byExam := make(map[string]*examStats)
+	total := &examStats{examID: "total"}
+	for i := range summaries {
+		summary := &summaries[i]
+		exam := byExam[summary.ExamID]
+		if exam == nil {
+			exam = &examStats{examID: summary.ExamID}
+			byExam[summary.ExamID] = exam
+		}
+		exam.add(summary)
+		total.add(summary)
+	}
+	exams := make([]*examStats, 0, len(byExam))
+	for _, exam := range byExam {
+		exams = append(exams, exam)
+	}
+	sort.Slice(exams, func(i, j int) bool { return exams[i].examID < exams[j].examID })
+
+	fmt.Printf("📊 %d submissions of %d exams\n\n", total.submissions, len(exams))
+	if len(exams) == 0 {
+		return nil
+	}
+	fmt.Printf("%-24s %11s %8s %7s %8s %12s %6s %6s %7s %6s\n",
+		"exam", "submissions", "attempts", "answers", "mismatch", "unreplayable", "pasted", "marked", "flagged", "timing")
+	for _, exam := range append(exams, total) {
+		fmt.Printf("%-24s %11d %8d %7d %8d %12d %6d %6d %7d %6d\n", exam.examID, exam.submissions, exam.attempts,
+			exam.answers, exam.mismatch, exam.unreplayable, exam.pasted, exam.marked, exam.flagged, exam.timing)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"backend/internal/config"
+	"backend/internal/middleware"
+	"backend/internal/storage"
+)
+
+// runUser manages the accounts that may sign in to the server
+func runUser(cfg *config.Config, args []string) error {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: drkka user <action> [flags] [username]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Actions:")
+		fmt.Fprintln(os.Stderr, "  add      Create a user (-role admin, evaluator or student)")
+		fmt.Fprintln(os.Stderr, "  list     List users")
+		fmt.Fprintln(os.Stderr, "  passwd   Set a user's password and sign it out")
+		fmt.Fprintln(os.Stderr, "  role     Change a user's role (-role) and sign it out")
+		fmt.Fprintln(os.Stderr, "  delete   Delete a user")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Passwords are read from standard input.")
+	}
+	if len(args) == 0 {
+		usage()
+		return errors.New("missing action")
+	}
+
+	action := args[0]
+	switch action {
+	case "-h", "-help", "--help", "help":
+		usage()
+		return nil
+	case "add", "list", "passwd", "role", "delete":
+	default:
+		usage()
+		return fmt.Errorf("unknown action %q", action)
+	}
+
+	fs := flag.NewFlagSet("user "+action, flag.ExitOnError)
+	driver := fs.String("driver", cfg.DB.Driver, "Storage driver: sqlite or postgres")
+	dsn := fs.String("db", cfg.DB.DSN(), "SQLite database file path or PostgreSQL URL")
+	role := fs.String("role", string(storage.RoleEvaluator), "Role: admin, evaluator or student")
+	fs.Parse(args[1:])
+
+	needsName := action != "list"
+	if needsName && fs.NArg() != 1 {
+		return fmt.Errorf("%s needs exactly one username", action)
+	}
+	username := fs.Arg(0)
+
+	store, err := storage.Open(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	// Like the server, bring the database up to date first unless
+	// DB_AUTO_MIGRATE leaves that to drkka migrate
+	if cfg.DB.AutoMigrate {
+		applied, err := store.Migrate()
+		for _, m := range applied {
+			fmt.Printf("🗄️  Applied migration %d: %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		statuses, err := store.Migrations()
+		if err != nil {
+			return err
+		}
+		for _, m := range statuses {
+			if !m.Applied {
+				return fmt.Errorf("migration %d (%s) is pending; run drkka migrate first", m.Version, m.Name)
+			}
+		}
+	}
+
+	switch action {
+	case "add":
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+		user, err := middleware.NewUser(username, password, storage.Role(*role))
+		if err != nil {
+			return err
+		}
+		if err := store.CreateUser(user); err != nil {
+			if errors.Is(err, storage.ErrExists) {
+				return fmt.Errorf("user %s already exists", username)
+			}
+			return err
+		}
+		fmt.Printf("✅ Created %s %s\n", user.Role, user.Username)
+
+	case "list":
+		users, err := store.ListUsers()
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			fmt.Printf("%-32s %-10s created %s\n", user.Username, user.Role, user.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+
+	case "passwd", "role":
+		user, err := store.GetUser(username)
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("no user %s", username)
+		}
+		if err != nil {
+			return err
+		}
+		if action == "passwd" {
+			password, err := readPassword()
+			if err != nil {
+				return err
+			}
+			if err := middleware.SetPassword(user, password); err != nil {
+				return err
+			}
+		} else {
+			user.Role = storage.Role(*role)
+			if !user.Role.Valid() {
+				return fmt.Errorf("role must be one of %s, %s, %s", storage.RoleAdmin, storage.RoleEvaluator, storage.RoleStudent)
+			}
+		}
+		if err := store.UpdateUser(user); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Updated %s %s\n", user.Role, user.Username)
+
+	case "delete":
+		if err := store.DeleteUser(username); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return fmt.Errorf("no user %s", username)
+			}
+			return err
+		}
+		fmt.Printf("✅ Deleted %s\n", username)
+	}
+	return nil
+}
+
+// readPassword reads a password from the first line of standard input
+func readPassword() (string, error) {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, "Password (shown as typed): ")
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -4,46 +4,106 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"backend/internal/config"
+	"backend/internal/examsession"
 	"backend/internal/handlers"
 	"backend/internal/middleware"
+	"backend/internal/proctor"
+	"backend/internal/questionbank"
 	"backend/internal/storage"
+	"backend/internal/suspicion"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
 
-	// Initialize SQLite storage
-	store, err := storage.NewSQLiteStorage(cfg.DB.Path)
+	// Initialize storage
+	store, err := openStorage(&cfg.DB)
 	if err != nil {
 		log.Fatalf("❌ Failed to initialize database: %v", err)
 	}
 	defer store.Close()
 
-	log.Printf("✅ Database initialized: %s", cfg.DB.Path)
+	log.Printf("✅ Database initialized: %s", describeDB(&cfg.DB))
+	checkAuth(&cfg.Auth, store)
+
+	// Exam sessions: the signing key and the question bank they are
+	// assigned from
+	signer, err := examSigner(&cfg.Exam)
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize exam sessions: %v", err)
+	}
+	if !questionbank.Mode(cfg.Exam.Assignment).Valid() {
+		log.Fatalf("❌ Unknown QUESTION_ASSIGNMENT %q (use seeded or random)", cfg.Exam.Assignment)
+	}
+	if err := checkQuestionBank(&cfg.Exam, store); err != nil {
+		log.Fatalf("❌ Failed to load questions: %v", err)
+	}
+	if !cfg.Exam.RequireSession {
+		log.Printf("⚠️  Exam sessions optional: submissions without a session token are accepted (EXAM_REQUIRE_SESSION=false)")
+	}
+
+	// Live proctoring of the sessions in progress
+	hub := proctor.NewHub(store, &cfg.Proctor)
+
+	// Rules submitted answers are scored with
+	rules, err := loadSuspicionRules(&cfg.Suspicion)
+	if err != nil {
+		log.Fatalf("❌ Failed to load suspicion rules: %v", err)
+	}
 
 	// Initialize handlers
-	submitHandler := handlers.NewSubmitHandler(store)
+	submitHandler := handlers.NewSubmitHandler(store, &cfg.Integrity, &cfg.Exam, signer, hub, rules)
+	examSessionHandler := handlers.NewExamSessionHandler(store, signer, &cfg.Exam, hub)
+	proctorHandler := handlers.NewProctorHandler(hub)
+	questionsHandler := handlers.NewQuestionsHandler(store)
+	examsHandler := handlers.NewExamsHandler(store)
 	submissionsHandler := handlers.NewSubmissionsHandler(store)
+	submissionHandler := handlers.NewSubmissionHandler(store)
+	authHandler := handlers.NewAuthHandler(store, &cfg.Auth)
+	usersHandler := handlers.NewUsersHandler(store)
 	staticHandler := handlers.NewStaticFileHandler(cfg.Static.Dir)
 
+	// Roles allowed per endpoint
+	auth := middleware.NewAuthenticator(store, &cfg.Auth)
+	evaluators := auth.Require(storage.RoleEvaluator, storage.RoleAdmin)
+	admins := auth.Require(storage.RoleAdmin)
+	submitters := func(next http.Handler) http.Handler { return next }
+	if cfg.Auth.StudentLogin {
+		submitters = auth.Require(storage.Roles...)
+	}
+
 	// Setup routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", handlers.HealthCheckHandler)
-	mux.HandleFunc("/submit", submitHandler.HandleSubmit)
-	mux.HandleFunc("/submissions", submissionsHandler.HandleListSubmissions)
+	mux.HandleFunc("/auth/login", authHandler.HandleLogin)
+	mux.HandleFunc("/auth/logout", authHandler.HandleLogout)
+	mux.HandleFunc("/auth/me", authHandler.HandleMe)
+	mux.Handle("/exam-sessions", submitters(examSessionHandler))
+	mux.Handle("/exam-sessions/", submitters(examSessionHandler))
+	mux.Handle("/questions", evaluators(questionsHandler))
+	mux.Handle("/questions/", evaluators(questionsHandler))
+	mux.Handle("/exams", evaluators(examsHandler))
+	mux.Handle("/exams/", readWrite(evaluators(examsHandler), admins(examsHandler)))
+	mux.Handle("/submit", submitters(http.HandlerFunc(submitHandler.HandleSubmit)))
+	mux.Handle("/submissions", evaluators(http.HandlerFunc(submissionsHandler.HandleListSubmissions)))
+	mux.Handle("/submissions/", evaluators(submissionHandler))
+	mux.Handle("/proctor/", evaluators(proctorHandler))
+	mux.Handle("/users", admins(usersHandler))
+	mux.Handle("/users/", admins(usersHandler))
 
 	// Serve static files (HTML, JS, JSON) - this should be last
 	mux.Handle("/", staticHandler)
 
-	// Wrap with CORS middleware
-	handler := middleware.CORS(&cfg.CORS)(mux)
+	// Resolve the signed-in user, then wrap with CORS middleware
+	handler := middleware.CORS(&cfg.CORS)(auth.Authenticate(mux))
 
 	// Configure server
 	server := &http.Server{
@@ -54,17 +114,27 @@ func main() {
 		IdleTimeout:    cfg.Server.IdleTimeout,
 		MaxHeaderBytes: cfg.Server.MaxHeaderBytes,
 	}
+	// End proctor streams so shutdown does not wait for them
+	server.RegisterOnShutdown(hub.Close)
 
 	// Start server in goroutine for graceful shutdown
 	serverErrors := make(chan error, 1)
 	go func() {
 		log.Printf("🚀 Server starting on http://localhost:%s", cfg.Server.Port)
 		log.Printf("📊 Health check: http://localhost:%s/health", cfg.Server.Port)
+		log.Printf("🎫 Exam sessions: http://localhost:%s/exam-sessions", cfg.Server.Port)
+		log.Printf("📚 Question bank: http://localhost:%s/questions", cfg.Server.Port)
+		log.Printf("🗓️  Exams: http://localhost:%s/exams", cfg.Server.Port)
 		log.Printf("📝 Submit endpoint: http://localhost:%s/submit", cfg.Server.Port)
+		log.Printf("👀 Proctoring: http://localhost:%s/proctor/{examId}/stream", cfg.Server.Port)
 		log.Printf("📋 Submissions list: http://localhost:%s/submissions", cfg.Server.Port)
+		log.Printf("📄 Submission: http://localhost:%s/submissions/{examId}/{studentId}", cfg.Server.Port)
+		log.Printf("🗂️  Revisions: http://localhost:%s/submissions/{examId}/{studentId}/revisions", cfg.Server.Port)
 		log.Printf("📄 Exam page: http://localhost:%s/exam.html", cfg.Server.Port)
 		log.Printf("📄 Review page: http://localhost:%s/review.html", cfg.Server.Port)
 		log.Printf("📄 Submissions page: http://localhost:%s/submissions.html", cfg.Server.Port)
+		log.Printf("📄 Proctor page: http://localhost:%s/proctor.html", cfg.Server.Port)
+		log.Printf("🔑 Login page: http://localhost:%s/login.html", cfg.Server.Port)
 		serverErrors <- server.ListenAndServe()
 	}()
 
@@ -95,3 +165,149 @@ func main() {
 		log.Println("✅ Server stopped gracefully")
 	}
 }
+
+// openStorage opens the database, applying pending migrations unless
+// DB_AUTO_MIGRATE is disabled, in which case they are only reported
+func openStorage(cfg *config.DBConfig) (storage.Store, error) {
+	store, err := storage.Open(cfg.Driver, cfg.DSN())
+	if err != nil {
+		return nil, err
+	}
+
+	if !cfg.AutoMigrate {
+		statuses, err := store.Migrations()
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		for _, m := range statuses {
+			if !m.Applied {
+				log.Printf("⚠️  Pending migration %d: %s (run: drkka migrate)", m.Version, m.Name)
+			}
+		}
+		return store, nil
+	}
+
+	applied, err := store.Migrate()
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	for _, m := range applied {
+		log.Printf("🗄️  Applied migration %d: %s", m.Version, m.Name)
+	}
+
+	return store, nil
+}
+
+// readWrite sends GET and HEAD requests to read and every other method
+// to write, so that one endpoint can allow different roles per method
+func readWrite(read, write http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			read.ServeHTTP(w, r)
+			return
+		}
+		write.ServeHTTP(w, r)
+	})
+}
+
+// checkAuth reports the authentication setup, warning when nobody could
+// sign in to use the protected endpoints
+func checkAuth(cfg *config.AuthConfig, store storage.Store) {
+	if !cfg.Enabled {
+		log.Printf("⚠️  Authentication disabled: submissions are readable by anyone (AUTH_ENABLED=false)")
+		return
+	}
+
+	users, err := store.ListUsers()
+	if err != nil {
+		log.Printf("⚠️  Failed to list users: %v", err)
+		return
+	}
+	for _, user := range users {
+		if user.Role == storage.RoleAdmin {
+			log.Printf("🔒 Authentication enabled: %d users, sessions last %s", len(users), cfg.SessionTTL)
+			return
+		}
+	}
+	log.Printf("⚠️  No admin account yet; create one with: drkka user add -role admin <username>")
+}
+
+// checkQuestionBank reports the question bank, importing QUESTIONS_FILE
+// into it when it is empty
+func checkQuestionBank(cfg *config.ExamConfig, store storage.Store) error {
+	bank, err := store.ListQuestions()
+	if err != nil {
+		return err
+	}
+	if len(bank) > 0 {
+		log.Printf("📚 Question bank: %d questions, %s assignment, %d per session", len(bank), cfg.Assignment, cfg.QuestionsPerSession)
+		return nil
+	}
+
+	data, err := os.ReadFile(cfg.QuestionsFile)
+	if os.IsNotExist(err) {
+		log.Printf("⚠️  Question bank is empty; import questions with: drkka questions import <file>")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	questions, err := questionbank.Parse(data)
+	if err != nil {
+		return err
+	}
+	result, err := questionbank.Import(store, questions)
+	if err != nil {
+		return err
+	}
+	log.Printf("📚 Imported %d questions from %s into the question bank", result.Added, cfg.QuestionsFile)
+	return nil
+}
+
+// loadSuspicionRules loads SUSPICION_RULES_FILE, or returns nil rules,
+// which score nothing, when the file does not exist
+func loadSuspicionRules(cfg *config.SuspicionConfig) (*suspicion.Rules, error) {
+	rules, err := suspicion.Load(cfg.RulesFile)
+	if os.IsNotExist(err) {
+		log.Printf("⚠️  %s not found: submitted answers are not scored for suspicion", cfg.RulesFile)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("🚩 Suspicion rules: %d from %s, highest score %g", len(rules.Rules), cfg.RulesFile, rules.MaxScore())
+	return rules, nil
+}
+
+// examSigner creates the signer of exam session tokens from the configured
+// secret, or from a random one that only lasts until the server restarts
+func examSigner(cfg *config.ExamConfig) (*examsession.Signer, error) {
+	if cfg.TokenSecret != "" {
+		return examsession.NewSigner([]byte(cfg.TokenSecret))
+	}
+
+	secret, err := examsession.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("⚠️  EXAM_TOKEN_SECRET not set: using a random key, exam sessions end when the server restarts")
+	return examsession.NewSigner(secret)
+}
+
+// describeDB names the configured database for logs without leaking the
+// credentials of a connection URL
+func describeDB(cfg *config.DBConfig) string {
+	switch cfg.Driver {
+	case storage.DriverPostgres:
+		if u, err := url.Parse(cfg.URL); err == nil {
+			return "postgres " + u.Redacted()
+		}
+		return "postgres"
+	case storage.DriverMemory:
+		return "in-memory (not persisted)"
+	default:
+		return cfg.Path
+	}
+}
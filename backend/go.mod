@@ -3,3 +3,12 @@ module backend
 go 1.21
 
 require github.com/mattn/go-sqlite3 v1.14.18
+
+require github.com/lib/pq v1.10.9
+
+require (
+	golang.org/x/crypto v0.21.0
+	golang.org/x/term v0.18.0
+)
+
+require golang.org/x/sys v0.18.0 // indirect
@@ -0,0 +1,88 @@
+package analytics
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Columns returns the CSV column names of Record, for exports that add
+// the features of each answer to its row
+func Columns() []string {
+	columns := []string{
+		"keystrokes",
+		"typed_chars",
+		"active_typing_ms",
+		"wpm",
+		"latency_mean_ms",
+		"latency_stddev_ms",
+		"latency_p10_ms",
+		"latency_p25_ms",
+		"latency_p50_ms",
+		"latency_p75_ms",
+		"latency_p90_ms",
+		"bursts",
+		"burst_mean_length",
+		"burst_max_length",
+	}
+	for i, min := range pauseBounds {
+		if i+1 < len(pauseBounds) {
+			columns = append(columns, fmt.Sprintf("pauses_%gs_%gs", min/1000, pauseBounds[i+1]/1000))
+		} else {
+			columns = append(columns, fmt.Sprintf("pauses_%gs_plus", min/1000))
+		}
+	}
+	return append(columns,
+		"correction_ratio",
+		"revision_share",
+		"revision_distance",
+		"time_to_first_keystroke_ms",
+	)
+}
+
+// Record returns the features as CSV fields in the order of Columns. The
+// time to the first keystroke is empty when nothing was typed.
+func (f Features) Record() []string {
+	record := []string{
+		strconv.Itoa(f.Keystrokes),
+		strconv.Itoa(f.TypedChars),
+		formatMs(f.ActiveTypingMs),
+		formatRatio(f.WordsPerMinute),
+		formatMs(f.Latency.MeanMs),
+		formatMs(f.Latency.StdDevMs),
+		formatMs(f.Latency.P10Ms),
+		formatMs(f.Latency.P25Ms),
+		formatMs(f.Latency.P50Ms),
+		formatMs(f.Latency.P75Ms),
+		formatMs(f.Latency.P90Ms),
+		strconv.Itoa(f.Bursts.Count),
+		formatRatio(f.Bursts.MeanLength),
+		strconv.Itoa(f.Bursts.MaxLength),
+	}
+	for i := range pauseBounds {
+		count := 0
+		if i < len(f.Pauses) {
+			count = f.Pauses[i].Count
+		}
+		record = append(record, strconv.Itoa(count))
+	}
+	timeToFirst := ""
+	if f.TimeToFirstKeystrokeMs != nil {
+		timeToFirst = formatMs(*f.TimeToFirstKeystrokeMs)
+	}
+	return append(record,
+		formatRatio(f.CorrectionRatio),
+		formatRatio(f.RevisionShare),
+		formatRatio(f.RevisionDistance),
+		timeToFirst,
+	)
+}
+
+// formatMs writes a duration rounded to the millisecond
+func formatMs(ms float64) string {
+	return strconv.FormatFloat(ms, 'f', 0, 64)
+}
+
+// formatRatio writes a rate or ratio with three decimals
+func formatRatio(v float64) string {
+	return strconv.FormatFloat(v, 'f', 3, 64)
+}
@@ -0,0 +1,235 @@
+// Package analytics derives keystroke-dynamics features from event logs:
+// how fast and how evenly an answer was typed, in what bursts, with which
+// pauses and how much of it was revised. Composing an answer shows pauses,
+// corrections and edits away from the end of the text; transcribing one
+// shows steady typing from start to end.
+package analytics
+
+import (
+	"math"
+	"sort"
+
+	"backend/internal/eventlog"
+)
+
+// BurstGapMs is the shortest pause that ends a burst of typing
+const BurstGapMs = 2000
+
+// charsPerWord is the conventional word length of words-per-minute rates
+const charsPerWord = 5
+
+// Features describes how one answer was typed
+type Features struct {
+	// Keystrokes counts typed characters and special key presses
+	Keystrokes int `json:"keystrokes"`
+	// TypedChars counts characters typed, Enter included, pastes excluded
+	TypedChars int `json:"typedChars"`
+	// ActiveTypingMs sums the gaps between actions up to
+	// eventlog.ActiveGapMs, like eventlog.Stats
+	ActiveTypingMs float64 `json:"activeTypingMs"`
+	// WordsPerMinute is TypedChars, in words of five characters, per
+	// minute of active typing
+	WordsPerMinute float64 `json:"wordsPerMinute"`
+	// Latency is the distribution of the gaps between consecutive
+	// keystrokes, pauses longer than eventlog.ActiveGapMs excluded
+	Latency Distribution `json:"latency"`
+	// Bursts are runs of keystrokes without a pause of BurstGapMs
+	Bursts Bursts `json:"bursts"`
+	// Pauses counts the gaps between actions by length, from BurstGapMs
+	Pauses []PauseBucket `json:"pauses"`
+	// CorrectionRatio is the share of text keystrokes that were Backspace
+	// or Delete
+	CorrectionRatio float64 `json:"correctionRatio"`
+	// RevisionShare is the share of edits made before the end of the text
+	// and RevisionDistance their mean distance from it, in characters
+	RevisionShare    float64 `json:"revisionShare"`
+	RevisionDistance float64 `json:"revisionDistance"`
+	// TimeToFirstKeystrokeMs is the time from the first event of the log,
+	// such as a click into the answer, to the first character typed or
+	// pasted; nil when nothing was
+	TimeToFirstKeystrokeMs *float64 `json:"timeToFirstKeystrokeMs"`
+}
+
+// Distribution summarizes a set of durations in milliseconds
+type Distribution struct {
+	Count  int     `json:"count"`
+	MeanMs float64 `json:"meanMs"`
+	// StdDevMs is low for implausibly even typing
+	StdDevMs float64 `json:"stdDevMs"`
+	P10Ms    float64 `json:"p10Ms"`
+	P25Ms    float64 `json:"p25Ms"`
+	P50Ms    float64 `json:"p50Ms"`
+	P75Ms    float64 `json:"p75Ms"`
+	P90Ms    float64 `json:"p90Ms"`
+}
+
+// Bursts summarizes the runs of keystrokes of an answer
+type Bursts struct {
+	Count int `json:"count"`
+	// MeanLength and MaxLength are in keystrokes
+	MeanLength float64 `json:"meanLength"`
+	MaxLength  int     `json:"maxLength"`
+}
+
+// PauseBucket counts the pauses from MinMs up to, not including, MaxMs;
+// MaxMs is 0 for the last, open-ended bucket
+type PauseBucket struct {
+	MinMs float64 `json:"minMs"`
+	MaxMs float64 `json:"maxMs"`
+	Count int     `json:"count"`
+}
+
+// pauseBounds are the lower bounds of the pause buckets
+var pauseBounds = []float64{BurstGapMs, 5000, 15000, 60000, 300000}
+
+// Compute replays the events and derives their features. COMPRESSED
+// events only keep the mean interval of a segment, so latencies within a
+// segment are all equal to it.
+func Compute(events []eventlog.Event) Features {
+	f := Features{Pauses: make([]PauseBucket, len(pauseBounds))}
+	for i, min := range pauseBounds {
+		f.Pauses[i].MinMs = min
+		if i+1 < len(pauseBounds) {
+			f.Pauses[i].MaxMs = pauseBounds[i+1]
+		}
+	}
+
+	var (
+		buf         = eventlog.NewBuffer()
+		latencies   []float64
+		corrections int
+		edits       int
+		revisions   int
+		distance    int
+		burst       int
+		bursts      []int
+		lastKey     = math.NaN()
+	)
+	endBurst := func() {
+		if burst > 0 {
+			bursts = append(bursts, burst)
+			burst = 0
+		}
+	}
+
+	actions := eventlog.Expand(events)
+	for i, action := range actions {
+		if i > 0 {
+			gap := action.At - actions[i-1].At
+			if gap <= eventlog.ActiveGapMs {
+				f.ActiveTypingMs += gap
+			}
+			if gap >= BurstGapMs {
+				f.Pauses[pauseBucket(gap)].Count++
+				endBurst()
+			}
+		}
+
+		keystroke := action.Op == eventlog.OpInsert || action.Op == eventlog.OpKey
+		typed := action.Op == eventlog.OpInsert || (action.Op == eventlog.OpKey && action.Key == eventlog.KeyEnter)
+		deletion := action.Op == eventlog.OpKey && (action.Key == eventlog.KeyBackspace || action.Key == eventlog.KeyDelete)
+
+		if keystroke {
+			f.Keystrokes++
+			if !math.IsNaN(lastKey) && action.At-lastKey <= eventlog.ActiveGapMs {
+				latencies = append(latencies, action.At-lastKey)
+			}
+			lastKey = action.At
+			burst++
+		} else {
+			endBurst()
+		}
+		if typed {
+			f.TypedChars++
+		}
+		if deletion {
+			corrections++
+		}
+		if (typed || action.Op == eventlog.OpPaste) && f.TimeToFirstKeystrokeMs == nil {
+			at := action.At
+			f.TimeToFirstKeystrokeMs = &at
+		}
+
+		if typed || deletion || action.Op == eventlog.OpPaste {
+			edits++
+			if fromEnd := buf.Len() - buf.Cursor(); fromEnd > 0 {
+				revisions++
+				distance += fromEnd
+			}
+		}
+		buf.Apply(action)
+	}
+	endBurst()
+
+	if f.ActiveTypingMs > 0 {
+		f.WordsPerMinute = float64(f.TypedChars) / charsPerWord / (f.ActiveTypingMs / 60000)
+	}
+	f.Latency = distribution(latencies)
+	if len(bursts) > 0 {
+		total := 0
+		for _, length := range bursts {
+			total += length
+			if length > f.Bursts.MaxLength {
+				f.Bursts.MaxLength = length
+			}
+		}
+		f.Bursts.Count = len(bursts)
+		f.Bursts.MeanLength = float64(total) / float64(len(bursts))
+	}
+	if f.TypedChars+corrections > 0 {
+		f.CorrectionRatio = float64(corrections) / float64(f.TypedChars+corrections)
+	}
+	if edits > 0 {
+		f.RevisionShare = float64(revisions) / float64(edits)
+	}
+	if revisions > 0 {
+		f.RevisionDistance = float64(distance) / float64(revisions)
+	}
+	return f
+}
+
+// pauseBucket returns the index of the bucket a pause of gap belongs to
+func pauseBucket(gap float64) int {
+	i := sort.SearchFloat64s(pauseBounds, gap)
+	if i == len(pauseBounds) || pauseBounds[i] > gap {
+		i--
+	}
+	return i
+}
+
+// distribution summarizes durations
+func distribution(values []float64) Distribution {
+	d := Distribution{Count: len(values)}
+	if len(values) == 0 {
+		return d
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	d.MeanMs = sum / float64(len(sorted))
+	variance := 0.0
+	for _, v := range sorted {
+		variance += (v - d.MeanMs) * (v - d.MeanMs)
+	}
+	d.StdDevMs = math.Sqrt(variance / float64(len(sorted)))
+
+	d.P10Ms = percentile(sorted, 0.10)
+	d.P25Ms = percentile(sorted, 0.25)
+	d.P50Ms = percentile(sorted, 0.50)
+	d.P75Ms = percentile(sorted, 0.75)
+	d.P90Ms = percentile(sorted, 0.90)
+	return d
+}
+
+// percentile interpolates the p-quantile (0 to 1) of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
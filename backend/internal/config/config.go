@@ -2,15 +2,21 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"time"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server ServerConfig
-	DB     DBConfig
-	Static StaticConfig
-	CORS   CORSConfig
+	Server    ServerConfig
+	DB        DBConfig
+	Static    StaticConfig
+	CORS      CORSConfig
+	Integrity IntegrityConfig
+	Auth      AuthConfig
+	Exam      ExamConfig
+	Proctor   ProctorConfig
+	Suspicion SuspicionConfig
 }
 
 // ServerConfig holds server-related configuration
@@ -24,7 +30,14 @@ type ServerConfig struct {
 
 // DBConfig holds database-related configuration
 type DBConfig struct {
+	// Driver selects the storage backend: "sqlite", "postgres" or "memory"
+	Driver string
+	// Path is the SQLite database file
 	Path string
+	// URL is the PostgreSQL connection URL
+	URL string
+	// AutoMigrate applies pending schema migrations at server startup
+	AutoMigrate bool
 }
 
 // StaticConfig holds static file serving configuration
@@ -37,6 +50,64 @@ type CORSConfig struct {
 	AllowedOrigins string
 }
 
+// IntegrityConfig holds event log integrity checking configuration
+type IntegrityConfig struct {
+	// Mode is "flag" to store the verdict and accept the submission, or
+	// "reject" to refuse submissions whose event log does not reproduce
+	// the final answer
+	Mode string
+}
+
+// AuthConfig holds authentication configuration
+type AuthConfig struct {
+	// Enabled requires a signed-in user with a suitable role for every
+	// endpoint except /submit, /login, /health and static files
+	Enabled bool
+	// SessionTTL is how long a login stays valid
+	SessionTTL time.Duration
+	// SecureCookie marks the session cookie Secure, for HTTPS deployments
+	SecureCookie bool
+	// StudentLogin also requires a signed-in user for /submit
+	StudentLogin bool
+}
+
+// ExamConfig holds exam session configuration
+type ExamConfig struct {
+	// TokenSecret signs exam session tokens; it must be at least 32
+	// bytes and shared by every server instance. When empty a random
+	// secret is used, so tokens do not survive a restart.
+	TokenSecret string
+	// SessionTTL is how long after starting a student may submit
+	SessionTTL time.Duration
+	// RequireSession rejects submissions without a valid session token
+	RequireSession bool
+	// QuestionsFile is the questions.json imported into an empty question
+	// bank at startup
+	QuestionsFile string
+	// Assignment is "seeded" to assign the same questions whenever a
+	// student starts an exam, or "random"
+	Assignment string
+	// QuestionsPerSession is the number of questions assigned per session
+	QuestionsPerSession int
+}
+
+// ProctorConfig holds live proctoring configuration
+type ProctorConfig struct {
+	// IdleAfter is how long after their last autosave a student counts
+	// as idle
+	IdleAfter time.Duration
+	// Buffer is the number of updates queued per proctor connection; a
+	// connection falling further behind is closed
+	Buffer int
+}
+
+// SuspicionConfig holds suspicion scoring configuration
+type SuspicionConfig struct {
+	// RulesFile is the JSON file of rules submitted answers are scored
+	// with; answers are not scored when it does not exist
+	RulesFile string
+}
+
 // Load loads configuration from environment variables
 func Load() *Config {
 	return &Config{
@@ -48,7 +119,10 @@ func Load() *Config {
 			MaxHeaderBytes: 1 << 20, // 1 MB
 		},
 		DB: DBConfig{
-			Path: getEnv("DB_PATH", "./drkka.db"),
+			Driver:      getEnv("DB_DRIVER", "sqlite"),
+			Path:        getEnv("DB_PATH", "./drkka.db"),
+			URL:         getEnv("DB_URL", ""),
+			AutoMigrate: getEnv("DB_AUTO_MIGRATE", "true") == "true",
 		},
 		Static: StaticConfig{
 			Dir: getEnv("STATIC_DIR", "../frontend/"),
@@ -56,9 +130,43 @@ func Load() *Config {
 		CORS: CORSConfig{
 			AllowedOrigins: getEnv("ALLOWED_ORIGINS", "http://localhost:3000,http://localhost:8080,http://127.0.0.1:3000,http://127.0.0.1:8080"),
 		},
+		Integrity: IntegrityConfig{
+			Mode: getEnv("INTEGRITY_MODE", "flag"),
+		},
+		Auth: AuthConfig{
+			Enabled:      getEnv("AUTH_ENABLED", "true") == "true",
+			SessionTTL:   getDuration("AUTH_SESSION_TTL", 12*time.Hour),
+			SecureCookie: getEnv("AUTH_SECURE_COOKIE", "false") == "true",
+			StudentLogin: getEnv("AUTH_STUDENT_LOGIN", "false") == "true",
+		},
+		Exam: ExamConfig{
+			TokenSecret:    getEnv("EXAM_TOKEN_SECRET", ""),
+			SessionTTL:     getDuration("EXAM_SESSION_TTL", 3*time.Hour),
+			RequireSession: getEnv("EXAM_REQUIRE_SESSION", "true") == "true",
+			QuestionsFile:  getEnv("QUESTIONS_FILE", "./questions.json"),
+
+			Assignment:          getEnv("QUESTION_ASSIGNMENT", "seeded"),
+			QuestionsPerSession: getInt("QUESTIONS_PER_SESSION", 1),
+		},
+		Proctor: ProctorConfig{
+			IdleAfter: getDuration("PROCTOR_IDLE_AFTER", 30*time.Second),
+			Buffer:    getInt("PROCTOR_BUFFER", 64),
+		},
+		Suspicion: SuspicionConfig{
+			RulesFile: getEnv("SUSPICION_RULES_FILE", "./suspicion_rules.json"),
+		},
 	}
 }
 
+// DSN returns the data source for the configured driver: the connection
+// URL for postgres, the database file otherwise
+func (c *DBConfig) DSN() string {
+	if c.Driver == "postgres" {
+		return c.URL
+	}
+	return c.Path
+}
+
 // getEnv gets an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -66,3 +174,21 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getInt gets an environment variable as a positive integer or returns a
+// default value when it is unset or invalid
+func getInt(key string, defaultValue int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n > 0 {
+		return n
+	}
+	return defaultValue
+}
+
+// getDuration gets an environment variable as a duration (e.g. 8h or
+// 30m) or returns a default value when it is unset or invalid
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
+	}
+	return defaultValue
+}
@@ -0,0 +1,279 @@
+package eventlog
+
+// Special key names captured by exam.js
+const (
+	KeyBackspace  = "Backspace"
+	KeyDelete     = "Delete"
+	KeyEnter      = "Enter"
+	KeyArrowLeft  = "ArrowLeft"
+	KeyArrowRight = "ArrowRight"
+	KeyArrowUp    = "ArrowUp"
+	KeyArrowDown  = "ArrowDown"
+	KeyHome       = "Home"
+	KeyEnd        = "End"
+)
+
+// Buffer is a text area with a cursor and selection, mirroring how a
+// browser textarea reacts to the captured input events.
+//
+// Offsets are counted in characters (runes). Browsers count UTF-16 code
+// units, which only differs for characters outside the Basic Multilingual
+// Plane.
+type Buffer struct {
+	text     []rune
+	pasted   []bool // pasted[i] reports whether text[i] came from a paste
+	selStart int
+	selEnd   int
+	goalCol  int // column kept across consecutive ArrowUp/ArrowDown presses
+}
+
+// NewBuffer creates an empty buffer with the cursor at offset 0
+func NewBuffer() *Buffer {
+	return &Buffer{goalCol: -1}
+}
+
+// Text returns the current contents
+func (b *Buffer) Text() string {
+	return string(b.text)
+}
+
+// Len returns the number of characters in the buffer
+func (b *Buffer) Len() int {
+	return len(b.text)
+}
+
+// PastedLen returns the number of characters in the buffer that were
+// inserted by a paste and have not been deleted since
+func (b *Buffer) PastedLen() int {
+	n := 0
+	for _, p := range b.pasted {
+		if p {
+			n++
+		}
+	}
+	return n
+}
+
+// Pasted reports whether the character at offset i was inserted by a paste
+func (b *Buffer) Pasted(i int) bool {
+	return i >= 0 && i < len(b.pasted) && b.pasted[i]
+}
+
+// Cursor returns the caret offset (the end of the selection)
+func (b *Buffer) Cursor() int {
+	return b.selEnd
+}
+
+// Selection returns the selected range; start equals end when collapsed
+func (b *Buffer) Selection() (start, end int) {
+	return b.selStart, b.selEnd
+}
+
+// HasSelection reports whether a non-empty range is selected
+func (b *Buffer) HasSelection() bool {
+	return b.selStart != b.selEnd
+}
+
+// Apply performs a single replay action and reports whether it was
+// understood; unknown special keys are ignored like the browser replay does
+func (b *Buffer) Apply(a Action) bool {
+	switch a.Op {
+	case OpInsert:
+		b.Insert(a.Text)
+		return true
+	case OpPaste:
+		b.insert(a.Text, true)
+		return true
+	case OpKey:
+		return b.Key(a.Key)
+	case OpSelect:
+		b.Select(a.Start, a.End)
+		return true
+	default:
+		return false
+	}
+}
+
+// Insert replaces the selection (or inserts at the cursor) with s
+func (b *Buffer) Insert(s string) {
+	b.insert(s, false)
+}
+
+// insert replaces the selection with s, recording whether it was pasted
+func (b *Buffer) insert(s string, pasted bool) {
+	b.goalCol = -1
+	b.deleteSelection()
+
+	ins := []rune(s)
+	pos := b.selStart
+
+	text := make([]rune, 0, len(b.text)+len(ins))
+	text = append(text, b.text[:pos]...)
+	text = append(text, ins...)
+	text = append(text, b.text[pos:]...)
+	b.text = text
+
+	origin := make([]bool, 0, len(b.pasted)+len(ins))
+	origin = append(origin, b.pasted[:pos]...)
+	for range ins {
+		origin = append(origin, pasted)
+	}
+	origin = append(origin, b.pasted[pos:]...)
+	b.pasted = origin
+
+	b.collapse(pos + len(ins))
+}
+
+// Select sets the selection, clamping offsets to the text like a textarea
+func (b *Buffer) Select(start, end int) {
+	b.goalCol = -1
+	start = b.clamp(start)
+	end = b.clamp(end)
+	if start > end {
+		start, end = end, start
+	}
+	b.selStart, b.selEnd = start, end
+}
+
+// Key applies a special key press and reports whether the key is known
+func (b *Buffer) Key(name string) bool {
+	// Vertical movement remembers its column, everything else resets it
+	if name != KeyArrowUp && name != KeyArrowDown {
+		b.goalCol = -1
+	}
+
+	switch name {
+	case KeyBackspace:
+		if b.HasSelection() {
+			b.deleteSelection()
+		} else if b.selStart > 0 {
+			b.deleteRange(b.selStart-1, b.selStart)
+		}
+
+	case KeyDelete:
+		if b.HasSelection() {
+			b.deleteSelection()
+		} else if b.selStart < len(b.text) {
+			b.deleteRange(b.selStart, b.selStart+1)
+		}
+
+	case KeyEnter:
+		b.Insert("\n")
+
+	case KeyArrowLeft:
+		if b.HasSelection() {
+			b.collapse(b.selStart)
+		} else if b.selStart > 0 {
+			b.collapse(b.selStart - 1)
+		}
+
+	case KeyArrowRight:
+		if b.HasSelection() {
+			b.collapse(b.selEnd)
+		} else if b.selEnd < len(b.text) {
+			b.collapse(b.selEnd + 1)
+		}
+
+	case KeyArrowUp:
+		b.moveVertical(-1)
+
+	case KeyArrowDown:
+		b.moveVertical(1)
+
+	// Home and End move to the ends of the hard line, like ArrowUp and
+	// ArrowDown ignoring soft wrapping
+	case KeyHome:
+		b.collapse(b.lineStart(b.selStart))
+
+	case KeyEnd:
+		b.collapse(b.lineEnd(b.selEnd))
+
+	default:
+		return false
+	}
+
+	return true
+}
+
+// moveVertical moves the cursor one hard line up (dir < 0) or down,
+// keeping the goal column. Soft wrapping in the browser is not known to
+// the log, so only newline-separated lines are considered.
+func (b *Buffer) moveVertical(dir int) {
+	pos := b.selEnd
+	if dir < 0 {
+		pos = b.selStart
+	}
+
+	lineStart := b.lineStart(pos)
+	if b.goalCol < 0 {
+		b.goalCol = pos - lineStart
+	}
+
+	if dir < 0 {
+		// First line: the caret jumps to the start of the text
+		if lineStart == 0 {
+			b.collapse(0)
+			return
+		}
+		prevStart := b.lineStart(lineStart - 1)
+		b.collapse(min(prevStart+b.goalCol, lineStart-1))
+		return
+	}
+
+	lineEnd := b.lineEnd(pos)
+	// Last line: the caret jumps to the end of the text
+	if lineEnd == len(b.text) {
+		b.collapse(len(b.text))
+		return
+	}
+	nextStart := lineEnd + 1
+	b.collapse(min(nextStart+b.goalCol, b.lineEnd(nextStart)))
+}
+
+// lineStart returns the offset of the first character of pos's line
+func (b *Buffer) lineStart(pos int) int {
+	for pos > 0 && b.text[pos-1] != '\n' {
+		pos--
+	}
+	return pos
+}
+
+// lineEnd returns the offset of the newline ending pos's line, or the
+// text length on the last line
+func (b *Buffer) lineEnd(pos int) int {
+	for pos < len(b.text) && b.text[pos] != '\n' {
+		pos++
+	}
+	return pos
+}
+
+// deleteSelection removes the selected text and collapses the cursor
+func (b *Buffer) deleteSelection() {
+	if b.HasSelection() {
+		b.deleteRange(b.selStart, b.selEnd)
+	}
+}
+
+// deleteRange removes text[start:end] and places the cursor at start
+func (b *Buffer) deleteRange(start, end int) {
+	b.text = append(b.text[:start], b.text[end:]...)
+	b.pasted = append(b.pasted[:start], b.pasted[end:]...)
+	b.collapse(start)
+}
+
+// collapse places the cursor at pos with no selection
+func (b *Buffer) collapse(pos int) {
+	pos = b.clamp(pos)
+	b.selStart, b.selEnd = pos, pos
+}
+
+// clamp limits an offset to the bounds of the text
+func (b *Buffer) clamp(pos int) int {
+	if pos < 0 {
+		return 0
+	}
+	if pos > len(b.text) {
+		return len(b.text)
+	}
+	return pos
+}
@@ -0,0 +1,106 @@
+package eventlog
+
+import "testing"
+
+// Actions for building buffer test cases
+func typed(s string) Action          { return Action{Op: OpInsert, Text: s} }
+func pressed(key string) Action      { return Action{Op: OpKey, Key: key} }
+func pasted(s string) Action         { return Action{Op: OpPaste, Text: s} }
+func selected(start, end int) Action { return Action{Op: OpSelect, Start: start, End: end} }
+
+func TestBufferKeys(t *testing.T) {
+	// threeLines has lines of 6, 2 and 6 characters; its newlines are at 6
+	// and 9
+	const threeLines = "abcdef\nab\nabcdef"
+
+	tests := []struct {
+		name    string
+		actions []Action
+		want    string
+		// start and end are the selection after the actions
+		start, end int
+	}{
+		// Selections are replaced by whatever is typed, pasted or deleted
+		{"typing replaces selection", []Action{typed("hello world"), selected(0, 5), typed("J")}, "J world", 1, 1},
+		{"paste replaces selection", []Action{typed("abc"), selected(1, 2), pasted("XY")}, "aXYc", 3, 3},
+		{"enter replaces selection", []Action{typed("abc"), selected(1, 3), pressed(KeyEnter)}, "a\n", 2, 2},
+		{"backspace deletes selection", []Action{typed("abcdef"), selected(1, 4), pressed(KeyBackspace)}, "aef", 1, 1},
+		{"delete deletes selection", []Action{typed("abcdef"), selected(4, 1), pressed(KeyDelete)}, "aef", 1, 1},
+		{"backspace at start", []Action{typed("ab"), selected(0, 0), pressed(KeyBackspace)}, "ab", 0, 0},
+		{"delete at end", []Action{typed("ab"), pressed(KeyDelete)}, "ab", 2, 2},
+		{"selection is clamped", []Action{typed("abc"), selected(10, -3)}, "abc", 0, 3},
+
+		// Horizontal movement collapses a selection to its side
+		{"arrow left collapses to start", []Action{typed("abcdef"), selected(2, 4), pressed(KeyArrowLeft)}, "abcdef", 2, 2},
+		{"arrow right collapses to end", []Action{typed("abcdef"), selected(2, 4), pressed(KeyArrowRight)}, "abcdef", 4, 4},
+		{"arrow left at start", []Action{typed("ab"), selected(0, 0), pressed(KeyArrowLeft)}, "ab", 0, 0},
+		{"arrow right at end", []Action{typed("ab"), pressed(KeyArrowRight)}, "ab", 2, 2},
+
+		// Vertical movement keeps the column across shorter lines
+		{"arrow up", []Action{typed(threeLines), pressed(KeyArrowUp)}, threeLines, 9, 9},
+		{"arrow up keeps column", []Action{typed(threeLines), pressed(KeyArrowUp), pressed(KeyArrowUp)}, threeLines, 6, 6},
+		{"arrow down keeps column", []Action{typed(threeLines), selected(6, 6), pressed(KeyArrowDown), pressed(KeyArrowDown)}, threeLines, 16, 16},
+		{"arrow up on first line", []Action{typed(threeLines), selected(3, 3), pressed(KeyArrowUp)}, threeLines, 0, 0},
+		{"arrow down on last line", []Action{typed(threeLines), selected(12, 12), pressed(KeyArrowDown)}, threeLines, 16, 16},
+		{"arrow up from selection start", []Action{typed(threeLines), selected(11, 14), pressed(KeyArrowUp)}, threeLines, 8, 8},
+		{"arrow down from selection end", []Action{typed(threeLines), selected(1, 8), pressed(KeyArrowDown)}, threeLines, 11, 11},
+		{"typing resets column", []Action{typed(threeLines), pressed(KeyArrowUp), typed("x"), pressed(KeyArrowUp)}, "abcdef\nabx\nabcdef", 3, 3},
+
+		// Home and End move within the hard line
+		{"home", []Action{typed(threeLines), pressed(KeyHome)}, threeLines, 10, 10},
+		{"home on first line", []Action{typed(threeLines), selected(4, 4), pressed(KeyHome)}, threeLines, 0, 0},
+		{"end", []Action{typed(threeLines), selected(7, 7), pressed(KeyEnd)}, threeLines, 9, 9},
+		{"end on last line", []Action{typed(threeLines), selected(12, 12), pressed(KeyEnd)}, threeLines, 16, 16},
+		{"home collapses selection", []Action{typed(threeLines), selected(8, 12), pressed(KeyHome)}, threeLines, 7, 7},
+		{"end collapses selection", []Action{typed(threeLines), selected(1, 8), pressed(KeyEnd)}, threeLines, 9, 9},
+		{"home then type", []Action{typed("world"), pressed(KeyHome), typed("hello ")}, "hello world", 6, 6},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := NewBuffer()
+			for i, action := range tc.actions {
+				if !buf.Apply(action) {
+					t.Fatalf("action %d (%+v) not understood", i, action)
+				}
+			}
+			if got := buf.Text(); got != tc.want {
+				t.Errorf("text = %q, want %q", got, tc.want)
+			}
+			if start, end := buf.Selection(); start != tc.start || end != tc.end {
+				t.Errorf("selection = %d-%d, want %d-%d", start, end, tc.start, tc.end)
+			}
+		})
+	}
+}
+
+func TestBufferPasted(t *testing.T) {
+	buf := NewBuffer()
+	for _, action := range []Action{typed("ab"), pasted("XYZ"), selected(3, 3), pressed(KeyBackspace), typed("c")} {
+		buf.Apply(action)
+	}
+	if got, want := buf.Text(), "abcYZ"; got != want {
+		t.Fatalf("text = %q, want %q", got, want)
+	}
+
+	want := []bool{false, false, false, true, true}
+	for i, pasted := range want {
+		if buf.Pasted(i) != pasted {
+			t.Errorf("Pasted(%d) = %t, want %t", i, buf.Pasted(i), pasted)
+		}
+	}
+	if buf.PastedLen() != 2 {
+		t.Errorf("PastedLen() = %d, want 2", buf.PastedLen())
+	}
+}
+
+func TestBufferUnknownKey(t *testing.T) {
+	buf := NewBuffer()
+	buf.Insert("abc")
+	if buf.Key("PageDown") {
+		t.Error("Key(PageDown) = true, want false")
+	}
+	if buf.Text() != "abc" || buf.Cursor() != 3 {
+		t.Errorf("unknown key changed the buffer to %q at %d", buf.Text(), buf.Cursor())
+	}
+}
@@ -0,0 +1,279 @@
+package eventlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Type identifies the kind of an event log entry
+type Type string
+
+// Event types produced by process_and_pack.js
+const (
+	TypeCompressed      Type = "COMPRESSED"
+	TypeRawKey          Type = "RAW_KEY"
+	TypeRawSpecial      Type = "RAW_SPECIAL"
+	TypeRawPaste        Type = "RAW_PASTE"
+	TypeSelectionChange Type = "SELECTION_CHANGE"
+)
+
+// Escape characters used inside COMPRESSED strings for special keys
+const (
+	EscapeBackspace = '\b'
+	EscapeEnter     = '\n'
+	EscapeDelete    = '\x7F'
+)
+
+// Event is a single entry of a compressed event log
+type Event interface {
+	// EventType returns the discriminator written to the "type" field
+	EventType() Type
+	// Latency returns the time in milliseconds since the previous event
+	Latency() float64
+}
+
+// Compressed is a segment of consistent typing, one character every IntervalMs
+type Compressed struct {
+	String     string  `json:"string"`
+	LatencyMs  float64 `json:"latency_ms"`
+	IntervalMs float64 `json:"interval_ms"`
+}
+
+// RawKey is a single character typed with variable timing
+type RawKey struct {
+	Key       string  `json:"key"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// RawSpecial is a non-character key press such as Backspace or ArrowLeft
+type RawSpecial struct {
+	Key       string  `json:"key"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// RawPaste is a paste of Content at the cursor
+type RawPaste struct {
+	Content   string  `json:"content"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// SelectionChange is a mouse-driven cursor move (Start == End) or selection
+type SelectionChange struct {
+	Start     int     `json:"start"`
+	End       int     `json:"end"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+func (e *Compressed) EventType() Type      { return TypeCompressed }
+func (e *RawKey) EventType() Type          { return TypeRawKey }
+func (e *RawSpecial) EventType() Type      { return TypeRawSpecial }
+func (e *RawPaste) EventType() Type        { return TypeRawPaste }
+func (e *SelectionChange) EventType() Type { return TypeSelectionChange }
+
+func (e *Compressed) Latency() float64      { return e.LatencyMs }
+func (e *RawKey) Latency() float64          { return e.LatencyMs }
+func (e *RawSpecial) Latency() float64      { return e.LatencyMs }
+func (e *RawPaste) Latency() float64        { return e.LatencyMs }
+func (e *SelectionChange) Latency() float64 { return e.LatencyMs }
+
+// DecodeError reports an event log entry that could not be decoded
+type DecodeError struct {
+	Index   int
+	Message string
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("event %d: %s", e.Index, e.Message)
+}
+
+// wireEvent is the union of all fields an event may carry on the wire
+type wireEvent struct {
+	Type       Type     `json:"type"`
+	String     *string  `json:"string,omitempty"`
+	Key        *string  `json:"key,omitempty"`
+	Content    *string  `json:"content,omitempty"`
+	Start      *int     `json:"start,omitempty"`
+	End        *int     `json:"end,omitempty"`
+	LatencyMs  *float64 `json:"latency_ms,omitempty"`
+	IntervalMs *float64 `json:"interval_ms,omitempty"`
+}
+
+// Log is an ordered event log that (un)marshals as the JSON "eventLog" array
+type Log []Event
+
+// UnmarshalJSON decodes a JSON array strictly into typed events
+func (l *Log) UnmarshalJSON(data []byte) error {
+	events, err := Decode(data)
+	if err != nil {
+		return err
+	}
+	*l = events
+	return nil
+}
+
+// MarshalJSON encodes the log as a JSON array, never null
+func (l Log) MarshalJSON() ([]byte, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]Event(l))
+}
+
+// Decode parses a JSON event log array into typed events. Unknown fields,
+// fields that do not belong to the event type and missing required fields
+// are rejected.
+func Decode(data []byte) (Log, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("event log must be an array: %w", err)
+	}
+
+	events := make(Log, 0, len(raw))
+	for i, item := range raw {
+		var w wireEvent
+		decoder := json.NewDecoder(bytes.NewReader(item))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&w); err != nil {
+			return nil, &DecodeError{Index: i, Message: err.Error()}
+		}
+
+		event, err := w.event()
+		if err != nil {
+			return nil, &DecodeError{Index: i, Message: err.Error()}
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// event converts the wire representation into its typed event
+func (w *wireEvent) event() (Event, error) {
+	latency := 0.0
+	if w.LatencyMs != nil {
+		latency = *w.LatencyMs
+	}
+	if latency < 0 {
+		return nil, fmt.Errorf("latency_ms must not be negative")
+	}
+
+	switch w.Type {
+	case TypeCompressed:
+		if err := w.only("string", "interval_ms"); err != nil {
+			return nil, err
+		}
+		if w.String == nil {
+			return nil, fmt.Errorf("COMPRESSED event requires string")
+		}
+		interval := 0.0
+		if w.IntervalMs != nil {
+			interval = *w.IntervalMs
+		}
+		if interval < 0 {
+			return nil, fmt.Errorf("interval_ms must not be negative")
+		}
+		return &Compressed{String: *w.String, LatencyMs: latency, IntervalMs: interval}, nil
+
+	case TypeRawKey:
+		if err := w.only("key"); err != nil {
+			return nil, err
+		}
+		if w.Key == nil {
+			return nil, fmt.Errorf("RAW_KEY event requires key")
+		}
+		return &RawKey{Key: *w.Key, LatencyMs: latency}, nil
+
+	case TypeRawSpecial:
+		if err := w.only("key"); err != nil {
+			return nil, err
+		}
+		if w.Key == nil {
+			return nil, fmt.Errorf("RAW_SPECIAL event requires key")
+		}
+		return &RawSpecial{Key: *w.Key, LatencyMs: latency}, nil
+
+	case TypeRawPaste:
+		if err := w.only("content"); err != nil {
+			return nil, err
+		}
+		if w.Content == nil {
+			return nil, fmt.Errorf("RAW_PASTE event requires content")
+		}
+		return &RawPaste{Content: *w.Content, LatencyMs: latency}, nil
+
+	case TypeSelectionChange:
+		if err := w.only("start", "end"); err != nil {
+			return nil, err
+		}
+		if w.Start == nil || w.End == nil {
+			return nil, fmt.Errorf("SELECTION_CHANGE event requires start and end")
+		}
+		if *w.Start < 0 || *w.End < 0 {
+			return nil, fmt.Errorf("selection offsets must not be negative")
+		}
+		return &SelectionChange{Start: *w.Start, End: *w.End, LatencyMs: latency}, nil
+
+	case "":
+		return nil, fmt.Errorf("type is missing")
+
+	default:
+		return nil, fmt.Errorf("unknown event type %q", w.Type)
+	}
+}
+
+// only rejects payload fields other than latency_ms and the given ones
+func (w *wireEvent) only(allowed ...string) error {
+	present := map[string]bool{
+		"string":      w.String != nil,
+		"key":         w.Key != nil,
+		"content":     w.Content != nil,
+		"start":       w.Start != nil,
+		"end":         w.End != nil,
+		"interval_ms": w.IntervalMs != nil,
+	}
+	for _, field := range allowed {
+		delete(present, field)
+	}
+
+	for _, field := range []string{"string", "key", "content", "start", "end", "interval_ms"} {
+		if present[field] {
+			return fmt.Errorf("%s event does not accept field %q", w.Type, field)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes the event with its "type" discriminator
+func (e *Compressed) MarshalJSON() ([]byte, error) {
+	return json.Marshal(wireEvent{Type: TypeCompressed, String: &e.String, LatencyMs: &e.LatencyMs, IntervalMs: &e.IntervalMs})
+}
+
+// MarshalJSON encodes the event with its "type" discriminator
+func (e *RawKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(wireEvent{Type: TypeRawKey, Key: &e.Key, LatencyMs: &e.LatencyMs})
+}
+
+// MarshalJSON encodes the event with its "type" discriminator
+func (e *RawSpecial) MarshalJSON() ([]byte, error) {
+	return json.Marshal(wireEvent{Type: TypeRawSpecial, Key: &e.Key, LatencyMs: &e.LatencyMs})
+}
+
+// MarshalJSON encodes the event with its "type" discriminator
+func (e *RawPaste) MarshalJSON() ([]byte, error) {
+	return json.Marshal(wireEvent{Type: TypeRawPaste, Content: &e.Content, LatencyMs: &e.LatencyMs})
+}
+
+// MarshalJSON encodes the event with its "type" discriminator
+func (e *SelectionChange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(wireEvent{Type: TypeSelectionChange, Start: &e.Start, End: &e.End, LatencyMs: &e.LatencyMs})
+}
+
+// Duration returns the total time in milliseconds covered by the events
+func Duration(events []Event) float64 {
+	actions := Expand(events)
+	if len(actions) == 0 {
+		return 0
+	}
+	return actions[len(actions)-1].At
+}
@@ -0,0 +1,95 @@
+package eventlog
+
+// Op identifies a primitive edit produced by expanding an event log
+type Op int
+
+const (
+	// OpInsert types a single character at the cursor
+	OpInsert Op = iota
+	// OpKey presses a special key such as Backspace or ArrowLeft
+	OpKey
+	// OpPaste inserts pasted text at the cursor
+	OpPaste
+	// OpSelect moves the cursor or selects a range
+	OpSelect
+)
+
+// String returns a readable name for the operation
+func (o Op) String() string {
+	switch o {
+	case OpInsert:
+		return "insert"
+	case OpKey:
+		return "key"
+	case OpPaste:
+		return "paste"
+	case OpSelect:
+		return "select"
+	default:
+		return "unknown"
+	}
+}
+
+// Action is one keystroke-level step of a replay
+type Action struct {
+	Op    Op
+	Text  string  // character for OpInsert, pasted content for OpPaste
+	Key   string  // key name for OpKey
+	Start int     // selection start for OpSelect
+	End   int     // selection end for OpSelect
+	At    float64 // milliseconds since the first event
+	Event int     // index of the source event in the log
+}
+
+// Expand flattens an event log into per-keystroke actions with their
+// timestamps, decompressing COMPRESSED segments character by character
+func Expand(events []Event) []Action {
+	actions := make([]Action, 0, len(events))
+	at := 0.0
+
+	for i, event := range events {
+		at += event.Latency()
+
+		switch e := event.(type) {
+		case *Compressed:
+			first := true
+			for _, r := range e.String {
+				// Characters after the first are spaced by the mean interval
+				if !first {
+					at += e.IntervalMs
+				}
+				first = false
+				actions = append(actions, compressedAction(r, at, i))
+			}
+
+		case *RawKey:
+			actions = append(actions, Action{Op: OpInsert, Text: e.Key, At: at, Event: i})
+
+		case *RawSpecial:
+			actions = append(actions, Action{Op: OpKey, Key: e.Key, At: at, Event: i})
+
+		case *RawPaste:
+			actions = append(actions, Action{Op: OpPaste, Text: e.Content, At: at, Event: i})
+
+		case *SelectionChange:
+			actions = append(actions, Action{Op: OpSelect, Start: e.Start, End: e.End, At: at, Event: i})
+		}
+	}
+
+	return actions
+}
+
+// compressedAction maps one character of a COMPRESSED string to its action,
+// translating the escape characters back into special keys
+func compressedAction(r rune, at float64, index int) Action {
+	switch r {
+	case EscapeBackspace:
+		return Action{Op: OpKey, Key: KeyBackspace, At: at, Event: index}
+	case EscapeEnter:
+		return Action{Op: OpKey, Key: KeyEnter, At: at, Event: index}
+	case EscapeDelete:
+		return Action{Op: OpKey, Key: KeyDelete, At: at, Event: index}
+	default:
+		return Action{Op: OpInsert, Text: string(r), At: at, Event: index}
+	}
+}
@@ -0,0 +1,101 @@
+package eventlog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []Event
+		want   []Action
+	}{
+		{
+			name:   "compressed",
+			events: []Event{&Compressed{String: "ab", LatencyMs: 10, IntervalMs: 100}},
+			want: []Action{
+				{Op: OpInsert, Text: "a", At: 10},
+				{Op: OpInsert, Text: "b", At: 110},
+			},
+		},
+		{
+			name:   "backspace escape",
+			events: []Event{&Compressed{String: "ab\bc", IntervalMs: 50}},
+			want: []Action{
+				{Op: OpInsert, Text: "a"},
+				{Op: OpInsert, Text: "b", At: 50},
+				{Op: OpKey, Key: KeyBackspace, At: 100},
+				{Op: OpInsert, Text: "c", At: 150},
+			},
+		},
+		{
+			name:   "enter escape",
+			events: []Event{&Compressed{String: "a\nb", IntervalMs: 50}},
+			want: []Action{
+				{Op: OpInsert, Text: "a"},
+				{Op: OpKey, Key: KeyEnter, At: 50},
+				{Op: OpInsert, Text: "b", At: 100},
+			},
+		},
+		{
+			name:   "delete escape",
+			events: []Event{&Compressed{String: "a\x7Fb", IntervalMs: 50}},
+			want: []Action{
+				{Op: OpInsert, Text: "a"},
+				{Op: OpKey, Key: KeyDelete, At: 50},
+				{Op: OpInsert, Text: "b", At: 100},
+			},
+		},
+		{
+			name:   "non-ASCII characters",
+			events: []Event{&Compressed{String: "दृक्", IntervalMs: 10}},
+			want: []Action{
+				{Op: OpInsert, Text: "द"},
+				{Op: OpInsert, Text: "ृ", At: 10},
+				{Op: OpInsert, Text: "क", At: 20},
+				{Op: OpInsert, Text: "्", At: 30},
+			},
+		},
+		{
+			name: "raw events",
+			events: []Event{
+				&RawKey{Key: "x", LatencyMs: 5},
+				&RawSpecial{Key: KeyArrowLeft, LatencyMs: 10},
+				&RawPaste{Content: "pasted\ntext", LatencyMs: 20},
+				&SelectionChange{Start: 2, End: 4, LatencyMs: 40},
+			},
+			want: []Action{
+				{Op: OpInsert, Text: "x", At: 5},
+				{Op: OpKey, Key: KeyArrowLeft, At: 15, Event: 1},
+				{Op: OpPaste, Text: "pasted\ntext", At: 35, Event: 2},
+				{Op: OpSelect, Start: 2, End: 4, At: 75, Event: 3},
+			},
+		},
+		{
+			name: "latencies accumulate across events",
+			events: []Event{
+				&Compressed{String: "ab", LatencyMs: 100, IntervalMs: 10},
+				&Compressed{String: "\b", LatencyMs: 1000, IntervalMs: 10},
+			},
+			want: []Action{
+				{Op: OpInsert, Text: "a", At: 100},
+				{Op: OpInsert, Text: "b", At: 110},
+				{Op: OpKey, Key: KeyBackspace, At: 1110, Event: 1},
+			},
+		},
+		{
+			name:   "empty",
+			events: nil,
+			want:   []Action{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Expand(tc.events); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expand() =\n%+v\nwant\n%+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package eventlog
+
+import "fmt"
+
+// Verdict is the outcome of replaying a question's event log against its
+// submitted final answer
+type Verdict string
+
+const (
+	// VerdictMatch means the replayed text equals the final answer
+	VerdictMatch Verdict = "match"
+	// VerdictMismatch means the replay produced different text
+	VerdictMismatch Verdict = "mismatch"
+	// VerdictUnreplayable means the event log contains input the replay
+	// engine cannot interpret
+	VerdictUnreplayable Verdict = "unreplayable"
+)
+
+// Integrity describes whether the recorded keystrokes explain the answer
+type Integrity struct {
+	Verdict Verdict `json:"verdict"`
+	// DiffOffset is the first character offset where replay and final
+	// answer disagree; only set for mismatches
+	DiffOffset *int   `json:"diffOffset,omitempty"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+// Check replays the events and compares the result with finalAnswer
+func Check(events []Event, finalAnswer string) Integrity {
+	buf := NewBuffer()
+	for _, action := range Expand(events) {
+		if !buf.Apply(action) {
+			return Integrity{
+				Verdict: VerdictUnreplayable,
+				Detail:  fmt.Sprintf("event %d: unknown special key %q", action.Event, action.Key),
+			}
+		}
+	}
+
+	replayed := buf.Text()
+	if replayed == finalAnswer {
+		return Integrity{Verdict: VerdictMatch}
+	}
+
+	offset := diffOffset([]rune(replayed), []rune(finalAnswer))
+	return Integrity{
+		Verdict:    VerdictMismatch,
+		DiffOffset: &offset,
+		Detail:     "replayed event log does not reproduce finalAnswer",
+	}
+}
+
+// diffOffset returns the index of the first differing character
+func diffOffset(a, b []rune) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
@@ -0,0 +1,94 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// loadSample reads the first question of a captured submission in testdata
+func loadSample(t *testing.T, name string) (events Log, finalAnswer string) {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sub struct {
+		Q1 struct {
+			FinalAnswer string `json:"finalAnswer"`
+			EventLog    Log    `json:"eventLog"`
+		} `json:"q1"`
+	}
+	if err := json.Unmarshal(data, &sub); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return sub.Q1.EventLog, sub.Q1.FinalAnswer
+}
+
+// TestCheckCapturedSample checks a log captured before exam.js ignored
+// keys pressed with modifiers: the v of the Ctrl+V pasting "Contact: ..."
+// was logged as typed, so no replay reproduces the final answer and the
+// mismatch is found at that v
+func TestCheckCapturedSample(t *testing.T) {
+	events, finalAnswer := loadSample(t, "restaurant.json")
+
+	got := Check(events, finalAnswer)
+	if got.Verdict != VerdictMismatch || got.DiffOffset == nil {
+		t.Fatalf("Check() = %+v, want mismatch with an offset", got)
+	}
+	const strayKey = 175
+	if *got.DiffOffset != strayKey {
+		t.Errorf("DiffOffset = %d, want %d", *got.DiffOffset, strayKey)
+	}
+
+	buf := NewBuffer()
+	for _, action := range Expand(events) {
+		if !buf.Apply(action) {
+			t.Fatalf("event %d (%s %q) not understood", action.Event, action.Op, action.Key)
+		}
+	}
+	replayed := []rune(buf.Text())
+	if string(replayed[:strayKey]) != string([]rune(finalAnswer)[:strayKey]) || replayed[strayKey] != 'v' {
+		t.Errorf("replayed %q, want the final answer up to a stray v at %d", string(replayed), strayKey)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		events      []Event
+		finalAnswer string
+		want        Verdict
+		// offset is the expected DiffOffset of mismatches
+		offset int
+	}{
+		{"match", []Event{&Compressed{String: "ab\bc"}}, "ac", VerdictMatch, 0},
+		{"empty", nil, "", VerdictMatch, 0},
+		{"edited after a selection", []Event{
+			&Compressed{String: "hello world"},
+			&SelectionChange{Start: 0, End: 5},
+			&RawPaste{Content: "goodbye"},
+		}, "goodbye world", VerdictMatch, 0},
+		{"differs", []Event{&Compressed{String: "abcd"}}, "abXd", VerdictMismatch, 2},
+		{"answer longer", []Event{&Compressed{String: "ab"}}, "abc", VerdictMismatch, 2},
+		{"answer shorter", []Event{&Compressed{String: "abc"}}, "ab", VerdictMismatch, 2},
+		{"unknown key", []Event{&RawKey{Key: "a"}, &RawSpecial{Key: "PageUp"}}, "a", VerdictUnreplayable, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Check(tc.events, tc.finalAnswer)
+			if got.Verdict != tc.want {
+				t.Fatalf("verdict = %s (%s), want %s", got.Verdict, got.Detail, tc.want)
+			}
+			switch {
+			case tc.want == VerdictMismatch && (got.DiffOffset == nil || *got.DiffOffset != tc.offset):
+				t.Errorf("DiffOffset = %v, want %d", got.DiffOffset, tc.offset)
+			case tc.want != VerdictMismatch && got.DiffOffset != nil:
+				t.Errorf("DiffOffset = %d, want none", *got.DiffOffset)
+			case tc.want == VerdictUnreplayable && got.Detail == "":
+				t.Error("unreplayable verdict without detail")
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package eventlog
+
+// Replay reconstructs the final text produced by an event log
+func Replay(events []Event) string {
+	buf := NewBuffer()
+	for _, action := range Expand(events) {
+		buf.Apply(action)
+	}
+	return buf.Text()
+}
+
+// ReplayUntil reconstructs the buffer as it was atMs milliseconds after
+// the first event, including every action timestamped at or before atMs
+func ReplayUntil(events []Event, atMs float64) *Buffer {
+	buf := NewBuffer()
+	for _, action := range Expand(events) {
+		if action.At > atMs {
+			break
+		}
+		buf.Apply(action)
+	}
+	return buf
+}
+
+// Replayer steps through an event log one action at a time
+type Replayer struct {
+	actions []Action
+	next    int
+	buf     *Buffer
+}
+
+// NewReplayer creates a replayer positioned before the first action
+func NewReplayer(events []Event) *Replayer {
+	return &Replayer{
+		actions: Expand(events),
+		buf:     NewBuffer(),
+	}
+}
+
+// Actions returns all expanded actions of the log
+func (r *Replayer) Actions() []Action {
+	return r.actions
+}
+
+// Buffer returns the buffer holding the replayed state
+func (r *Replayer) Buffer() *Buffer {
+	return r.buf
+}
+
+// Position returns the number of actions applied so far
+func (r *Replayer) Position() int {
+	return r.next
+}
+
+// Done reports whether every action has been applied
+func (r *Replayer) Done() bool {
+	return r.next >= len(r.actions)
+}
+
+// Step applies the next action and returns it; ok is false at the end
+func (r *Replayer) Step() (action Action, ok bool) {
+	if r.Done() {
+		return Action{}, false
+	}
+	action = r.actions[r.next]
+	r.buf.Apply(action)
+	r.next++
+	return action, true
+}
+
+// Seek rewinds or fast-forwards so that exactly n actions are applied
+func (r *Replayer) Seek(n int) {
+	if n < 0 {
+		n = 0
+	}
+	if n > len(r.actions) {
+		n = len(r.actions)
+	}
+
+	// The buffer cannot be undone, so seeking backwards replays from scratch
+	if n < r.next {
+		r.buf = NewBuffer()
+		r.next = 0
+	}
+	for r.next < n {
+		r.Step()
+	}
+}
+
+// SeekTime positions the replayer after every action at or before atMs
+func (r *Replayer) SeekTime(atMs float64) {
+	n := 0
+	for n < len(r.actions) && r.actions[n].At <= atMs {
+		n++
+	}
+	r.Seek(n)
+}
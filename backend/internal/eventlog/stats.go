@@ -0,0 +1,68 @@
+package eventlog
+
+// ActiveGapMs is the longest gap between two actions that still counts as
+// active typing; longer gaps are pauses
+const ActiveGapMs = 5000
+
+// Stats summarizes how an answer was produced, for evaluators
+type Stats struct {
+	// PasteCount is the number of paste events
+	PasteCount int `json:"pasteCount"`
+	// PastedChars is the total number of characters pasted
+	PastedChars int `json:"pastedChars"`
+	// PasteShare is the fraction (0 to 1) of the replayed answer made of
+	// pasted characters that were never deleted
+	PasteShare float64 `json:"pasteShare"`
+	// BackspaceCount is the number of Backspace presses
+	BackspaceCount int `json:"backspaceCount"`
+	// SelectionChanges is the number of mouse cursor moves and selections
+	SelectionChanges int `json:"selectionChanges"`
+	// ActiveTypingMs sums the gaps between actions up to ActiveGapMs
+	ActiveTypingMs float64 `json:"activeTypingMs"`
+	// LongestPauseMs is the longest gap between two actions
+	LongestPauseMs float64 `json:"longestPauseMs"`
+}
+
+// PasteUsed reports whether the answer contains any paste
+func (s Stats) PasteUsed() bool {
+	return s.PasteCount > 0
+}
+
+// ComputeStats replays the events and summarizes them
+func ComputeStats(events []Event) Stats {
+	var stats Stats
+	buf := NewBuffer()
+
+	actions := Expand(events)
+	for i, action := range actions {
+		buf.Apply(action)
+
+		switch action.Op {
+		case OpPaste:
+			stats.PasteCount++
+			stats.PastedChars += len([]rune(action.Text))
+		case OpKey:
+			if action.Key == KeyBackspace {
+				stats.BackspaceCount++
+			}
+		case OpSelect:
+			stats.SelectionChanges++
+		}
+
+		if i == 0 {
+			continue
+		}
+		gap := action.At - actions[i-1].At
+		if gap > stats.LongestPauseMs {
+			stats.LongestPauseMs = gap
+		}
+		if gap <= ActiveGapMs {
+			stats.ActiveTypingMs += gap
+		}
+	}
+
+	if buf.Len() > 0 {
+		stats.PasteShare = float64(buf.PastedLen()) / float64(buf.Len())
+	}
+	return stats
+}
@@ -0,0 +1,125 @@
+package examsession
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Errors returned by Verify
+var (
+	ErrInvalidToken = errors.New("invalid exam session token")
+	ErrExpired      = errors.New("exam session expired")
+)
+
+// Claims is what a token vouches for
+type Claims struct {
+	// SessionID identifies the registered session
+	SessionID string `json:"sid"`
+	ExamID    string `json:"exam"`
+	StudentID string `json:"student"`
+	// Questions holds the question indexes assigned to the student
+	Questions []int `json:"questions"`
+	// IssuedAt is the server time the session started; ExpiresAt is when
+	// the token stops being accepted. Both are Unix seconds.
+	IssuedAt  int64 `json:"iat"`
+	ExpiresAt int64 `json:"exp"`
+}
+
+// StartedAt returns IssuedAt as a time
+func (c *Claims) StartedAt() time.Time {
+	return time.Unix(c.IssuedAt, 0).UTC()
+}
+
+// Assigned reports whether the question index was assigned to the student
+func (c *Claims) Assigned(questionIndex int) bool {
+	for _, index := range c.Questions {
+		if index == questionIndex {
+			return true
+		}
+	}
+	return false
+}
+
+// Signer signs and verifies tokens with one secret key. A token is two
+// base64url segments joined by a dot: the JSON claims and their
+// HMAC-SHA256 under the key.
+type Signer struct {
+	key []byte
+}
+
+// NewSigner creates a signer for a secret of at least 32 bytes
+func NewSigner(secret []byte) (*Signer, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("exam session secret must be at least 32 bytes, got %d", len(secret))
+	}
+	return &Signer{key: secret}, nil
+}
+
+// NewSecret returns a random secret for NewSigner
+func NewSecret() ([]byte, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return secret, nil
+}
+
+// NewSessionID returns a random session or student identifier
+func NewSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Sign returns the token for claims
+func (s *Signer) Sign(claims Claims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %w", err)
+	}
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + base64.RawURLEncoding.EncodeToString(s.mac(body)), nil
+}
+
+// Verify checks a token's signature and expiry at now and returns its claims
+func (s *Signer) Verify(token string, now time.Time) (*Claims, error) {
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, s.mac(body)) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if now.Unix() >= claims.ExpiresAt {
+		return &claims, ErrExpired
+	}
+	return &claims, nil
+}
+
+// mac computes the signature of a token's body segment
+func (s *Signer) mac(body string) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(body))
+	return h.Sum(nil)
+}
@@ -0,0 +1,128 @@
+package examsession
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testNow    = time.Date(2025, 11, 29, 10, 0, 0, 0, time.UTC)
+	testClaims = Claims{
+		SessionID: "2ec233c6d27291b4a46712dc77a66b7b",
+		ExamID:    "EXAM-1",
+		StudentID: "ada",
+		Questions: []int{3, 7},
+		IssuedAt:  testNow.Unix(),
+		ExpiresAt: testNow.Add(time.Hour).Unix(),
+	}
+)
+
+func testSigner(t *testing.T, secret string) *Signer {
+	t.Helper()
+	signer, err := NewSigner([]byte(strings.Repeat(secret, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func TestSignAndVerify(t *testing.T) {
+	signer := testSigner(t, "k")
+	token, err := signer.Sign(testClaims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := signer.Verify(token, testNow)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !reflect.DeepEqual(*claims, testClaims) {
+		t.Errorf("claims = %+v, want %+v", *claims, testClaims)
+	}
+	if !claims.StartedAt().Equal(testNow) || !claims.Assigned(7) || claims.Assigned(4) {
+		t.Errorf("claims started %v with questions %v, want %v with 3 and 7", claims.StartedAt(), claims.Questions, testNow)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	signer := testSigner(t, "k")
+	token, err := signer.Sign(testClaims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, sig, _ := strings.Cut(token, ".")
+
+	// tampered swaps the student in the claims, keeping the signature
+	tamperedClaims := testClaims
+	tamperedClaims.StudentID = "eve"
+	tampered, err := signer.Sign(tamperedClaims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tamperedBody, _, _ := strings.Cut(tampered, ".")
+
+	// flipped changes the first byte of the signature
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac[0] ^= 1
+	flipped := base64.RawURLEncoding.EncodeToString(mac)
+
+	otherKey, err := testSigner(t, "o").Sign(testClaims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"tampered claims", tamperedBody + "." + sig},
+		{"tampered signature", body + "." + flipped},
+		{"other key", otherKey},
+		{"empty", ""},
+		{"one segment", body},
+		{"empty signature", body + "."},
+		{"extra segment", token + "." + sig},
+		{"not base64", body + ".!!!"},
+		{"claims not JSON", base64.RawURLEncoding.EncodeToString([]byte("not json")) + "." + sig},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if claims, err := signer.Verify(tc.token, testNow); !errors.Is(err, ErrInvalidToken) || claims != nil {
+				t.Errorf("Verify() = %+v, %v; want ErrInvalidToken", claims, err)
+			}
+		})
+	}
+}
+
+func TestVerifyExpired(t *testing.T) {
+	signer := testSigner(t, "k")
+	token, err := signer.Sign(testClaims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expiresAt := time.Unix(testClaims.ExpiresAt, 0)
+	if _, err := signer.Verify(token, expiresAt.Add(-time.Second)); err != nil {
+		t.Errorf("Verify() a second before expiry: error = %v", err)
+	}
+	for _, now := range []time.Time{expiresAt, expiresAt.Add(time.Hour)} {
+		if _, err := signer.Verify(token, now); !errors.Is(err, ErrExpired) {
+			t.Errorf("Verify() at %v: error = %v, want ErrExpired", now, err)
+		}
+	}
+}
+
+func TestNewSignerRejectsShortSecrets(t *testing.T) {
+	if _, err := NewSigner(make([]byte, 31)); err == nil {
+		t.Error("NewSigner() with a 31-byte secret: error = nil")
+	}
+}
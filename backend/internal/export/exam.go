@@ -0,0 +1,169 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"backend/internal/storage"
+)
+
+// PageSize is the number of submissions read from the database at a time
+// while exporting
+const PageSize = 100
+
+// ErrNoSubmissions is returned when an exam has no submissions to export
+var ErrNoSubmissions = errors.New("no submissions")
+
+// Exam reads the current submissions of an exam one page at a time,
+// ordered by student id. Each page starts after the last student of the
+// previous one, so submissions stored during an export do not shift the
+// pages.
+type Exam struct {
+	store  storage.Store
+	examID string
+	total  int
+	first  []*storage.Submission
+}
+
+// OpenExam reads the first page of the current submissions of an exam. It
+// returns ErrNoSubmissions if there are none, so callers can fail before
+// writing anything.
+func OpenExam(store storage.Store, examID string) (*Exam, error) {
+	e := &Exam{store: store, examID: examID}
+	subs, total, err := store.ListSubmissions(e.filter(""), e.page())
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("%w for exam %s", ErrNoSubmissions, examID)
+	}
+	e.first, e.total = subs, total
+	return e, nil
+}
+
+// Total returns the number of submissions of the exam when it was opened
+func (e *Exam) Total() int {
+	return e.total
+}
+
+// Each calls fn with every submission, holding only a page in memory
+func (e *Exam) Each(fn func(sub *storage.Submission) error) error {
+	return e.eachPage(func(subs []*storage.Submission) error {
+		for _, sub := range subs {
+			if err := fn(sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// eachPage calls fn with every page of submissions
+func (e *Exam) eachPage(fn func(subs []*storage.Submission) error) error {
+	subs := e.first
+	for len(subs) > 0 {
+		if err := fn(subs); err != nil {
+			return err
+		}
+		if len(subs) < PageSize {
+			break
+		}
+		var err error
+		if subs, _, err = e.store.ListSubmissions(e.filter(subs[len(subs)-1].StudentID), e.page()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MetadataKeys returns the metadata fields of the exam's submissions in
+// alphabetical order, leaving out studentName, which has a column of its
+// own
+func (e *Exam) MetadataKeys() ([]string, error) {
+	all, err := e.store.ListMetadataKeys(e.examID)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(all))
+	for _, key := range all {
+		if key != "studentName" {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Write writes every submission of the exam to w in format and returns the
+// number written. CSV exports include the current evaluator marks, read
+// once per page.
+func (e *Exam) Write(w io.Writer, format Format) (int, error) {
+	var writer Writer
+	var err error
+	// marks holds the current marks of the page being written
+	var marks map[string][]storage.QuestionMark
+	if format == FormatCSV {
+		var keys []string
+		if keys, err = e.MetadataKeys(); err != nil {
+			return 0, err
+		}
+		writer, err = NewCSVWriter(w, CSVOptions{MetadataKeys: keys, Marks: func(sub *storage.Submission) ([]storage.QuestionMark, error) {
+			return marks[sub.StudentID], nil
+		}})
+	} else {
+		writer, err = NewWriter(w, format)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	written := 0
+	err = e.eachPage(func(subs []*storage.Submission) error {
+		if format == FormatCSV {
+			studentIDs := make([]string, len(subs))
+			for i, sub := range subs {
+				studentIDs[i] = sub.StudentID
+			}
+			var err error
+			if marks, err = e.store.ListExamMarks(e.examID, studentIDs); err != nil {
+				return err
+			}
+		}
+		for _, sub := range subs {
+			if err := writer.Write(sub); err != nil {
+				return err
+			}
+			written++
+		}
+		return nil
+	})
+	if err != nil {
+		return written, err
+	}
+	return written, writer.Close()
+}
+
+// FileName names the export of an exam, keeping only letters, digits, '-'
+// and '.' of its id
+func FileName(examID string, format Format) string {
+	clean := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, examID)
+	return clean + "." + string(format)
+}
+
+// filter selects the submissions of the exam after a student, or from
+// the first one when after is empty
+func (e *Exam) filter(after string) storage.SubmissionFilter {
+	return storage.SubmissionFilter{ExamID: e.examID, StudentIDAfter: after}
+}
+
+// page selects a page of submissions in student id order
+func (e *Exam) page() storage.Page {
+	return storage.Page{Sort: storage.SortStudentID, Limit: PageSize}
+}
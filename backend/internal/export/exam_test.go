@@ -0,0 +1,114 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"testing"
+	"time"
+
+	"backend/internal/storage"
+)
+
+// saveStudent stores a submission of EXAM-1 for a student
+func saveStudent(t *testing.T, store storage.Store, studentID string, metadata map[string]string) {
+	t.Helper()
+	sub := &storage.Submission{
+		ExamID:         "EXAM-1",
+		StudentID:      studentID,
+		SubmissionTime: time.Now().UTC(),
+		Metadata:       metadata,
+		Questions:      []storage.Question{{Key: "q1", QuestionIndex: 1, FinalAnswer: "answer of " + studentID}},
+	}
+	if err := store.SaveSubmission(sub); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExamEachIgnoresNewSubmissions(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	const students = 2*PageSize + 5
+	for i := 0; i < students; i++ {
+		saveStudent(t, store, fmt.Sprintf("s%03d", i), map[string]string{"studentName": "Student"})
+	}
+
+	exam, err := OpenExam(store, "EXAM-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]int{}
+	err = exam.Each(func(sub *storage.Submission) error {
+		seen[sub.StudentID]++
+		// A student sorting before every page arrives during the export
+		if len(seen) == PageSize {
+			saveStudent(t, store, "a-late", map[string]string{"studentName": "Late"})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(seen) != students {
+		t.Errorf("exported %d students, want %d", len(seen), students)
+	}
+	for studentID, count := range seen {
+		if count != 1 {
+			t.Errorf("student %s exported %d times", studentID, count)
+		}
+	}
+}
+
+func TestExamWriteCSV(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	for i := 0; i < PageSize+1; i++ {
+		saveStudent(t, store, fmt.Sprintf("s%03d", i), map[string]string{"studentName": "Student", "group": "A"})
+	}
+	saveStudent(t, store, "s999", map[string]string{"studentName": "Student", "seat": "12"})
+	for _, studentID := range []string{"s000", fmt.Sprintf("s%03d", PageSize), "s999"} {
+		if err := store.SaveMark("EXAM-1", studentID, &storage.QuestionMark{QuestionKey: "q1", Mark: storage.MarkCopied, Evaluator: "eve"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exam, err := OpenExam(store, "EXAM-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	written, err := exam.Write(&buf, FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != PageSize+2 {
+		t.Errorf("written = %d, want %d", written, PageSize+2)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	column := map[string]int{}
+	for i, name := range records[0] {
+		column[name] = i
+	}
+	if _, ok := column["metadata_group"]; !ok {
+		t.Errorf("header %v lacks metadata_group", records[0])
+	}
+	if _, ok := column["metadata_seat"]; !ok {
+		t.Errorf("header %v lacks metadata_seat", records[0])
+	}
+	if _, ok := column["metadata_studentName"]; ok {
+		t.Errorf("header %v repeats studentName as metadata", records[0])
+	}
+
+	marked := map[string]bool{}
+	for _, record := range records[1:] {
+		if record[column["mark"]] != "" {
+			marked[record[column["student_id"]]] = true
+		}
+	}
+	if len(marked) != 3 || !marked["s000"] || !marked["s999"] {
+		t.Errorf("marked students = %v, want s000, s%03d and s999", marked, PageSize)
+	}
+}
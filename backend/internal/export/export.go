@@ -0,0 +1,262 @@
+// Package export writes submissions to files one at a time, so an exam of
+// any size is streamed: as a JSON array or NDJSON of payloads, in the
+// layout POST /submit accepts, or as CSV with one row per answer for
+// spreadsheets.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/analytics"
+	"backend/internal/storage"
+)
+
+// Format is an export file format
+type Format string
+
+// Export formats
+const (
+	FormatJSON   Format = "json"
+	FormatNDJSON Format = "ndjson"
+	FormatCSV    Format = "csv"
+)
+
+// Formats lists the export formats
+var Formats = []Format{FormatJSON, FormatNDJSON, FormatCSV}
+
+// Writer writes exported submissions
+type Writer interface {
+	// Write adds a submission to the export
+	Write(sub *storage.Submission) error
+	// Close completes the export without closing the underlying writer
+	Close() error
+}
+
+// ParseFormat checks the name of an export format
+func ParseFormat(name string) (Format, error) {
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		if string(f) == name {
+			return f, nil
+		}
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("unknown export format %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// NewWriter creates a writer of format to w
+func NewWriter(w io.Writer, format Format) (Writer, error) {
+	switch format {
+	case FormatJSON:
+		return &jsonWriter{w: w}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{w: w}, nil
+	case FormatCSV:
+		return NewCSVWriter(w, CSVOptions{})
+	}
+	_, err := ParseFormat(string(format))
+	return nil, err
+}
+
+// jsonWriter writes a JSON array of payloads, one per line
+type jsonWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (j *jsonWriter) Write(sub *storage.Submission) error {
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+	separator := ",\n"
+	if j.count == 0 {
+		separator = "[\n"
+	}
+	j.count++
+	if _, err := io.WriteString(j.w, separator); err != nil {
+		return err
+	}
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	closing := "\n]\n"
+	if j.count == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(j.w, closing)
+	return err
+}
+
+// ndjsonWriter writes one payload per line
+type ndjsonWriter struct {
+	w io.Writer
+}
+
+func (n *ndjsonWriter) Write(sub *storage.Submission) error {
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+	_, err = n.w.Write(append(data, '\n'))
+	return err
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+// CSVOptions sets the columns of a CSV export that depend on the exam
+type CSVOptions struct {
+	// MetadataKeys are the metadata fields given a column each, in order
+	MetadataKeys []string
+	// Marks returns the current evaluator marks of a submission; nil
+	// leaves the mark columns empty
+	Marks func(sub *storage.Submission) ([]storage.QuestionMark, error)
+}
+
+// csvWriter writes one row per answer, with its metadata, typing
+// statistics, evaluator mark and keystroke-dynamics features
+type csvWriter struct {
+	w    *csv.Writer
+	opts CSVOptions
+}
+
+// Columns returns the CSV header for the given metadata fields
+func Columns(metadataKeys []string) []string {
+	columns := []string{
+		"exam_id",
+		"student_id",
+		"student_name",
+	}
+	for _, key := range metadataKeys {
+		columns = append(columns, "metadata_"+key)
+	}
+	columns = append(columns,
+		"revision",
+		"received_at",
+		"submission_time",
+		"question_key",
+		"question_index",
+		"question_title",
+		"duration_ms",
+		"integrity_verdict",
+		"paste_count",
+		"pasted_chars",
+		"paste_share",
+		"backspace_count",
+		"selection_changes",
+		"longest_pause_ms",
+		"suspicion_score",
+		"suspicion_rules",
+		"mark",
+		"mark_comment",
+		"mark_evaluator",
+		"marked_at",
+		"final_answer",
+	)
+	return append(columns, analytics.Columns()...)
+}
+
+// NewCSVWriter creates a CSV writer to w and writes its header
+func NewCSVWriter(w io.Writer, opts CSVOptions) (Writer, error) {
+	c := &csvWriter{w: csv.NewWriter(w), opts: opts}
+	if err := c.w.Write(Columns(opts.MetadataKeys)); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *csvWriter) Write(sub *storage.Submission) error {
+	marks := map[string]storage.QuestionMark{}
+	if c.opts.Marks != nil {
+		list, err := c.opts.Marks(sub)
+		if err != nil {
+			return err
+		}
+		for _, mark := range list {
+			marks[mark.QuestionKey] = mark
+		}
+	}
+
+	for _, question := range sub.Questions {
+		record := []string{
+			text(sub.ExamID),
+			text(sub.StudentID),
+			text(sub.StudentName()),
+		}
+		for _, key := range c.opts.MetadataKeys {
+			record = append(record, text(sub.Metadata[key]))
+		}
+		mark := marks[question.Key]
+		record = append(record,
+			strconv.Itoa(sub.Revision),
+			formatTime(sub.ReceivedAt),
+			formatTime(sub.SubmissionTime),
+			text(question.Key),
+			strconv.Itoa(question.QuestionIndex),
+			text(question.QuestionTitle),
+			strconv.FormatFloat(question.EndTimeMs-question.StartTimeMs, 'f', 0, 64),
+			string(question.Integrity.Verdict),
+			strconv.Itoa(question.Stats.PasteCount),
+			strconv.Itoa(question.Stats.PastedChars),
+			strconv.FormatFloat(question.Stats.PasteShare, 'f', 3, 64),
+			strconv.Itoa(question.Stats.BackspaceCount),
+			strconv.Itoa(question.Stats.SelectionChanges),
+			strconv.FormatFloat(question.Stats.LongestPauseMs, 'f', 0, 64),
+			formatScore(question.Suspicion),
+			strings.Join(question.Suspicion.Rules, ","),
+			string(mark.Mark),
+			text(mark.Comment),
+			text(mark.Evaluator),
+			formatTime(mark.MarkedAt),
+			text(question.FinalAnswer),
+		)
+		record = append(record, analytics.Compute(question.EventLog).Record()...)
+		if err := c.w.Write(record); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// formatScore writes a suspicion score, or nothing when unscored
+func formatScore(suspicion storage.Suspicion) string {
+	if suspicion.Unscored {
+		return ""
+	}
+	return strconv.FormatFloat(suspicion.Score, 'f', -1, 64)
+}
+
+// text guards a cell holding text from the submission or an evaluator:
+// spreadsheets run cells starting with =, +, - or @ as formulas, and some
+// skip a leading tab or carriage return first, so such cells start with an
+// apostrophe instead
+func text(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// formatTime writes a timestamp as RFC 3339 in UTC, or nothing when unset
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
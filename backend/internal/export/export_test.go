@@ -0,0 +1,90 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"backend/internal/storage"
+)
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1+1", "'+1+1"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "'\r=1"},
+		{"", ""},
+		{"plain answer", "plain answer"},
+		{"a=1", "a=1"},
+		{" =1", " =1"},
+		{"'quoted", "'quoted"},
+	}
+
+	for _, tc := range tests {
+		if got := text(tc.in); got != tc.want {
+			t.Errorf("text(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestCSVWriterGuardsFormulas(t *testing.T) {
+	sub := &storage.Submission{
+		ExamID:    "EXAM-1",
+		StudentID: "-s1",
+		Metadata:  map[string]string{"studentName": "=cmd|' /C calc'!A0", "group": "@group"},
+		Questions: []storage.Question{{Key: "q1", QuestionIndex: 1, QuestionTitle: "+title", FinalAnswer: "=1+1"}},
+		Revision:  1,
+	}
+	mark := storage.QuestionMark{QuestionKey: "q1", Mark: storage.MarkOK, Comment: "-fine", Evaluator: "eve", MarkedAt: time.Now()}
+
+	var buf bytes.Buffer
+	w, err := NewCSVWriter(&buf, CSVOptions{
+		MetadataKeys: []string{"group"},
+		Marks: func(*storage.Submission) ([]storage.QuestionMark, error) {
+			return []storage.QuestionMark{mark}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(sub); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("records = %d, want a header and one row", len(records))
+	}
+	row := map[string]string{}
+	for i, column := range records[0] {
+		row[column] = records[1][i]
+	}
+
+	want := map[string]string{
+		"student_id":     "'-s1",
+		"student_name":   "'=cmd|' /C calc'!A0",
+		"metadata_group": "'@group",
+		"question_title": "'+title",
+		"final_answer":   "'=1+1",
+		"mark_comment":   "'-fine",
+		"mark_evaluator": "eve",
+		"exam_id":        "EXAM-1",
+	}
+	for column, value := range want {
+		if row[column] != value {
+			t.Errorf("%s = %q, want %q", column, row[column], value)
+		}
+	}
+}
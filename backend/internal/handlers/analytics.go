@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"backend/internal/analytics"
+)
+
+// QuestionAnalytics is the keystroke-dynamics features of one question of
+// a submission
+type QuestionAnalytics struct {
+	Key           string             `json:"key"`
+	QuestionIndex int                `json:"questionIndex"`
+	Features      analytics.Features `json:"features"`
+}
+
+// getAnalytics writes the typing features of every question of the current
+// revision of a submission
+func (h *SubmissionHandler) getAnalytics(w http.ResponseWriter, r *http.Request, examID, studentID string) {
+	submission, ok := h.loadSubmission(w, examID, studentID)
+	if !ok {
+		return
+	}
+
+	questions := make([]QuestionAnalytics, len(submission.Questions))
+	for i, question := range submission.Questions {
+		questions[i] = QuestionAnalytics{
+			Key:           question.Key,
+			QuestionIndex: question.QuestionIndex,
+			Features:      analytics.Compute(question.EventLog),
+		}
+	}
+
+	body, err := json.Marshal(questions)
+	if err != nil {
+		log.Printf("Error encoding analytics: %v", err)
+		http.Error(w, "Failed to encode analytics", http.StatusInternalServerError)
+		return
+	}
+	writeCachedJSON(w, r, body)
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/middleware"
+	"backend/internal/storage"
+)
+
+// maxLoginBody limits the size of a POST /auth/login request
+const maxLoginBody = 4 << 10
+
+// AuthHandler handles signing in and out
+type AuthHandler struct {
+	storage storage.Store
+	cfg     *config.AuthConfig
+}
+
+// NewAuthHandler creates a new authentication handler
+func NewAuthHandler(storage storage.Store, cfg *config.AuthConfig) *AuthHandler {
+	return &AuthHandler{storage: storage, cfg: cfg}
+}
+
+// LoginRequest is the body of POST /auth/login
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginResponse describes a new session. The token is also set as the
+// session cookie; API clients send it as "Authorization: Bearer <token>".
+type LoginResponse struct {
+	Token     string       `json:"token"`
+	ExpiresAt time.Time    `json:"expiresAt"`
+	User      storage.User `json:"user"`
+}
+
+// HandleLogin handles POST /auth/login requests
+func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	// Only accept POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request LoginRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxLoginBody)).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.storage.GetUser(request.Username)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("Error retrieving user: %v", err)
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		return
+	}
+	if !middleware.CheckPassword(user, request.Password) {
+		log.Printf("⚠️  Failed login: user=%s, ip=%s", request.Username, clientIP(r))
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return
+	}
+
+	token, err := middleware.NewToken()
+	if err != nil {
+		log.Printf("Error creating session: %v", err)
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		return
+	}
+	session := storage.Session{
+		TokenHash: middleware.HashToken(token),
+		User:      *user,
+		ExpiresAt: time.Now().Add(h.cfg.SessionTTL).UTC(),
+	}
+	if err := h.storage.CreateSession(&session); err != nil {
+		log.Printf("Error creating session: %v", err)
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, h.cookie(token, session.ExpiresAt))
+	log.Printf("🔑 Signed in: user=%s (%s)", user.Username, user.Role)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(LoginResponse{Token: token, ExpiresAt: session.ExpiresAt, User: *user})
+}
+
+// HandleLogout handles POST /auth/logout requests, ending the session of
+// the request's token
+func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	// Only accept POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if token := middleware.Token(r); token != "" {
+		if err := h.storage.DeleteSession(middleware.HashToken(token)); err != nil {
+			log.Printf("Error ending session: %v", err)
+			http.Error(w, "Failed to sign out", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Expire the cookie in the browser as well
+	cookie := h.cookie("", time.Unix(0, 0))
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleMe handles GET /auth/me requests, describing the signed-in user
+func (h *AuthHandler) HandleMe(w http.ResponseWriter, r *http.Request) {
+	// Only accept GET requests
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := middleware.UserFrom(r.Context())
+	if !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+// cookie builds the session cookie carrying token
+func (h *AuthHandler) cookie(token string, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     middleware.SessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   h.cfg.SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+
+	"backend/internal/eventlog"
+	"backend/internal/storage"
+)
+
+// maxChunkBody limits the size of one autosaved chunk
+const maxChunkBody = 256 << 10
+
+// ChunkRequest is the body of POST /exam-sessions/{sessionId}/chunks: the
+// events typed in a question since the previous chunk, in the eventLog
+// format of POST /submit
+type ChunkRequest struct {
+	QuestionIndex *int         `json:"questionIndex"`
+	Seq           int          `json:"seq"`
+	Events        eventlog.Log `json:"events"`
+}
+
+// ChunkResponse acknowledges a stored chunk. Duplicate is set when the
+// chunk had already been stored by an earlier attempt.
+type ChunkResponse struct {
+	QuestionIndex int  `json:"questionIndex"`
+	Seq           int  `json:"seq"`
+	NextSeq       int  `json:"nextSeq"`
+	Duplicate     bool `json:"duplicate"`
+}
+
+// AutosaveResponse lists what was autosaved for each question of an exam
+// session
+type AutosaveResponse struct {
+	SessionID string              `json:"sessionId"`
+	Questions []AutosavedQuestion `json:"questions"`
+}
+
+// AutosavedQuestion is the state of a question rebuilt from its chunks,
+// from which the student continues typing
+type AutosavedQuestion struct {
+	QuestionIndex int `json:"questionIndex"`
+	// NextSeq is the Seq the next chunk of the question must have
+	NextSeq    int `json:"nextSeq"`
+	EventCount int `json:"eventCount"`
+	// Text is the replayed answer and SelectionStart/SelectionEnd the
+	// cursor or selection after the last event, in characters
+	Text           string  `json:"text"`
+	SelectionStart int     `json:"selectionStart"`
+	SelectionEnd   int     `json:"selectionEnd"`
+	DurationMs     float64 `json:"durationMs"`
+}
+
+// saveChunk stores the next chunk of a question's event log. Chunks must
+// arrive in Seq order; sending a stored chunk again is acknowledged
+// without storing it twice, so clients can retry until they get an answer.
+// Sessions stop taking chunks once an answer was submitted in them.
+func (h *ExamSessionHandler) saveChunk(w http.ResponseWriter, r *http.Request, id string) {
+	claims, sessionErr := h.sessionClaims(r, id)
+	if sessionErr != nil {
+		http.Error(w, sessionErr.Error(), sessionErr.Status)
+		return
+	}
+
+	var request ChunkRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxChunkBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.QuestionIndex == nil {
+		http.Error(w, "questionIndex is required", http.StatusBadRequest)
+		return
+	}
+	if request.Seq < 1 {
+		http.Error(w, "seq must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	if len(request.Events) == 0 {
+		http.Error(w, "events must not be empty", http.StatusBadRequest)
+		return
+	}
+	if !claims.Assigned(*request.QuestionIndex) {
+		http.Error(w, fmt.Sprintf("question %d was not assigned in the exam session", *request.QuestionIndex), http.StatusForbidden)
+		return
+	}
+	submitted, err := h.storage.ExamSessionSubmitted(id)
+	if err != nil {
+		log.Printf("Error checking exam session: %v", err)
+		http.Error(w, "Failed to save chunk", http.StatusInternalServerError)
+		return
+	}
+	if submitted {
+		http.Error(w, "exam session was already submitted", http.StatusConflict)
+		return
+	}
+
+	chunk := storage.Chunk{
+		SessionID:     id,
+		QuestionIndex: *request.QuestionIndex,
+		Seq:           request.Seq,
+		Events:        request.Events,
+	}
+	duplicate, err := h.appendChunk(&chunk)
+	var conflict *chunkConflict
+	if errors.As(err, &conflict) {
+		http.Error(w, conflict.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error saving chunk: %v", err)
+		http.Error(w, "Failed to save chunk", http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if !duplicate {
+		status = http.StatusCreated
+		h.hub.Chunk(claims.ExamID, claims.StudentID, &chunk)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ChunkResponse{
+		QuestionIndex: chunk.QuestionIndex,
+		Seq:           chunk.Seq,
+		NextSeq:       chunk.Seq + 1,
+		Duplicate:     duplicate,
+	})
+}
+
+// chunkConflict rejects a chunk that is out of order or differs from the
+// stored chunk with its Seq
+type chunkConflict struct {
+	Message string
+}
+
+func (e *chunkConflict) Error() string {
+	return e.Message
+}
+
+// appendChunk stores chunk when it is the next of its question, reporting
+// whether an identical chunk was already stored. Only the question's last
+// chunk is read; retries racing each other are told apart by SaveChunk
+// refusing a Seq that is already stored.
+func (h *ExamSessionHandler) appendChunk(chunk *storage.Chunk) (duplicate bool, err error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		last, err := h.storage.LastChunk(chunk.SessionID, chunk.QuestionIndex)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return false, err
+		}
+
+		next := 1
+		if last != nil {
+			next = last.Seq + 1
+		}
+		if last != nil && last.Seq == chunk.Seq {
+			same, err := sameEvents(last.Events, chunk.Events)
+			if err != nil {
+				return false, err
+			}
+			if !same {
+				return false, &chunkConflict{Message: fmt.Sprintf("chunk %d of question %d was already saved with other events", chunk.Seq, chunk.QuestionIndex)}
+			}
+			return true, nil
+		}
+		if chunk.Seq != next {
+			return false, &chunkConflict{Message: fmt.Sprintf("chunk %d of question %d is out of order: expected chunk %d", chunk.Seq, chunk.QuestionIndex, next)}
+		}
+
+		err = h.storage.SaveChunk(chunk)
+		if !errors.Is(err, storage.ErrExists) {
+			return false, err
+		}
+		// A retry of the same chunk was stored meanwhile; compare with it
+	}
+	return false, fmt.Errorf("chunk %d of question %d: %w", chunk.Seq, chunk.QuestionIndex, storage.ErrExists)
+}
+
+// sameEvents reports whether two event logs encode identically
+func sameEvents(a, b eventlog.Log) (bool, error) {
+	encodedA, err := json.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+	encodedB, err := json.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(encodedA, encodedB), nil
+}
+
+// getAutosave rebuilds every question of an exam session from its chunks,
+// so a student who reloads the page continues where they stopped
+func (h *ExamSessionHandler) getAutosave(w http.ResponseWriter, r *http.Request, id string) {
+	if _, sessionErr := h.sessionClaims(r, id); sessionErr != nil {
+		http.Error(w, sessionErr.Error(), sessionErr.Status)
+		return
+	}
+
+	session, err := h.storage.GetExamSession(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Exam session not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error retrieving exam session: %v", err)
+		http.Error(w, "Failed to retrieve autosave", http.StatusInternalServerError)
+		return
+	}
+	chunks, err := h.storage.ListChunks(id)
+	if err != nil {
+		log.Printf("Error retrieving chunks: %v", err)
+		http.Error(w, "Failed to retrieve autosave", http.StatusInternalServerError)
+		return
+	}
+
+	response := AutosaveResponse{SessionID: session.ID, Questions: make([]AutosavedQuestion, len(session.Questions))}
+	for i, questionIndex := range session.Questions {
+		events, count := autosavedEvents(chunks, questionIndex)
+		buf := eventlog.ReplayUntil(events, math.Inf(1))
+		start, end := buf.Selection()
+		response.Questions[i] = AutosavedQuestion{
+			QuestionIndex:  questionIndex,
+			NextSeq:        count + 1,
+			EventCount:     len(events),
+			Text:           buf.Text(),
+			SelectionStart: start,
+			SelectionEnd:   end,
+			DurationMs:     eventlog.Duration(events),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// autosavedEvents joins the events of a question's chunks, which must be
+// in Seq order, and counts the chunks
+func autosavedEvents(chunks []storage.Chunk, questionIndex int) (eventlog.Log, int) {
+	var events eventlog.Log
+	count := 0
+	for _, chunk := range chunks {
+		if chunk.QuestionIndex == questionIndex {
+			events = append(events, chunk.Events...)
+			count++
+		}
+	}
+	return events, count
+}
@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/export"
+	"backend/internal/similarity"
+	"backend/internal/storage"
+)
+
+// maxExamBody limits the size of an exam definition request
+const maxExamBody = 16 << 10
+
+// ExamsHandler handles exam definitions
+type ExamsHandler struct {
+	storage storage.Store
+}
+
+// NewExamsHandler creates a new exams handler
+func NewExamsHandler(storage storage.Store) *ExamsHandler {
+	return &ExamsHandler{storage: storage}
+}
+
+// ExamRequest is the body of PUT /exams/{examId}. Omitted limits mean no
+// limit; omitted questionIds mean the whole question bank.
+type ExamRequest struct {
+	Title           string              `json:"title"`
+	QuestionIDs     []int               `json:"questionIds"`
+	OpensAt         *time.Time          `json:"opensAt"`
+	ClosesAt        *time.Time          `json:"closesAt"`
+	DurationSeconds int                 `json:"durationSeconds"`
+	MaxAttempts     int                 `json:"maxAttempts"`
+	Enforcement     storage.Enforcement `json:"enforcement"`
+}
+
+// ServeHTTP routes the exam endpoints:
+//
+//	GET    /exams
+//	GET    /exams/{examId}
+//	PUT    /exams/{examId}
+//	DELETE /exams/{examId}
+//	GET    /exams/{examId}/similarity
+//	GET    /exams/{examId}/export.csv
+func (h *ExamsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/exams" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.listExams(w)
+		return
+	}
+
+	parts, ok := pathSegments(r, "/exams/")
+	if ok && len(parts) == 2 && parts[0] != "" && parts[1] == "similarity" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.getSimilarity(w, r, parts[0])
+		return
+	}
+	if ok && len(parts) == 2 && parts[0] != "" && parts[1] == "export.csv" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.getExport(w, r, parts[0])
+		return
+	}
+	if !ok || len(parts) != 1 || parts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		h.getExam(w, parts[0])
+	case http.MethodPut:
+		h.putExam(w, r, parts[0])
+	case http.MethodDelete:
+		h.deleteExam(w, r, parts[0])
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// listExams writes every exam
+func (h *ExamsHandler) listExams(w http.ResponseWriter) {
+	exams, err := h.storage.ListExams()
+	if err != nil {
+		log.Printf("Error retrieving exams: %v", err)
+		http.Error(w, "Failed to retrieve exams", http.StatusInternalServerError)
+		return
+	}
+	if exams == nil {
+		exams = []storage.Exam{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(exams)
+}
+
+// getExam writes one exam
+func (h *ExamsHandler) getExam(w http.ResponseWriter, id string) {
+	exam, err := h.storage.GetExam(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Exam not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error retrieving exam: %v", err)
+		http.Error(w, "Failed to retrieve exam", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(exam)
+}
+
+// putExam creates or replaces an exam definition
+func (h *ExamsHandler) putExam(w http.ResponseWriter, r *http.Request, id string) {
+	if len(id) > maxExamIDLength {
+		http.Error(w, fmt.Sprintf("examId must be at most %d bytes", maxExamIDLength), http.StatusBadRequest)
+		return
+	}
+
+	var request ExamRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxExamBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.validateExam(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	exam := storage.Exam{
+		ID:              id,
+		Title:           strings.TrimSpace(request.Title),
+		QuestionIDs:     request.QuestionIDs,
+		OpensAt:         request.OpensAt,
+		ClosesAt:        request.ClosesAt,
+		DurationSeconds: request.DurationSeconds,
+		MaxAttempts:     request.MaxAttempts,
+		Enforcement:     request.Enforcement,
+	}
+	if err := h.storage.SaveExam(&exam); err != nil {
+		log.Printf("Error saving exam: %v", err)
+		http.Error(w, "Failed to save exam", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("🗓️  Saved exam: %s by %s", exam.ID, actor(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(exam)
+}
+
+// validateExam checks an exam definition, defaulting its enforcement to
+// flag
+func (h *ExamsHandler) validateExam(request *ExamRequest) error {
+	if request.Enforcement == "" {
+		request.Enforcement = storage.EnforceFlag
+	}
+	if !request.Enforcement.Valid() {
+		return fmt.Errorf("enforcement must be one of %s, %s", storage.EnforceFlag, storage.EnforceReject)
+	}
+	if request.OpensAt != nil && request.ClosesAt != nil && !request.ClosesAt.After(*request.OpensAt) {
+		return errors.New("closesAt must be after opensAt")
+	}
+	if request.DurationSeconds < 0 {
+		return errors.New("durationSeconds must not be negative")
+	}
+	if request.MaxAttempts < 0 {
+		return errors.New("maxAttempts must not be negative")
+	}
+
+	seen := make(map[int]bool, len(request.QuestionIDs))
+	for _, id := range request.QuestionIDs {
+		if seen[id] {
+			return fmt.Errorf("questionIds: duplicate question %d", id)
+		}
+		seen[id] = true
+
+		_, err := h.storage.GetQuestion(id, 0)
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("questionIds: no question %d in the question bank", id)
+		}
+		if err != nil {
+			return fmt.Errorf("questionIds: %w", err)
+		}
+	}
+	return nil
+}
+
+// deleteExam removes an exam nobody has submitted to
+func (h *ExamsHandler) deleteExam(w http.ResponseWriter, r *http.Request, id string) {
+	err := h.storage.DeleteExam(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Exam not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, storage.ErrInUse) {
+		http.Error(w, "Exam has submissions", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting exam: %v", err)
+		http.Error(w, "Failed to delete exam", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("🗓️  Deleted exam: %s by %s", id, actor(r))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getSimilarity compares the current submissions of an exam and writes
+// the pairs of students whose answers or pastes look alike, optionally
+// filtered by ?threshold= and cut to the ?limit= highest scores
+func (h *ExamsHandler) getSimilarity(w http.ResponseWriter, r *http.Request, id string) {
+	opts := similarity.DefaultOptions()
+	query := r.URL.Query()
+	if value := query.Get("threshold"); value != "" {
+		threshold, err := strconv.ParseFloat(value, 64)
+		if err != nil || threshold <= 0 || threshold > 1 {
+			http.Error(w, "threshold must be a number above 0 and at most 1", http.StatusBadRequest)
+			return
+		}
+		opts.Threshold = threshold
+	}
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxPageLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxPageLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	subs, _, err := h.storage.ListSubmissions(storage.SubmissionFilter{ExamID: id}, storage.Page{})
+	if err != nil {
+		log.Printf("Error retrieving submissions: %v", err)
+		http.Error(w, "Failed to retrieve submissions", http.StatusInternalServerError)
+		return
+	}
+	if len(subs) == 0 {
+		http.Error(w, "No submissions for exam", http.StatusNotFound)
+		return
+	}
+
+	report := similarity.Analyze(id, subs, opts)
+	if limit > 0 && len(report.Pairs) > limit {
+		report.Pairs = report.Pairs[:limit]
+	}
+
+	log.Printf("🔍 Compared %d submissions of exam %s: %d suspicious pairs by %s", report.Submissions, id, len(report.Pairs), actor(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// getExport streams the current submissions of an exam as CSV, one row per
+// answer, one page of submissions at a time. Every write gets its own
+// deadline, so large exams are not cut off by the server's write timeout.
+func (h *ExamsHandler) getExport(w http.ResponseWriter, r *http.Request, id string) {
+	exam, err := export.OpenExam(h.storage, id)
+	if errors.Is(err, export.ErrNoSubmissions) {
+		http.Error(w, "No submissions for exam", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error retrieving submissions: %v", err)
+		http.Error(w, "Failed to retrieve submissions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+export.FileName(id, export.FormatCSV)+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	out := bufio.NewWriter(&deadlineWriter{w: w, rc: http.NewResponseController(w)})
+	exported, err := exam.Write(out, export.FormatCSV)
+	if err == nil {
+		err = out.Flush()
+	}
+	if err != nil {
+		// The status is sent; the client sees a truncated file
+		log.Printf("Error exporting exam %s after %d submissions: %v", id, exported, err)
+		return
+	}
+	log.Printf("📤 Exported %d submissions of exam %s as CSV for %s", exported, id, actor(r))
+}
+
+// exportWriteTimeout is how long one write of an export may take
+const exportWriteTimeout = 15 * time.Second
+
+// deadlineWriter extends the write deadline of a response before every
+// write
+type deadlineWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	d.rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	return d.w.Write(p)
+}
+
+// timingFlagNames lists timing flags for messages
+func timingFlagNames(flags []storage.TimingFlag) string {
+	names := make([]string, len(flags))
+	for i, flag := range flags {
+		names[i] = string(flag)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,382 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/examsession"
+	"backend/internal/middleware"
+	"backend/internal/proctor"
+	"backend/internal/questionbank"
+	"backend/internal/storage"
+)
+
+// ExamSessionHeader carries the exam session token of a submission
+const ExamSessionHeader = "X-Exam-Session"
+
+// maxExamIDLength limits the exam IDs sessions can be started for
+const maxExamIDLength = 128
+
+// ExamQuestion is a question assigned to a student
+type ExamQuestion struct {
+	QuestionIndex   int    `json:"questionIndex"`
+	QuestionVersion int    `json:"questionVersion"`
+	QuestionTitle   string `json:"questionTitle"`
+	Question        string `json:"question"`
+}
+
+// ExamSessionHandler starts exam sessions and serves their questions
+type ExamSessionHandler struct {
+	storage storage.Store
+	signer  *examsession.Signer
+	cfg     *config.ExamConfig
+	hub     *proctor.Hub
+}
+
+// NewExamSessionHandler creates a handler assigning questions from the
+// question bank to the sessions it starts and announcing the sessions
+// and their autosaves to proctors through hub
+func NewExamSessionHandler(storage storage.Store, signer *examsession.Signer, cfg *config.ExamConfig, hub *proctor.Hub) *ExamSessionHandler {
+	return &ExamSessionHandler{storage: storage, signer: signer, cfg: cfg, hub: hub}
+}
+
+// StartRequest is the body of POST /exam-sessions
+type StartRequest struct {
+	ExamID string `json:"examId"`
+}
+
+// ExamSessionResponse describes an exam session and its questions. The
+// token, returned only when the session starts, must be sent in the
+// X-Exam-Session header of POST /submit.
+type ExamSessionResponse struct {
+	Token     string    `json:"token,omitempty"`
+	SessionID string    `json:"sessionId"`
+	ExamID    string    `json:"examId"`
+	StudentID string    `json:"studentId"`
+	StartedAt time.Time `json:"startedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// Deadline is when the exam's window or duration limit ends, if it
+	// has one
+	Deadline  *time.Time     `json:"deadline,omitempty"`
+	Questions []ExamQuestion `json:"questions"`
+}
+
+// ServeHTTP routes the exam session endpoints:
+//
+//	POST /exam-sessions
+//	GET  /exam-sessions/{sessionId}
+//	POST /exam-sessions/{sessionId}/chunks
+//	GET  /exam-sessions/{sessionId}/autosave
+func (h *ExamSessionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/exam-sessions" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.startSession(w, r)
+		return
+	}
+
+	parts, ok := pathSegments(r, "/exam-sessions/")
+	if !ok || len(parts) == 0 || parts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		h.getSession(w, r, parts[0])
+	case len(parts) == 2 && parts[1] == "chunks" && r.Method == http.MethodPost:
+		h.saveChunk(w, r, parts[0])
+	case len(parts) == 2 && parts[1] == "autosave" && r.Method == http.MethodGet:
+		h.getAutosave(w, r, parts[0])
+	case len(parts) == 1 || (len(parts) == 2 && (parts[1] == "chunks" || parts[1] == "autosave")):
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// startSession registers a student for an exam, assigns questions from
+// the question bank and returns the signed session token
+func (h *ExamSessionHandler) startSession(w http.ResponseWriter, r *http.Request) {
+	var request StartRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	request.ExamID = strings.TrimSpace(request.ExamID)
+	if request.ExamID == "" || len(request.ExamID) > maxExamIDLength {
+		http.Error(w, fmt.Sprintf("examId must be a non-empty string of at most %d bytes", maxExamIDLength), http.StatusBadRequest)
+		return
+	}
+
+	sessionID, err := examsession.NewSessionID()
+	if err != nil {
+		log.Printf("Error starting exam session: %v", err)
+		http.Error(w, "Failed to start exam session", http.StatusInternalServerError)
+		return
+	}
+
+	// Signed-in students keep their username; anyone else gets a fresh ID,
+	// so only signed-in students are held to attempts and duration across
+	// sessions
+	studentID := sessionID
+	signedIn := false
+	if user, ok := middleware.UserFrom(r.Context()); ok && user.Role == storage.RoleStudent {
+		studentID = user.Username
+		signedIn = true
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	exam, err := h.exam(request.ExamID)
+	if err != nil {
+		log.Printf("Error retrieving exam: %v", err)
+		http.Error(w, "Failed to start exam session", http.StatusInternalServerError)
+		return
+	}
+	if !exam.Open(now) && exam.Enforcement == storage.EnforceReject {
+		http.Error(w, "Exam is not open", http.StatusForbidden)
+		return
+	}
+
+	startedAt := now
+	if signedIn {
+		first, attempts, err := h.attempts(exam, studentID)
+		if err != nil {
+			log.Printf("Error checking exam attempts: %v", err)
+			http.Error(w, "Failed to start exam session", http.StatusInternalServerError)
+			return
+		}
+		if first != nil {
+			startedAt = first.StartedAt
+		}
+		if exam.Enforcement == storage.EnforceReject {
+			if exam.MaxAttempts > 0 && attempts >= exam.MaxAttempts {
+				http.Error(w, "No attempts left for this exam", http.StatusForbidden)
+				return
+			}
+			if exam.DurationSeconds > 0 && !now.Before(startedAt.Add(exam.Duration())) {
+				http.Error(w, "Exam time is up", http.StatusForbidden)
+				return
+			}
+		}
+	}
+
+	bank, err := h.storage.ListQuestions()
+	if err != nil {
+		log.Printf("Error retrieving questions: %v", err)
+		http.Error(w, "Failed to start exam session", http.StatusInternalServerError)
+		return
+	}
+	assigned := questionbank.Assign(questionbank.Mode(h.cfg.Assignment), examQuestions(exam, bank), h.cfg.QuestionsPerSession, request.ExamID, studentID)
+	if len(assigned) == 0 {
+		log.Printf("⚠️  No active questions for exam %s in the question bank", request.ExamID)
+		http.Error(w, "No questions available", http.StatusServiceUnavailable)
+		return
+	}
+
+	session := storage.ExamSession{
+		ID:        sessionID,
+		ExamID:    request.ExamID,
+		StudentID: studentID,
+		StartedAt: now,
+		ExpiresAt: now.Add(h.cfg.SessionTTL),
+		ClientIP:  clientIP(r),
+	}
+	questions := make([]ExamQuestion, len(assigned))
+	for i, q := range assigned {
+		session.Questions = append(session.Questions, q.ID)
+		session.Versions = append(session.Versions, q.Version)
+		questions[i] = examQuestion(q)
+	}
+	if err := h.storage.CreateExamSession(&session); err != nil {
+		log.Printf("Error saving exam session: %v", err)
+		http.Error(w, "Failed to start exam session", http.StatusInternalServerError)
+		return
+	}
+
+	token, err := h.signer.Sign(examsession.Claims{
+		SessionID: session.ID,
+		ExamID:    session.ExamID,
+		StudentID: session.StudentID,
+		Questions: session.Questions,
+		IssuedAt:  session.StartedAt.Unix(),
+		ExpiresAt: session.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		log.Printf("Error signing exam session: %v", err)
+		http.Error(w, "Failed to start exam session", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("🎫 Exam session started: exam=%s, student=%s, questions=%v",
+		session.ExamID, session.StudentID, session.Questions)
+	h.hub.Started(&session)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ExamSessionResponse{
+		Token:     token,
+		SessionID: session.ID,
+		ExamID:    session.ExamID,
+		StudentID: session.StudentID,
+		StartedAt: session.StartedAt,
+		ExpiresAt: session.ExpiresAt,
+		Deadline:  exam.Deadline(startedAt),
+		Questions: questions,
+	})
+}
+
+// exam returns the definition of an exam, or one without limits when
+// the exam is not defined
+func (h *ExamSessionHandler) exam(id string) (*storage.Exam, error) {
+	exam, err := h.storage.GetExam(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return &storage.Exam{ID: id, Enforcement: storage.EnforceFlag}, nil
+	}
+	return exam, err
+}
+
+// attempts returns a student's first session of an exam, nil before the
+// first, and the number of answers the student has submitted to it
+func (h *ExamSessionHandler) attempts(exam *storage.Exam, studentID string) (*storage.ExamSession, int, error) {
+	first, err := h.storage.FirstExamSession(exam.ID, studentID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	revisions, err := h.storage.ListRevisions(exam.ID, studentID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, 0, err
+	}
+	return first, len(revisions), nil
+}
+
+// examQuestions narrows the question bank to the questions of an exam
+func examQuestions(exam *storage.Exam, bank []storage.BankQuestion) []storage.BankQuestion {
+	if len(exam.QuestionIDs) == 0 {
+		return bank
+	}
+	included := make(map[int]bool, len(exam.QuestionIDs))
+	for _, id := range exam.QuestionIDs {
+		included[id] = true
+	}
+	var questions []storage.BankQuestion
+	for _, q := range bank {
+		if included[q.ID] {
+			questions = append(questions, q)
+		}
+	}
+	return questions
+}
+
+// getSession returns the questions assigned in an exam session to the
+// holder of its token, in the versions first served
+func (h *ExamSessionHandler) getSession(w http.ResponseWriter, r *http.Request, id string) {
+	if _, sessionErr := h.sessionClaims(r, id); sessionErr != nil {
+		http.Error(w, sessionErr.Error(), sessionErr.Status)
+		return
+	}
+
+	session, err := h.storage.GetExamSession(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Exam session not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error retrieving exam session: %v", err)
+		http.Error(w, "Failed to retrieve exam session", http.StatusInternalServerError)
+		return
+	}
+
+	questions := make([]ExamQuestion, len(session.Questions))
+	for i, questionID := range session.Questions {
+		// Sessions started before the question bank have no versions
+		version := 0
+		if i < len(session.Versions) {
+			version = session.Versions[i]
+		}
+		q, err := h.storage.GetQuestion(questionID, version)
+		if err != nil {
+			log.Printf("Error retrieving question %d of exam session %s: %v", questionID, id, err)
+			http.Error(w, "Failed to retrieve exam session", http.StatusInternalServerError)
+			return
+		}
+		questions[i] = examQuestion(*q)
+	}
+
+	exam, err := h.exam(session.ExamID)
+	if err != nil {
+		log.Printf("Error retrieving exam: %v", err)
+		http.Error(w, "Failed to retrieve exam session", http.StatusInternalServerError)
+		return
+	}
+	// The duration runs from the student's first session
+	first, err := h.storage.FirstExamSession(session.ExamID, session.StudentID)
+	if err != nil {
+		log.Printf("Error retrieving first exam session: %v", err)
+		http.Error(w, "Failed to retrieve exam session", http.StatusInternalServerError)
+		return
+	}
+	startedAt := session.StartedAt
+	if first.StartedAt.Before(startedAt) {
+		startedAt = first.StartedAt
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ExamSessionResponse{
+		SessionID: session.ID,
+		ExamID:    session.ExamID,
+		StudentID: session.StudentID,
+		StartedAt: session.StartedAt,
+		ExpiresAt: session.ExpiresAt,
+		Deadline:  exam.Deadline(startedAt),
+		Questions: questions,
+	})
+}
+
+// sessionClaims verifies the exam session token of a request for the
+// session id
+func (h *ExamSessionHandler) sessionClaims(r *http.Request, id string) (*examsession.Claims, *SessionError) {
+	claims, sessionErr := verifyToken(h.signer, r.Header.Get(ExamSessionHeader))
+	if sessionErr != nil {
+		return nil, sessionErr
+	}
+	if claims.SessionID != id {
+		return nil, &SessionError{Status: http.StatusForbidden, Message: "exam session token is for another session"}
+	}
+	return claims, nil
+}
+
+// examQuestion is the student's view of a bank question
+func examQuestion(q storage.BankQuestion) ExamQuestion {
+	return ExamQuestion{QuestionIndex: q.ID, QuestionVersion: q.Version, QuestionTitle: q.Title, Question: q.Text}
+}
+
+// verifyToken checks the signature and expiry of an exam session token
+func verifyToken(signer *examsession.Signer, token string) (*examsession.Claims, *SessionError) {
+	if token == "" {
+		return nil, &SessionError{Status: http.StatusUnauthorized, Message: "exam session token required (start one with POST /exam-sessions)"}
+	}
+
+	claims, err := signer.Verify(token, time.Now())
+	if errors.Is(err, examsession.ErrExpired) {
+		return nil, &SessionError{Status: http.StatusForbidden, Message: "exam session expired"}
+	}
+	if err != nil {
+		return nil, &SessionError{Status: http.StatusForbidden, Message: err.Error()}
+	}
+	return claims, nil
+}
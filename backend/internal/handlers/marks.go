@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"backend/internal/middleware"
+	"backend/internal/storage"
+)
+
+// Limits of a PUT .../mark request
+const (
+	maxMarkBody    = 64 << 10
+	maxCommentSize = 4000
+)
+
+// MarkRequest is the body of PUT /submissions/{examId}/{studentId}/questions/{questionKey}/mark
+type MarkRequest struct {
+	Mark      string `json:"mark"`
+	Comment   string `json:"comment"`
+	Evaluator string `json:"evaluator"`
+}
+
+// listMarks writes the current mark of every marked question
+func (h *SubmissionHandler) listMarks(w http.ResponseWriter, examID, studentID string) {
+	marks, ok := h.loadMarks(w, examID, studentID)
+	if !ok {
+		return
+	}
+	if marks == nil {
+		marks = []storage.QuestionMark{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(marks)
+}
+
+// getMark writes the current mark of one question
+func (h *SubmissionHandler) getMark(w http.ResponseWriter, examID, studentID, key string) {
+	marks, ok := h.loadMarks(w, examID, studentID)
+	if !ok {
+		return
+	}
+
+	for _, mark := range marks {
+		if mark.QuestionKey == key {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(mark)
+			return
+		}
+	}
+	http.Error(w, "Mark not found", http.StatusNotFound)
+}
+
+// putMark records a new current mark for one question
+func (h *SubmissionHandler) putMark(w http.ResponseWriter, r *http.Request, examID, studentID, key string) {
+	var request MarkRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxMarkBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mark := storage.QuestionMark{
+		QuestionKey: key,
+		Mark:        storage.Mark(strings.ToUpper(strings.TrimSpace(request.Mark))),
+		Comment:     strings.TrimSpace(request.Comment),
+		Evaluator:   strings.TrimSpace(request.Evaluator),
+	}
+	// A signed-in evaluator marks under their own name
+	if user, ok := middleware.UserFrom(r.Context()); ok {
+		mark.Evaluator = user.Username
+	}
+	if err := validateMark(&mark); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := h.storage.SaveMark(examID, studentID, &mark)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Question not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error saving mark: %v", err)
+		http.Error(w, "Failed to save mark", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("📝 Marked %s: exam=%s, student=%s, question=%s, evaluator=%s",
+		mark.Mark, examID, studentID, key, mark.Evaluator)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(mark)
+}
+
+// markHistory writes every mark given to one question, oldest first
+func (h *SubmissionHandler) markHistory(w http.ResponseWriter, examID, studentID, key string) {
+	if _, ok := h.loadMarks(w, examID, studentID); !ok {
+		return
+	}
+
+	history, err := h.storage.MarkHistory(examID, studentID, key)
+	if err != nil {
+		log.Printf("Error retrieving mark history: %v", err)
+		http.Error(w, "Failed to retrieve mark history", http.StatusInternalServerError)
+		return
+	}
+	if history == nil {
+		history = []storage.QuestionMark{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
+
+// loadMarks retrieves the current marks of a submission, writing the
+// error response and returning false when that fails
+func (h *SubmissionHandler) loadMarks(w http.ResponseWriter, examID, studentID string) ([]storage.QuestionMark, bool) {
+	marks, err := h.storage.ListMarks(examID, studentID)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Submission not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error retrieving marks: %v", err)
+		http.Error(w, "Failed to retrieve marks", http.StatusInternalServerError)
+		return nil, false
+	}
+	return marks, true
+}
+
+// validateMark checks the fields of a mark given by an evaluator
+func validateMark(mark *storage.QuestionMark) error {
+	if !mark.Mark.Valid() {
+		return fmt.Errorf("mark must be one of %s", markNames())
+	}
+	if mark.Evaluator == "" {
+		return fmt.Errorf("evaluator must be a non-empty string")
+	}
+	if len([]rune(mark.Comment)) > maxCommentSize {
+		return fmt.Errorf("comment must be at most %d characters", maxCommentSize)
+	}
+	return nil
+}
+
+// markNames lists the valid marks for error messages
+func markNames() string {
+	names := make([]string, len(storage.Marks))
+	for i, known := range storage.Marks {
+		names[i] = string(known)
+	}
+	return strings.Join(names, ", ")
+}
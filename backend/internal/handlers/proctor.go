@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"backend/internal/proctor"
+)
+
+// Stream timing: how often the summary is resent, which also keeps idle
+// connections open, and how long one write to a proctor may take
+const (
+	proctorHeartbeat    = 15 * time.Second
+	proctorWriteTimeout = 10 * time.Second
+)
+
+// ProctorHandler shows proctors the exam sessions in progress
+type ProctorHandler struct {
+	hub *proctor.Hub
+}
+
+// NewProctorHandler creates a new proctor handler
+func NewProctorHandler(hub *proctor.Hub) *ProctorHandler {
+	return &ProctorHandler{hub: hub}
+}
+
+// ProctorSummary lists the students of an exam and how many have each
+// status
+type ProctorSummary struct {
+	ExamID   string                 `json:"examId"`
+	Counts   map[proctor.Status]int `json:"counts"`
+	Students []proctor.Student      `json:"students"`
+}
+
+// ServeHTTP routes the proctoring endpoints:
+//
+//	GET /proctor/{examId}
+//	GET /proctor/{examId}/stream
+func (h *ProctorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts, ok := pathSegments(r, "/proctor/")
+	switch {
+	case ok && len(parts) == 1 && parts[0] != "":
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(proctorSummary(parts[0], h.hub.Students(parts[0])))
+	case ok && len(parts) == 2 && parts[0] != "" && parts[1] == "stream":
+		h.stream(w, r, parts[0])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// stream sends the exam's summary, then every update as a Server-Sent
+// Event named after its type, resending the summary on every heartbeat.
+// A proctor too slow to keep up is disconnected; EventSource reconnects
+// and starts again from a fresh summary.
+func (h *ProctorHandler) stream(w http.ResponseWriter, r *http.Request, examID string) {
+	rc := http.NewResponseController(w)
+
+	sub, students := h.hub.Subscribe(examID)
+	defer h.hub.Unsubscribe(examID, sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, data interface{}) bool {
+		rc.SetWriteDeadline(time.Now().Add(proctorWriteTimeout))
+		payload, err := json.Marshal(data)
+		if err == nil {
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		return err == nil
+	}
+
+	log.Printf("👀 Proctor stream opened: exam=%s by %s", examID, actor(r))
+	defer log.Printf("👀 Proctor stream closed: exam=%s by %s", examID, actor(r))
+
+	if !send("summary", proctorSummary(examID, students)) {
+		return
+	}
+	heartbeat := time.NewTicker(proctorHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.Done():
+			return
+		case update := <-sub.Updates():
+			if !send(string(update.Type), update) {
+				return
+			}
+		case <-heartbeat.C:
+			if !send("summary", proctorSummary(examID, h.hub.Students(examID))) {
+				return
+			}
+		}
+	}
+}
+
+// proctorSummary counts the students of an exam by status
+func proctorSummary(examID string, students []proctor.Student) ProctorSummary {
+	counts := make(map[proctor.Status]int)
+	for _, student := range students {
+		counts[student.Status]++
+	}
+	return ProctorSummary{ExamID: examID, Counts: counts, Students: students}
+}
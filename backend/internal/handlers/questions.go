@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"backend/internal/storage"
+)
+
+// QuestionsHandler serves the question bank to evaluators
+type QuestionsHandler struct {
+	storage storage.Store
+}
+
+// NewQuestionsHandler creates a new questions handler
+func NewQuestionsHandler(storage storage.Store) *QuestionsHandler {
+	return &QuestionsHandler{storage: storage}
+}
+
+// ServeHTTP routes the question bank endpoints:
+//
+//	GET /questions
+//	GET /questions/{id}
+func (h *QuestionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.Path == "/questions" {
+		h.listQuestions(w)
+		return
+	}
+
+	parts, ok := pathSegments(r, "/questions/")
+	if !ok || len(parts) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := strconv.Atoi(parts[0])
+	if err != nil || id < 0 {
+		http.Error(w, "question id must be a non-negative integer", http.StatusBadRequest)
+		return
+	}
+	h.questionVersions(w, id)
+}
+
+// listQuestions writes the current version of every question
+func (h *QuestionsHandler) listQuestions(w http.ResponseWriter) {
+	questions, err := h.storage.ListQuestions()
+	if err != nil {
+		log.Printf("Error retrieving questions: %v", err)
+		http.Error(w, "Failed to retrieve questions", http.StatusInternalServerError)
+		return
+	}
+	if questions == nil {
+		questions = []storage.BankQuestion{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(questions)
+}
+
+// questionVersions writes every version of a question, oldest first
+func (h *QuestionsHandler) questionVersions(w http.ResponseWriter, id int) {
+	versions, err := h.storage.QuestionVersions(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Question not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error retrieving question: %v", err)
+		http.Error(w, "Failed to retrieve question", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(versions)
+}
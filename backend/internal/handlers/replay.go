@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"backend/internal/playback"
+	"backend/internal/render"
+)
+
+// getCast writes the replay of one question of the current revision of a
+// submission as an asciinema cast file download
+func (h *SubmissionHandler) getCast(w http.ResponseWriter, r *http.Request, examID, studentID, key string) {
+	opts, err := parseCastOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	submission, ok := h.loadSubmission(w, examID, studentID)
+	if !ok {
+		return
+	}
+	question := submission.Question(key)
+	if question == nil {
+		http.Error(w, "Question not found", http.StatusNotFound)
+		return
+	}
+
+	opts.Title = fmt.Sprintf("%s / %s (%s) / %s", examID, studentID, submission.StudentName(), question.Key)
+	opts.Timestamp = submission.SubmissionTime
+	var body bytes.Buffer
+	if err := playback.WriteCast(&body, question.EventLog, opts); err != nil {
+		log.Printf("Error writing cast: %v", err)
+		http.Error(w, "Failed to write cast", http.StatusInternalServerError)
+		return
+	}
+
+	filename := playback.FileName(examID, studentID, question.Key, "cast")
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+	log.Printf("🎬 Cast of %s downloaded by %s: exam=%s, student=%s", question.Key, actor(r), examID, studentID)
+}
+
+// parseCastOptions reads the cols, rows and maxPause query parameters of
+// a cast download
+func parseCastOptions(query url.Values) (playback.CastOptions, error) {
+	opts := playback.CastOptions{Width: playback.DefaultCols, Height: playback.DefaultRows}
+
+	var err error
+	if value := query.Get("cols"); value != "" {
+		if opts.Width, err = strconv.Atoi(value); err != nil {
+			return opts, fmt.Errorf("cols must be an integer")
+		}
+	}
+	if value := query.Get("rows"); value != "" {
+		if opts.Height, err = strconv.Atoi(value); err != nil {
+			return opts, fmt.Errorf("rows must be an integer")
+		}
+	}
+	if err := playback.CheckSize(opts.Width, opts.Height); err != nil {
+		return opts, err
+	}
+
+	if value := query.Get("maxPause"); value != "" {
+		maxPause, err := time.ParseDuration(value)
+		if err != nil || maxPause < 0 {
+			return opts, fmt.Errorf("maxPause must be a non-negative duration such as 3s")
+		}
+		opts.MaxPauseMs = float64(maxPause) / float64(time.Millisecond)
+	}
+	return opts, nil
+}
+
+// getImage writes the replay of one question of the current revision of a
+// submission as an animated GIF, or as a PNG still of the moment given by
+// the at query parameter
+func (h *SubmissionHandler) getImage(w http.ResponseWriter, r *http.Request, examID, studentID, key, format string) {
+	query := r.URL.Query()
+	opts, err := parseRenderOptions(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	atMs := math.Inf(1)
+	if value := query.Get("at"); value != "" {
+		at, err := time.ParseDuration(value)
+		if err != nil || at < 0 {
+			http.Error(w, "at must be a non-negative duration such as 90s", http.StatusBadRequest)
+			return
+		}
+		atMs = float64(at) / float64(time.Millisecond)
+	}
+
+	submission, ok := h.loadSubmission(w, examID, studentID)
+	if !ok {
+		return
+	}
+	question := submission.Question(key)
+	if question == nil {
+		http.Error(w, "Question not found", http.StatusNotFound)
+		return
+	}
+
+	opts.Title = fmt.Sprintf("%s / %s (%s) / %s", examID, studentID, submission.StudentName(), question.Key)
+	var body bytes.Buffer
+	if format == "png" {
+		err = render.PNG(&body, question.EventLog, atMs, opts)
+	} else {
+		err = render.GIF(&body, question.EventLog, opts)
+	}
+	if err != nil {
+		log.Printf("Error rendering replay: %v", err)
+		http.Error(w, "Failed to render replay", http.StatusInternalServerError)
+		return
+	}
+
+	filename := playback.FileName(examID, studentID, question.Key, format)
+	w.Header().Set("Content-Type", "image/"+format)
+	w.Header().Set("Content-Disposition", `inline; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+	log.Printf("🎞️ Replay %s of %s rendered for %s: exam=%s, student=%s", format, question.Key, actor(r), examID, studentID)
+}
+
+// parseRenderOptions reads the width, height, maxPause and speed query
+// parameters of a rendered replay
+func parseRenderOptions(query url.Values) (render.Options, error) {
+	opts := render.Options{Width: render.DefaultWidth, Height: render.DefaultHeight, Speed: 1}
+
+	var err error
+	if value := query.Get("width"); value != "" {
+		if opts.Width, err = strconv.Atoi(value); err != nil {
+			return opts, fmt.Errorf("width must be an integer")
+		}
+	}
+	if value := query.Get("height"); value != "" {
+		if opts.Height, err = strconv.Atoi(value); err != nil {
+			return opts, fmt.Errorf("height must be an integer")
+		}
+	}
+	if err := render.CheckSize(opts.Width, opts.Height); err != nil {
+		return opts, err
+	}
+
+	if value := query.Get("maxPause"); value != "" {
+		maxPause, err := time.ParseDuration(value)
+		if err != nil || maxPause < 0 {
+			return opts, fmt.Errorf("maxPause must be a non-negative duration such as 3s")
+		}
+		opts.MaxPauseMs = float64(maxPause) / float64(time.Millisecond)
+	}
+	if value := query.Get("speed"); value != "" {
+		if opts.Speed, err = strconv.ParseFloat(value, 64); err != nil || opts.Speed <= 0 || math.IsInf(opts.Speed, 0) {
+			return opts, fmt.Errorf("speed must be a positive number")
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"backend/internal/storage"
+)
+
+// RevisionResponse is one revision's receipt together with its payload
+type RevisionResponse struct {
+	storage.Revision
+	Submission *storage.Submission `json:"submission"`
+}
+
+// listRevisions writes the receipts of every revision, oldest first
+func (h *SubmissionHandler) listRevisions(w http.ResponseWriter, examID, studentID string) {
+	revisions, err := h.storage.ListRevisions(examID, studentID)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Submission not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error retrieving revisions: %v", err)
+		http.Error(w, "Failed to retrieve revisions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(revisions)
+	log.Printf("📋 Listed %d revisions: exam=%s, student=%s", len(revisions), examID, studentID)
+}
+
+// getRevision writes one revision with the payload received in it
+func (h *SubmissionHandler) getRevision(w http.ResponseWriter, r *http.Request, examID, studentID string, revision int) {
+	rev, submission, err := h.storage.GetRevision(examID, studentID, revision)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Revision not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error retrieving revision: %v", err)
+		http.Error(w, "Failed to retrieve revision", http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(RevisionResponse{Revision: *rev, Submission: submission})
+	if err != nil {
+		log.Printf("Error encoding revision: %v", err)
+		http.Error(w, "Failed to encode revision", http.StatusInternalServerError)
+		return
+	}
+	writeCachedJSON(w, r, body)
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"backend/internal/eventlog"
+	"backend/internal/storage"
+)
+
+// Event log formats selected by the events query parameter
+const (
+	// eventsCompressed returns the event log as submitted
+	eventsCompressed = "compressed"
+	// eventsExpanded returns one event per keystroke with timestamps
+	eventsExpanded = "expanded"
+)
+
+// SubmissionHandler handles requests for a single submission and the
+// resources below it
+type SubmissionHandler struct {
+	storage storage.Store
+}
+
+// NewSubmissionHandler creates a new single-submission handler
+func NewSubmissionHandler(storage storage.Store) *SubmissionHandler {
+	return &SubmissionHandler{storage: storage}
+}
+
+// ServeHTTP routes the /submissions/{examId}/{studentId} subtree:
+//
+//	GET /submissions/{examId}/{studentId}
+//	GET /submissions/{examId}/{studentId}/questions/{questionKey}
+//	GET /submissions/{examId}/{studentId}/revisions
+//	GET /submissions/{examId}/{studentId}/revisions/{revision}
+//	GET /submissions/{examId}/{studentId}/marks
+//	GET /submissions/{examId}/{studentId}/analytics
+//	GET /submissions/{examId}/{studentId}/questions/{questionKey}/mark
+//	PUT /submissions/{examId}/{studentId}/questions/{questionKey}/mark
+//	GET /submissions/{examId}/{studentId}/questions/{questionKey}/mark/history
+//	GET /submissions/{examId}/{studentId}/questions/{questionKey}/replay.cast
+//	GET /submissions/{examId}/{studentId}/questions/{questionKey}/replay.gif
+//	GET /submissions/{examId}/{studentId}/questions/{questionKey}/replay.png
+func (h *SubmissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts, ok := pathSegments(r, "/submissions/")
+	if !ok || len(parts) < 2 || len(parts) > 6 || parts[0] == "" || parts[1] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	examID, studentID := parts[0], parts[1]
+
+	// Only accept GET requests, and PUT to set a mark
+	markPath := len(parts) == 5 && parts[2] == "questions" && parts[4] == "mark"
+	if r.Method != http.MethodGet && !(r.Method == http.MethodPut && markPath) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("events")
+	if format == "" {
+		format = eventsCompressed
+	}
+	if format != eventsCompressed && format != eventsExpanded {
+		http.Error(w, "events must be compressed or expanded", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(parts) == 2:
+		h.getSubmission(w, r, examID, studentID, format)
+	case parts[2] == "questions" && len(parts) == 4:
+		h.getQuestion(w, r, examID, studentID, parts[3], format)
+	case markPath && r.Method == http.MethodPut:
+		h.putMark(w, r, examID, studentID, parts[3])
+	case markPath:
+		h.getMark(w, examID, studentID, parts[3])
+	case parts[2] == "questions" && len(parts) == 6 && parts[4] == "mark" && parts[5] == "history":
+		h.markHistory(w, examID, studentID, parts[3])
+	case parts[2] == "questions" && len(parts) == 5 && parts[4] == "replay.cast":
+		h.getCast(w, r, examID, studentID, parts[3])
+	case parts[2] == "questions" && len(parts) == 5 && parts[4] == "replay.gif":
+		h.getImage(w, r, examID, studentID, parts[3], "gif")
+	case parts[2] == "questions" && len(parts) == 5 && parts[4] == "replay.png":
+		h.getImage(w, r, examID, studentID, parts[3], "png")
+	case parts[2] == "marks" && len(parts) == 3:
+		h.listMarks(w, examID, studentID)
+	case parts[2] == "analytics" && len(parts) == 3:
+		h.getAnalytics(w, r, examID, studentID)
+	case parts[2] == "revisions" && len(parts) == 3:
+		h.listRevisions(w, examID, studentID)
+	case parts[2] == "revisions" && len(parts) == 4:
+		revision, err := strconv.Atoi(parts[3])
+		if err != nil || revision <= 0 {
+			http.Error(w, "revision must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		h.getRevision(w, r, examID, studentID, revision)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// getSubmission writes the current revision of a submission in the
+// layout it was received in
+func (h *SubmissionHandler) getSubmission(w http.ResponseWriter, r *http.Request, examID, studentID, format string) {
+	submission, ok := h.loadSubmission(w, examID, studentID)
+	if !ok {
+		return
+	}
+
+	body, err := marshalSubmission(submission, format)
+	if err != nil {
+		log.Printf("Error encoding submission: %v", err)
+		http.Error(w, "Failed to encode submission", http.StatusInternalServerError)
+		return
+	}
+	writeCachedJSON(w, r, body)
+}
+
+// QuestionResponse is one question of a submission with its stored
+// integrity verdict, typing statistics and suspicion score
+type QuestionResponse struct {
+	Key       string             `json:"key"`
+	Integrity eventlog.Integrity `json:"integrity"`
+	Stats     eventlog.Stats     `json:"stats"`
+	Suspicion storage.Suspicion  `json:"suspicion"`
+	// Question is a storage.Question or an expandedQuestion
+	Question interface{} `json:"question"`
+}
+
+// getQuestion writes one question of the current revision of a submission
+func (h *SubmissionHandler) getQuestion(w http.ResponseWriter, r *http.Request, examID, studentID, key, format string) {
+	submission, ok := h.loadSubmission(w, examID, studentID)
+	if !ok {
+		return
+	}
+
+	question := submission.Question(key)
+	if question == nil {
+		http.Error(w, "Question not found", http.StatusNotFound)
+		return
+	}
+
+	body, err := json.Marshal(QuestionResponse{
+		Key:       question.Key,
+		Integrity: question.Integrity,
+		Stats:     question.Stats,
+		Suspicion: question.Suspicion,
+		Question:  encodeQuestion(format)(*question),
+	})
+	if err != nil {
+		log.Printf("Error encoding question: %v", err)
+		http.Error(w, "Failed to encode question", http.StatusInternalServerError)
+		return
+	}
+	writeCachedJSON(w, r, body)
+}
+
+// loadSubmission retrieves a submission, writing the error response and
+// returning false when that fails
+func (h *SubmissionHandler) loadSubmission(w http.ResponseWriter, examID, studentID string) (*storage.Submission, bool) {
+	submission, err := h.storage.GetSubmission(examID, studentID)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Submission not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error retrieving submission: %v", err)
+		http.Error(w, "Failed to retrieve submission", http.StatusInternalServerError)
+		return nil, false
+	}
+	return submission, true
+}
+
+// expandedQuestion is a question whose event log is replaced by its
+// per-keystroke expansion
+type expandedQuestion struct {
+	storage.Question
+	EventLogFormat string          `json:"eventLogFormat"`
+	EventLog       []expandedEvent `json:"eventLog"`
+}
+
+// expandedEvent is one keystroke-level action of an expanded event log
+type expandedEvent struct {
+	Op    string  `json:"op"`
+	Text  *string `json:"text,omitempty"`
+	Key   *string `json:"key,omitempty"`
+	Start *int    `json:"start,omitempty"`
+	End   *int    `json:"end,omitempty"`
+	// OffsetMs is the time since the first event; TimeMs is the same
+	// instant on the client clock of startTime_ms and endTime_ms
+	OffsetMs float64 `json:"offset_ms"`
+	TimeMs   float64 `json:"time_ms"`
+	// Event is the index of the source event in the compressed log
+	Event int `json:"event"`
+}
+
+// marshalSubmission encodes a submission with its event logs in format
+func marshalSubmission(submission *storage.Submission, format string) ([]byte, error) {
+	if format == eventsCompressed {
+		return json.Marshal(submission)
+	}
+	return submission.MarshalJSONWith(encodeQuestion(format))
+}
+
+// encodeQuestion returns the encoder of questions for an event log format
+func encodeQuestion(format string) func(storage.Question) interface{} {
+	if format != eventsExpanded {
+		return func(question storage.Question) interface{} { return question }
+	}
+
+	return func(question storage.Question) interface{} {
+		actions := eventlog.Expand(question.EventLog)
+		events := make([]expandedEvent, 0, len(actions))
+		for _, action := range actions {
+			action := action
+			event := expandedEvent{
+				Op:       action.Op.String(),
+				OffsetMs: action.At,
+				TimeMs:   question.StartTimeMs + action.At,
+				Event:    action.Event,
+			}
+			switch action.Op {
+			case eventlog.OpInsert, eventlog.OpPaste:
+				event.Text = &action.Text
+			case eventlog.OpKey:
+				event.Key = &action.Key
+			case eventlog.OpSelect:
+				event.Start, event.End = &action.Start, &action.End
+			}
+			events = append(events, event)
+		}
+		return expandedQuestion{Question: question, EventLogFormat: eventsExpanded, EventLog: events}
+	}
+}
+
+// writeCachedJSON writes a JSON body with an ETag derived from its
+// content, answering 304 Not Modified when the client already has it
+func writeCachedJSON(w http.ResponseWriter, r *http.Request, body []byte) {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// etagMatches reports whether an If-None-Match header lists etag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// pathSegments splits the escaped request path after prefix into its
+// unescaped segments, so that IDs may contain an encoded '/'
+func pathSegments(r *http.Request, prefix string) ([]string, bool) {
+	path := r.URL.EscapedPath()
+	if !strings.HasPrefix(path, prefix) {
+		return nil, false
+	}
+
+	parts := strings.Split(strings.TrimPrefix(path, prefix), "/")
+	for i, part := range parts {
+		unescaped, err := url.PathUnescape(part)
+		if err != nil {
+			return nil, false
+		}
+		parts[i] = unescaped
+	}
+	return parts, true
+}
@@ -2,19 +2,31 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"backend/internal/eventlog"
 	"backend/internal/storage"
 )
 
+// Page sizes of GET /submissions
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
 // SubmissionsHandler handles submission listing requests
 type SubmissionsHandler struct {
-	storage *storage.SQLiteStorage
+	storage storage.Store
 }
 
 // NewSubmissionsHandler creates a new submissions handler
-func NewSubmissionsHandler(storage *storage.SQLiteStorage) *SubmissionsHandler {
+func NewSubmissionsHandler(storage storage.Store) *SubmissionsHandler {
 	return &SubmissionsHandler{storage: storage}
 }
 
@@ -24,6 +36,32 @@ type SubmissionSummary struct {
 	StudentID      string `json:"studentId"`
 	StudentName    string `json:"studentName"`
 	SubmissionTime string `json:"submissionTime"`
+	// Revision counts the attempts stored for the student; ReceivedAt is
+	// when the server received the current one
+	Revision   int    `json:"revision"`
+	ReceivedAt string `json:"receivedAt"`
+	// Integrity holds the replay verdict per question key
+	Integrity map[string]eventlog.Integrity `json:"integrity,omitempty"`
+	// PasteUsed reports whether any question contains a paste; Stats
+	// holds the typing statistics per question key
+	PasteUsed bool                      `json:"pasteUsed"`
+	Stats     map[string]eventlog.Stats `json:"stats,omitempty"`
+	// Suspicion holds the suspicion score per question key
+	Suspicion map[string]storage.Suspicion `json:"suspicion,omitempty"`
+	// Marks holds the current evaluator mark per marked question key
+	Marks map[string]storage.QuestionMark `json:"marks,omitempty"`
+	// TimingFlags lists the exam limits the current revision broke
+	TimingFlags []storage.TimingFlag `json:"timingFlags,omitempty"`
+}
+
+// SubmissionsPage is one page of GET /submissions
+type SubmissionsPage struct {
+	// Submissions holds []SubmissionSummary or []*storage.Submission
+	Submissions interface{} `json:"submissions"`
+	// Total counts every submission matching the filters
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
 }
 
 // HandleListSubmissions handles GET /submissions requests
@@ -34,51 +72,192 @@ func (h *SubmissionsHandler) HandleListSubmissions(w http.ResponseWriter, r *htt
 		return
 	}
 
+	query := r.URL.Query()
+
 	// Check if summary=true query parameter is set
-	summaryOnly := r.URL.Query().Get("summary") == "true"
+	summaryOnly := query.Get("summary") == "true"
 
-	// Get all submissions from database
-	submissions, err := h.storage.GetAllSubmissions()
+	filter, err := parseSubmissionFilter(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	page, err := parsePage(query)
 	if err != nil {
-		log.Printf("Error retrieving submissions: %v", err)
-		http.Error(w, "Failed to retrieve submissions", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	response := SubmissionsPage{Limit: page.Limit, Offset: page.Offset}
+
+	if summaryOnly {
+		// Summaries are read from indexed columns, without decoding payloads
+		var summaries []storage.Summary
+		summaries, response.Total, err = h.storage.ListSummaries(filter, page)
+		if err != nil {
+			log.Printf("Error retrieving submissions: %v", err)
+			http.Error(w, "Failed to retrieve submissions", http.StatusInternalServerError)
+			return
+		}
+
+		items := make([]SubmissionSummary, 0, len(summaries))
+		for _, summary := range summaries {
+			items = append(items, SubmissionSummary{
+				ExamID:         summary.ExamID,
+				StudentID:      summary.StudentID,
+				StudentName:    summary.StudentName,
+				SubmissionTime: summary.SubmissionTime.Format(time.RFC3339Nano),
+				Revision:       summary.Revision,
+				ReceivedAt:     summary.ReceivedAt.Format(time.RFC3339Nano),
+				Integrity:      summary.Integrity,
+				PasteUsed:      summary.PasteUsed(),
+				Stats:          summary.Stats,
+				Suspicion:      summary.Suspicion,
+				Marks:          summary.Marks,
+				TimingFlags:    summary.TimingFlags,
+			})
+		}
+		response.Submissions = items
+	} else {
+		// Return full submissions
+		var submissions []*storage.Submission
+		submissions, response.Total, err = h.storage.ListSubmissions(filter, page)
+		if err != nil {
+			log.Printf("Error retrieving submissions: %v", err)
+			http.Error(w, "Failed to retrieve submissions", http.StatusInternalServerError)
+			return
+		}
+
+		if submissions == nil {
+			submissions = []*storage.Submission{}
+		}
+		response.Submissions = submissions
+	}
+
 	// Return JSON response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+	log.Printf("📋 Listed submissions %d+%d of %d (summary=%t)", page.Offset, page.Limit, response.Total, summaryOnly)
+}
 
-	if summaryOnly {
-		// Convert to summary format for listing
-		summaries := make([]SubmissionSummary, 0, len(submissions))
-		for _, submission := range submissions {
-			summary := SubmissionSummary{
-				ExamID:         getStringField(submission, "examId"),
-				StudentID:      getStringField(submission, "studentId"),
-				SubmissionTime: getStringField(submission, "submissionTime"),
-			}
+// parseSubmissionFilter reads the filter query parameters of GET /submissions
+func parseSubmissionFilter(query url.Values) (storage.SubmissionFilter, error) {
+	filter := storage.SubmissionFilter{
+		ExamID:       query.Get("examId"),
+		StudentID:    query.Get("studentId"),
+		NameContains: query.Get("name"),
+	}
+
+	var err error
+	if filter.SubmittedFrom, err = parseTimeParam(query, "from"); err != nil {
+		return filter, err
+	}
+	if filter.SubmittedTo, err = parseTimeParam(query, "to"); err != nil {
+		return filter, err
+	}
+
+	if value := query.Get("pasteUsed"); value != "" {
+		pasteUsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("pasteUsed must be true or false")
+		}
+		filter.PasteUsed = &pasteUsed
+	}
 
-			// Extract student name from metadata
-			if metadata, ok := submission["metadata"].(map[string]interface{}); ok {
-				summary.StudentName = getStringField(metadata, "studentName")
+	if value := query.Get("verdict"); value != "" {
+		verdict := eventlog.Verdict(value)
+		switch verdict {
+		case eventlog.VerdictMatch, eventlog.VerdictMismatch, eventlog.VerdictUnreplayable:
+			filter.Verdict = verdict
+		default:
+			return filter, fmt.Errorf("verdict must be one of %s, %s, %s",
+				eventlog.VerdictMatch, eventlog.VerdictMismatch, eventlog.VerdictUnreplayable)
+		}
+	}
+
+	if value := query.Get("mark"); value != "" {
+		filter.Mark = storage.Mark(strings.ToUpper(value))
+		if !filter.Mark.Valid() {
+			return filter, fmt.Errorf("mark must be one of %s", markNames())
+		}
+	}
+
+	if value := query.Get("timing"); value != "" {
+		filter.Timing = storage.TimingFlag(value)
+		if filter.Timing != storage.TimingAny && !filter.Timing.Valid() {
+			return filter, fmt.Errorf("timing must be %s or one of %s", storage.TimingAny, timingFlagNames(storage.TimingFlags))
+		}
+	}
+
+	if value := query.Get("minSuspicion"); value != "" {
+		minSuspicion, err := strconv.ParseFloat(value, 64)
+		if err != nil || minSuspicion < 0 {
+			return filter, fmt.Errorf("minSuspicion must be a non-negative number")
+		}
+		filter.MinSuspicion = minSuspicion
+	}
+
+	return filter, nil
+}
+
+// parseTimeParam reads an optional RFC 3339 timestamp or YYYY-MM-DD date
+func parseTimeParam(query url.Values, name string) (time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp or a YYYY-MM-DD date", name)
+}
+
+// parsePage reads the sort and pagination query parameters of GET
+// /submissions. sort names a field, prefixed with - for descending order.
+func parsePage(query url.Values) (storage.Page, error) {
+	page := storage.Page{Sort: storage.SortSubmissionTime, Descending: true, Limit: defaultPageLimit}
+
+	if value := query.Get("sort"); value != "" {
+		page.Descending = strings.HasPrefix(value, "-")
+		page.Sort = storage.SortField(strings.TrimPrefix(value, "-"))
+		if !validSortField(page.Sort) {
+			names := make([]string, len(storage.SortFields))
+			for i, field := range storage.SortFields {
+				names[i] = string(field)
 			}
+			return page, fmt.Errorf("sort must be one of %s, optionally prefixed with -", strings.Join(names, ", "))
+		}
+	}
 
-			summaries = append(summaries, summary)
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxPageLimit {
+			return page, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
 		}
-		json.NewEncoder(w).Encode(summaries)
-		log.Printf("📋 Listed %d submission summaries", len(summaries))
-	} else {
-		// Return full submissions
-		json.NewEncoder(w).Encode(submissions)
-		log.Printf("📋 Listed %d full submissions", len(submissions))
+		page.Limit = limit
 	}
+
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return page, fmt.Errorf("offset must be a non-negative integer")
+		}
+		page.Offset = offset
+	}
+
+	return page, nil
 }
 
-// getStringField safely extracts a string field from a map
-func getStringField(m map[string]interface{}, key string) string {
-	if val, ok := m[key].(string); ok {
-		return val
+// validSortField reports whether field is a known sort field
+func validSortField(field storage.SortField) bool {
+	for _, known := range storage.SortFields {
+		if field == known {
+			return true
+		}
 	}
-	return ""
+	return false
 }
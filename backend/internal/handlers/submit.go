@@ -2,20 +2,38 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 
+	"backend/internal/config"
+	"backend/internal/eventlog"
+	"backend/internal/examsession"
+	"backend/internal/ingest"
+	"backend/internal/proctor"
 	"backend/internal/storage"
+	"backend/internal/suspicion"
 )
 
 // SubmitHandler handles submission requests
 type SubmitHandler struct {
-	storage *storage.SQLiteStorage
+	storage   storage.Store
+	integrity *config.IntegrityConfig
+	exam      *config.ExamConfig
+	signer    *examsession.Signer
+	hub       *proctor.Hub
+	rules     *suspicion.Rules
 }
 
-// NewSubmitHandler creates a new submit handler
-func NewSubmitHandler(storage *storage.SQLiteStorage) *SubmitHandler {
-	return &SubmitHandler{storage: storage}
+// NewSubmitHandler creates a new submit handler verifying exam session
+// tokens with signer, announcing submissions to proctors through hub and
+// scoring answers with rules, which may be nil
+func NewSubmitHandler(storage storage.Store, integrity *config.IntegrityConfig, exam *config.ExamConfig, signer *examsession.Signer, hub *proctor.Hub, rules *suspicion.Rules) *SubmitHandler {
+	return &SubmitHandler{storage: storage, integrity: integrity, exam: exam, signer: signer, hub: hub, rules: rules}
 }
 
 // HandleSubmit handles POST /submit requests
@@ -26,47 +44,90 @@ func (h *SubmitHandler) HandleSubmit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse JSON payload
-	var payload map[string]interface{}
-	decoder := json.NewDecoder(r.Body)
-	decoder.DisallowUnknownFields()
-
-	if err := decoder.Decode(&payload); err != nil {
+	// Parse JSON payload; malformed questions and events fail here
+	var submission storage.Submission
+	if err := json.NewDecoder(r.Body).Decode(&submission); err != nil {
 		log.Printf("Error decoding JSON: %v", err)
-		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// Validate required fields
-	if err := validatePayload(payload); err != nil {
+	if err := ingest.Validate(&submission); err != nil {
 		log.Printf("Validation error: %v", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Save to database
-	if err := h.storage.SaveSubmission(payload); err != nil {
+	// Check the submission against the exam session the server started
+	if err := h.verifySession(r, &submission); err != nil {
+		log.Printf("Exam session rejected: %v", err)
+		http.Error(w, err.Error(), err.Status)
+		return
+	}
+
+	// Questions sent without events are finalized from their autosave
+	if err := h.finalizeAutosave(&submission); err != nil {
+		http.Error(w, err.Error(), err.Status)
+		return
+	}
+
+	// Check the exam's window, duration and attempt limits
+	submission.ReceivedAt = time.Now().UTC()
+	if err := h.checkTiming(&submission); err != nil {
+		log.Printf("Timing check failed: %v", err)
+		http.Error(w, err.Error(), err.Status)
+		return
+	}
+
+	// Replay each question's event log against its final answer
+	if err := ingest.Check(&submission, h.integrity.Mode, h.rules); err != nil {
+		log.Printf("Integrity check failed: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Record the receipt and save to database as a new revision
+	submission.ClientIP = clientIP(r)
+	if err := h.storage.SaveSubmission(&submission); err != nil {
 		log.Printf("Error saving submission: %v", err)
 		http.Error(w, "Failed to save submission", http.StatusInternalServerError)
 		return
 	}
 
-	// Get student info for logging
-	examID, _ := payload["examId"].(string)
-	studentID, _ := payload["studentId"].(string)
-	var studentName string
-	if metadata, ok := payload["metadata"].(map[string]interface{}); ok {
-		studentName, _ = metadata["studentName"].(string)
+	log.Printf("✅ Submission saved: exam=%s, student=%s (%s), revision=%d",
+		submission.ExamID, submission.StudentID, submission.StudentName(), submission.Revision)
+	for _, question := range submission.Questions {
+		if question.Integrity.Verdict != eventlog.VerdictMatch {
+			log.Printf("⚠️  Integrity %s: exam=%s, student=%s, question=%s",
+				question.Integrity.Verdict, submission.ExamID, submission.StudentID, question.Key)
+		}
+	}
+	for _, question := range submission.Questions {
+		if len(question.Suspicion.Rules) > 0 {
+			log.Printf("🚩 Suspicion %g (%s): exam=%s, student=%s, question=%s", question.Suspicion.Score,
+				strings.Join(question.Suspicion.Rules, ", "), submission.ExamID, submission.StudentID, question.Key)
+		}
+	}
+	if submission.SessionID != "" {
+		h.hub.Submitted(submission.ExamID, submission.StudentID, submission.SessionID, submission.ReceivedAt)
+	}
+	if len(submission.TimingFlags) > 0 {
+		log.Printf("⚠️  Timing %v: exam=%s, student=%s", submission.TimingFlags, submission.ExamID, submission.StudentID)
 	}
-
-	log.Printf("✅ Submission saved: exam=%s, student=%s (%s)", examID, studentID, studentName)
 
 	// Return success response
 	response := map[string]interface{}{
-		"success": true,
-		"message": "Submission received successfully",
-		"examId":  examID,
-		"studentId": studentID,
+		"success":   true,
+		"message":   "Submission received successfully",
+		"examId":    submission.ExamID,
+		"studentId": submission.StudentID,
+		"revision":  submission.Revision,
+		"integrity": integrityByQuestion(&submission),
+		"suspicion": suspicionByQuestion(&submission),
+	}
+	if len(submission.TimingFlags) > 0 {
+		response["timingFlags"] = submission.TimingFlags
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -74,67 +135,163 @@ func (h *SubmitHandler) HandleSubmit(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// validatePayload validates the submission payload
-func validatePayload(payload map[string]interface{}) error {
-	// Check required top-level fields
-	requiredFields := []string{"examId", "studentId", "submissionTime", "metadata"}
-	for _, field := range requiredFields {
-		if _, ok := payload[field]; !ok {
-			return &ValidationError{Field: field, Message: "required field missing"}
-		}
+// verifySession checks the exam session token of a submission: it must be
+// signed by this server, unexpired, registered and issued for the
+// submission's exam, student and questions. On success the session and its
+// server-side start time are recorded on the submission. Submissions
+// without a token are accepted only when sessions are not required.
+func (h *SubmitHandler) verifySession(r *http.Request, submission *storage.Submission) *SessionError {
+	token := r.Header.Get(ExamSessionHeader)
+	if token == "" && !h.exam.RequireSession {
+		return nil
 	}
 
-	// Validate examId
-	examID, ok := payload["examId"].(string)
-	if !ok || examID == "" {
-		return &ValidationError{Field: "examId", Message: "must be a non-empty string"}
+	claims, sessionErr := verifyToken(h.signer, token)
+	if sessionErr != nil {
+		return sessionErr
 	}
 
-	// Validate studentId
-	studentID, ok := payload["studentId"].(string)
-	if !ok || studentID == "" {
-		return &ValidationError{Field: "studentId", Message: "must be a non-empty string"}
+	if claims.ExamID != submission.ExamID || claims.StudentID != submission.StudentID {
+		return &SessionError{Status: http.StatusForbidden, Message: "examId and studentId do not match the exam session"}
+	}
+	for _, question := range submission.Questions {
+		if !claims.Assigned(question.QuestionIndex) {
+			return &SessionError{
+				Status:  http.StatusForbidden,
+				Message: fmt.Sprintf("%s: question %d was not assigned in the exam session", question.Key, question.QuestionIndex),
+			}
+		}
 	}
 
-	// Validate submissionTime
-	submissionTime, ok := payload["submissionTime"].(string)
-	if !ok || submissionTime == "" {
-		return &ValidationError{Field: "submissionTime", Message: "must be a non-empty string"}
+	session, err := h.storage.GetExamSession(claims.SessionID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return &SessionError{Status: http.StatusForbidden, Message: "unknown exam session"}
+	}
+	if err != nil {
+		log.Printf("Error retrieving exam session: %v", err)
+		return &SessionError{Status: http.StatusInternalServerError, Message: "Failed to check exam session"}
 	}
 
-	// Validate metadata
-	metadata, ok := payload["metadata"].(map[string]interface{})
-	if !ok {
-		return &ValidationError{Field: "metadata", Message: "must be an object"}
+	submission.SessionID, submission.StartedAt = session.ID, session.StartedAt
+	return nil
+}
+
+// finalizeAutosave fills the empty event logs of a submission made in an
+// exam session with the chunks autosaved for their questions
+func (h *SubmitHandler) finalizeAutosave(submission *storage.Submission) *SessionError {
+	if submission.SessionID == "" {
+		return nil
 	}
 
-	// Validate studentName in metadata
-	studentName, ok := metadata["studentName"].(string)
-	if !ok || studentName == "" {
-		return &ValidationError{Field: "metadata.studentName", Message: "must be a non-empty string"}
+	var chunks []storage.Chunk
+	loaded := false
+	for i := range submission.Questions {
+		question := &submission.Questions[i]
+		if len(question.EventLog) > 0 {
+			continue
+		}
+		if !loaded {
+			var err error
+			if chunks, err = h.storage.ListChunks(submission.SessionID); err != nil {
+				log.Printf("Error retrieving chunks: %v", err)
+				return &SessionError{Status: http.StatusInternalServerError, Message: "Failed to read autosave"}
+			}
+			loaded = true
+		}
+
+		events, count := autosavedEvents(chunks, question.QuestionIndex)
+		if count > 0 {
+			question.EventLog = events
+			log.Printf("💾 Finalized %s from %d autosaved chunks: exam=%s, student=%s",
+				question.Key, count, submission.ExamID, submission.StudentID)
+		}
+	}
+	return nil
+}
+
+// checkTiming checks a submission against the window, duration and
+// attempt limits of its exam, recording the limits it breaks or, when the
+// exam rejects such submissions, returning the error response. Exams
+// without a definition have no limits.
+func (h *SubmitHandler) checkTiming(submission *storage.Submission) *SessionError {
+	exam, err := h.storage.GetExam(submission.ExamID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving exam: %v", err)
+		return &SessionError{Status: http.StatusInternalServerError, Message: "Failed to check exam"}
 	}
 
-	// Check for at least one question (q1, q2, etc.)
-	hasQuestion := false
-	for key := range payload {
-		if len(key) == 2 && key[0] == 'q' && key[1] >= '1' && key[1] <= '9' {
-			hasQuestion = true
-			break
+	attempt := 1
+	if exam.MaxAttempts > 0 {
+		revisions, err := h.storage.ListRevisions(submission.ExamID, submission.StudentID)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Error retrieving revisions: %v", err)
+			return &SessionError{Status: http.StatusInternalServerError, Message: "Failed to check exam"}
 		}
+		attempt += len(revisions)
 	}
-	if !hasQuestion {
-		return &ValidationError{Field: "questions", Message: "at least one question (q1, q2, etc.) is required"}
+
+	// Duration runs from the student's first session, so starting another
+	// session does not restart the clock
+	startedAt := submission.StartedAt
+	if exam.DurationSeconds > 0 && submission.SessionID != "" {
+		first, err := h.storage.FirstExamSession(submission.ExamID, submission.StudentID)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Error retrieving first exam session: %v", err)
+			return &SessionError{Status: http.StatusInternalServerError, Message: "Failed to check exam"}
+		}
+		if first != nil && first.StartedAt.Before(startedAt) {
+			startedAt = first.StartedAt
+		}
 	}
 
+	flags := exam.Check(submission.ReceivedAt, startedAt, attempt)
+	if len(flags) > 0 && exam.Enforcement == storage.EnforceReject {
+		return &SessionError{Status: http.StatusForbidden, Message: fmt.Sprintf("submission outside the limits of exam %s: %s", exam.ID, timingFlagNames(flags))}
+	}
+	submission.TimingFlags = flags
 	return nil
 }
 
-// ValidationError represents a validation error
-type ValidationError struct {
-	Field   string
+// integrityByQuestion returns the integrity verdicts keyed by question key,
+// skipping questions that were never checked
+func integrityByQuestion(submission *storage.Submission) map[string]eventlog.Integrity {
+	verdicts := make(map[string]eventlog.Integrity, len(submission.Questions))
+	for _, question := range submission.Questions {
+		if question.Integrity.Verdict != "" {
+			verdicts[question.Key] = question.Integrity
+		}
+	}
+	return verdicts
+}
+
+// suspicionByQuestion returns the suspicion scores keyed by question key
+func suspicionByQuestion(submission *storage.Submission) map[string]storage.Suspicion {
+	scores := make(map[string]storage.Suspicion, len(submission.Questions))
+	for _, question := range submission.Questions {
+		scores[question.Key] = question.Suspicion
+	}
+	return scores
+}
+
+// clientIP returns the address the request came from, without its port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// SessionError rejects a submission over its exam session or the limits
+// of its exam
+type SessionError struct {
+	Status  int
 	Message string
 }
 
-func (e *ValidationError) Error() string {
-	return "validation error: " + e.Field + " - " + e.Message
+func (e *SessionError) Error() string {
+	return e.Message
 }
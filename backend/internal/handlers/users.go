@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"backend/internal/middleware"
+	"backend/internal/storage"
+)
+
+// maxUserBody limits the size of a users request
+const maxUserBody = 4 << 10
+
+// UsersHandler handles account management by admins
+type UsersHandler struct {
+	storage storage.Store
+}
+
+// NewUsersHandler creates a new users handler
+func NewUsersHandler(storage storage.Store) *UsersHandler {
+	return &UsersHandler{storage: storage}
+}
+
+// UserRequest is the body of POST /users and PUT /users/{username}.
+// PUT leaves empty fields unchanged.
+type UserRequest struct {
+	Username string       `json:"username"`
+	Password string       `json:"password"`
+	Role     storage.Role `json:"role"`
+}
+
+// ServeHTTP routes the user endpoints:
+//
+//	GET    /users
+//	POST   /users
+//	PUT    /users/{username}
+//	DELETE /users/{username}
+func (h *UsersHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/users" {
+		switch r.Method {
+		case http.MethodGet:
+			h.listUsers(w)
+		case http.MethodPost:
+			h.createUser(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	parts, ok := pathSegments(r, "/users/")
+	if !ok || len(parts) != 1 || parts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		h.updateUser(w, r, parts[0])
+	case http.MethodDelete:
+		h.deleteUser(w, r, parts[0])
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// listUsers writes every account
+func (h *UsersHandler) listUsers(w http.ResponseWriter) {
+	users, err := h.storage.ListUsers()
+	if err != nil {
+		log.Printf("Error retrieving users: %v", err)
+		http.Error(w, "Failed to retrieve users", http.StatusInternalServerError)
+		return
+	}
+	if users == nil {
+		users = []storage.User{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(users)
+}
+
+// createUser adds an account
+func (h *UsersHandler) createUser(w http.ResponseWriter, r *http.Request) {
+	request, ok := decodeUserRequest(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := middleware.NewUser(request.Username, request.Password, request.Role)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.storage.CreateUser(user)
+	if errors.Is(err, storage.ErrExists) {
+		http.Error(w, "User already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error creating user: %v", err)
+		http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("👤 Created user: %s (%s) by %s", user.Username, user.Role, actor(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+// updateUser changes the password and/or role of an account, signing it
+// out everywhere
+func (h *UsersHandler) updateUser(w http.ResponseWriter, r *http.Request, username string) {
+	request, ok := decodeUserRequest(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.storage.GetUser(username)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error retrieving user: %v", err)
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+
+	if request.Role != "" {
+		if !request.Role.Valid() {
+			http.Error(w, "role must be one of admin, evaluator, student", http.StatusBadRequest)
+			return
+		}
+		user.Role = request.Role
+	}
+	if request.Password != "" {
+		if err := middleware.SetPassword(user, request.Password); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.storage.UpdateUser(user); err != nil {
+		log.Printf("Error updating user: %v", err)
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("👤 Updated user: %s (%s) by %s", user.Username, user.Role, actor(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+// deleteUser removes an account and its sessions
+func (h *UsersHandler) deleteUser(w http.ResponseWriter, r *http.Request, username string) {
+	err := h.storage.DeleteUser(username)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting user: %v", err)
+		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("👤 Deleted user: %s by %s", username, actor(r))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeUserRequest reads a users request body, writing the error
+// response and returning false when that fails
+func decodeUserRequest(w http.ResponseWriter, r *http.Request) (UserRequest, bool) {
+	var request UserRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxUserBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return request, false
+	}
+	return request, true
+}
+
+// actor names the signed-in user of a request for logs
+func actor(r *http.Request) string {
+	if user, ok := middleware.UserFrom(r.Context()); ok {
+		return user.Username
+	}
+	return "anonymous"
+}
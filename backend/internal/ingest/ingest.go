@@ -0,0 +1,92 @@
+// Package ingest holds the checks every submission goes through before it
+// is stored, whether it arrives at POST /submit or is imported from a file
+// by drkka import: required fields, the replay of each event log against
+// its final answer, typing statistics and suspicion scores.
+package ingest
+
+import (
+	"fmt"
+
+	"backend/internal/eventlog"
+	"backend/internal/storage"
+	"backend/internal/suspicion"
+)
+
+// IntegrityReject is the integrity mode rejecting submissions whose event
+// logs do not reproduce their final answers
+const IntegrityReject = "reject"
+
+// Validate checks the required fields of a decoded submission
+func Validate(submission *storage.Submission) error {
+	// Validate examId
+	if submission.ExamID == "" {
+		return &ValidationError{Field: "examId", Message: "must be a non-empty string"}
+	}
+
+	// Validate studentId
+	if submission.StudentID == "" {
+		return &ValidationError{Field: "studentId", Message: "must be a non-empty string"}
+	}
+
+	// Validate submissionTime
+	if submission.SubmissionTime.IsZero() {
+		return &ValidationError{Field: "submissionTime", Message: "required field missing"}
+	}
+
+	// Validate metadata
+	if submission.Metadata == nil {
+		return &ValidationError{Field: "metadata", Message: "must be an object"}
+	}
+
+	// Validate studentName in metadata
+	if submission.StudentName() == "" {
+		return &ValidationError{Field: "metadata.studentName", Message: "must be a non-empty string"}
+	}
+
+	// Check for at least one question (questions array, or q1, q2, etc.)
+	if len(submission.Questions) == 0 {
+		return &ValidationError{Field: "questions", Message: "at least one question is required"}
+	}
+
+	return nil
+}
+
+// Check replays every question, recording its integrity verdict, typing
+// statistics and suspicion score under rules, which may be nil. With
+// integrity mode IntegrityReject, a question whose event log does not
+// reproduce its final answer fails the submission.
+func Check(submission *storage.Submission, integrityMode string, rules *suspicion.Rules) error {
+	for i := range submission.Questions {
+		question := &submission.Questions[i]
+		question.Integrity = eventlog.Check(question.EventLog, question.FinalAnswer)
+		question.Stats = eventlog.ComputeStats(question.EventLog)
+		question.Suspicion = rules.Score(question)
+	}
+
+	if integrityMode != IntegrityReject {
+		return nil
+	}
+	for _, question := range submission.Questions {
+		result := question.Integrity
+		switch result.Verdict {
+		case eventlog.VerdictMismatch:
+			return &ValidationError{
+				Field:   question.Key + ".finalAnswer",
+				Message: fmt.Sprintf("does not match replayed eventLog at offset %d", *result.DiffOffset),
+			}
+		case eventlog.VerdictUnreplayable:
+			return &ValidationError{Field: question.Key + ".eventLog", Message: "cannot be replayed: " + result.Detail}
+		}
+	}
+	return nil
+}
+
+// ValidationError represents a validation error
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return "validation error: " + e.Field + " - " + e.Message
+}
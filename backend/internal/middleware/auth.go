@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"backend/internal/config"
+	"backend/internal/storage"
+)
+
+// SessionCookie names the cookie that carries the session token
+const SessionCookie = "drkka_session"
+
+// MinPasswordLength is the shortest password accepted for an account
+const MinPasswordLength = 8
+
+// usernamePattern restricts usernames to characters safe in URLs and logs
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._@-]{1,64}$`)
+
+// contextKey keys the values this package stores in request contexts
+type contextKey int
+
+const userKey contextKey = iota
+
+// Authenticator resolves the signed-in user of each request and enforces
+// the roles required by endpoints
+type Authenticator struct {
+	store storage.Store
+	cfg   *config.AuthConfig
+}
+
+// NewAuthenticator creates an authenticator backed by the user and
+// session tables of store
+func NewAuthenticator(store storage.Store, cfg *config.AuthConfig) *Authenticator {
+	return &Authenticator{store: store, cfg: cfg}
+}
+
+// Authenticate attaches the user of a valid session token, taken from an
+// "Authorization: Bearer" header or the session cookie, to the request
+// context. Requests without a valid token continue anonymously.
+func (a *Authenticator) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := Token(r); token != "" {
+			session, err := a.store.GetSession(HashToken(token))
+			switch {
+			case err == nil:
+				r = r.WithContext(context.WithValue(r.Context(), userKey, &session.User))
+			case !errors.Is(err, storage.ErrNotFound):
+				log.Printf("Error retrieving session: %v", err)
+				http.Error(w, "Failed to check session", http.StatusInternalServerError)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Require only lets requests through whose user has one of roles,
+// answering 401 Unauthorized without a signed-in user and 403 Forbidden
+// for other roles. With authentication disabled every request passes.
+func (a *Authenticator) Require(roles ...storage.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !a.cfg.Enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := UserFrom(r.Context())
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="drkka"`)
+				http.Error(w, "Authentication required", http.StatusUnauthorized)
+				return
+			}
+			for _, role := range roles {
+				if user.Role == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		})
+	}
+}
+
+// UserFrom returns the signed-in user attached by Authenticate
+func UserFrom(ctx context.Context) (*storage.User, bool) {
+	user, ok := ctx.Value(userKey).(*storage.User)
+	return user, ok
+}
+
+// Token returns the session token of a request: the bearer token of the
+// Authorization header, or else the session cookie
+func Token(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// NewToken returns a random session token
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hash under which a session token is stored
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewUser validates an account and hashes its password
+func NewUser(username, password string, role storage.Role) (*storage.User, error) {
+	if !usernamePattern.MatchString(username) {
+		return nil, fmt.Errorf("username must be 1-64 letters, digits or . _ @ -")
+	}
+	if !role.Valid() {
+		return nil, fmt.Errorf("role must be one of %s, %s, %s", storage.RoleAdmin, storage.RoleEvaluator, storage.RoleStudent)
+	}
+
+	user := &storage.User{Username: username, Role: role}
+	if err := SetPassword(user, password); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetPassword validates password and stores its bcrypt hash in user
+func SetPassword(user *storage.User, password string) error {
+	if len([]rune(password)) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	// bcrypt ignores everything past 72 bytes
+	if len(password) > 72 {
+		return fmt.Errorf("password must be at most 72 bytes")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	user.PasswordHash = string(hash)
+	return nil
+}
+
+// dummyHash is compared against when a username is unknown, so that
+// failed logins take as long whether or not the user exists
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("drkka-unknown-user"), bcrypt.DefaultCost)
+	return hash
+})
+
+// CheckPassword reports whether password is the password of user, which
+// may be nil for an unknown username
+func CheckPassword(user *storage.User, password string) bool {
+	if user == nil {
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) == nil
+}
@@ -0,0 +1,142 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/handlers"
+	"backend/internal/middleware"
+	"backend/internal/storage"
+)
+
+const testPassword = "correct horse"
+
+// newTestServer serves an endpoint for evaluators and admins behind the
+// login and logout endpoints, with users ada (evaluator) and sam (student)
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	store := storage.NewMemoryStorage()
+	for username, role := range map[string]storage.Role{"ada": storage.RoleEvaluator, "sam": storage.RoleStudent} {
+		user, err := middleware.NewUser(username, testPassword, role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.CreateUser(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.AuthConfig{Enabled: true, SessionTTL: time.Hour}
+	auth := middleware.NewAuthenticator(store, cfg)
+	authHandler := handlers.NewAuthHandler(store, cfg)
+	evaluators := auth.Require(storage.RoleEvaluator, storage.RoleAdmin)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auth/login", authHandler.HandleLogin)
+	mux.HandleFunc("/auth/logout", authHandler.HandleLogout)
+	mux.Handle("/submissions", evaluators(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := middleware.UserFrom(r.Context())
+		w.Write([]byte(user.Username))
+	})))
+
+	server := httptest.NewServer(auth.Authenticate(mux))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// login signs in and returns the session token
+func login(t *testing.T, server *httptest.Server, username string) string {
+	t.Helper()
+	body := `{"username": "` + username + `", "password": "` + testPassword + `"}`
+	resp, err := http.Post(server.URL+"/auth/login", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login as %s: status %d", username, resp.StatusCode)
+	}
+	var response handlers.LoginResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	return response.Token
+}
+
+// send makes a request with a bearer token, unless token is empty
+func send(t *testing.T, server *httptest.Server, method, path, token string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestRequire(t *testing.T) {
+	server := newTestServer(t)
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"allowed role", login(t, server, "ada"), http.StatusOK},
+		{"other role", login(t, server, "sam"), http.StatusForbidden},
+		{"no session", "", http.StatusUnauthorized},
+		{"unknown token", "not-a-session", http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := send(t, server, http.MethodGet, "/submissions", tc.token)
+			if resp.StatusCode != tc.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tc.want)
+			}
+			if tc.want == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+				t.Error("401 without a WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestLoggedOutTokenStopsWorking(t *testing.T) {
+	server := newTestServer(t)
+	token := login(t, server, "ada")
+	other := login(t, server, "ada")
+
+	if resp := send(t, server, http.MethodPost, "/auth/logout", token); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("logout: status %d", resp.StatusCode)
+	}
+	if resp := send(t, server, http.MethodGet, "/submissions", token); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("logged-out token: status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+	// Other sessions of the user stay signed in
+	if resp := send(t, server, http.MethodGet, "/submissions", other); resp.StatusCode != http.StatusOK {
+		t.Errorf("other session: status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestRequireDisabled(t *testing.T) {
+	auth := middleware.NewAuthenticator(storage.NewMemoryStorage(), &config.AuthConfig{})
+	handler := auth.Require(storage.RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status with authentication disabled = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
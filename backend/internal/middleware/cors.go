@@ -18,12 +18,17 @@ func CORS(cfg *config.CORSConfig) func(http.Handler) http.Handler {
 			// Check if origin is allowed
 			if origin != "" && isOriginAllowed(origin, allowedOrigins) {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Vary", "Origin")
+				// Listed origins may send the session cookie
+				if allowedOrigins != "*" {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
 			} else if allowedOrigins == "*" {
 				w.Header().Set("Access-Control-Allow-Origin", "*")
 			}
 
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Exam-Session")
 			w.Header().Set("Access-Control-Max-Age", "3600")
 
 			// Handle preflight requests
@@ -0,0 +1,152 @@
+package playback
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"backend/internal/eventlog"
+)
+
+// Terminal sizes of casts: the default and the largest accepted
+const (
+	DefaultCols = 80
+	DefaultRows = 24
+	MaxCols     = 400
+	MaxRows     = 200
+)
+
+// frameMs is the shortest time between two frames of a cast; actions
+// closer together are drawn in one frame
+const frameMs = 1000.0 / 30
+
+// CastOptions tunes a cast file
+type CastOptions struct {
+	// Title is stored in the header and shown by players
+	Title string
+	// Width and Height are the terminal size in columns and rows
+	Width  int
+	Height int
+	// MaxPauseMs, when above 0, shortens longer pauses to it
+	MaxPauseMs float64
+	// Timestamp is when the recording started, such as the submission time
+	Timestamp time.Time
+}
+
+// castHeader is the first line of an asciinema v2 cast file
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Duration  float64           `json:"duration"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env"`
+}
+
+// WriteCast writes events as an asciinema v2 cast: a JSON header line,
+// then one [seconds, "o", output] line per frame of the answer as it was
+// typed, with pasted text highlighted and the cursor in reverse video
+func WriteCast(w io.Writer, events []eventlog.Event, opts CastOptions) error {
+	if err := CheckSize(opts.Width, opts.Height); err != nil {
+		return err
+	}
+
+	replayer := eventlog.NewReplayer(events)
+	actions := replayer.Actions()
+
+	// Frames: the index of the first action after each one and its time
+	type frame struct {
+		next int
+		at   float64
+	}
+	frames := []frame{{next: 0, at: 0}}
+	clock, prev := 0.0, 0.0
+	for i := 0; i < len(actions); {
+		gap := actions[i].At - prev
+		if opts.MaxPauseMs > 0 && gap > opts.MaxPauseMs {
+			gap = opts.MaxPauseMs
+		}
+		clock += gap
+		start := actions[i].At
+		for i < len(actions) && actions[i].At-start < frameMs {
+			i++
+		}
+		prev = actions[i-1].At
+		frames = append(frames, frame{next: i, at: clock + prev - start})
+		clock += prev - start
+	}
+
+	header := castHeader{
+		Version:  2,
+		Width:    opts.Width,
+		Height:   opts.Height,
+		Duration: roundSeconds(frames[len(frames)-1].at),
+		Title:    opts.Title,
+		Env:      map[string]string{"TERM": "xterm-256color"},
+	}
+	if !opts.Timestamp.IsZero() {
+		header.Timestamp = opts.Timestamp.Unix()
+	}
+	if err := writeJSONLine(w, header); err != nil {
+		return err
+	}
+
+	last := ""
+	for _, f := range frames {
+		replayer.Seek(f.next)
+		output := clearScreen + Render(replayer.Buffer(), opts.Height)
+		if output == last {
+			continue
+		}
+		if err := writeJSONLine(w, []interface{}{roundSeconds(f.at), "o", output}); err != nil {
+			return err
+		}
+		last = output
+	}
+	return nil
+}
+
+// CheckSize checks a terminal size against MaxCols and MaxRows
+func CheckSize(cols, rows int) error {
+	if cols < 1 || cols > MaxCols {
+		return fmt.Errorf("cols must be between 1 and %d", MaxCols)
+	}
+	if rows < 1 || rows > MaxRows {
+		return fmt.Errorf("rows must be between 1 and %d", MaxRows)
+	}
+	return nil
+}
+
+// writeJSONLine writes v as one line of JSON
+func writeJSONLine(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// roundSeconds converts milliseconds to seconds with microsecond precision
+func roundSeconds(ms float64) float64 {
+	return math.Round(ms*1000) / 1e6
+}
+
+// FileName names the file of a replay of a question, keeping only
+// letters, digits, '-' and '.' of its parts
+func FileName(examID, studentID, key, ext string) string {
+	clean := func(part string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.' {
+				return r
+			}
+			return '-'
+		}, part)
+	}
+	return clean(examID) + "_" + clean(studentID) + "_" + clean(key) + "." + ext
+}
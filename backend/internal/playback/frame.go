@@ -0,0 +1,85 @@
+// Package playback shows how an answer was typed in a terminal: frames of
+// the text as it was at a point of the event log, a transcript of the
+// edits, and an interactive player stepping through them in real time.
+package playback
+
+import (
+	"strings"
+
+	"backend/internal/eventlog"
+)
+
+// ANSI styles of rendered frames
+const (
+	stylePaste  = "\x1b[30;43m" // pasted characters: black on yellow
+	styleCursor = "\x1b[7m"     // cursor and selection: reverse video
+	styleReset  = "\x1b[0m"
+)
+
+// Render draws the text of buf for an ANSI terminal, with pasted
+// characters highlighted and the cursor or selection in reverse video.
+// Lines end with "\r\n", which terminals in raw mode need. With height
+// above 0, only that many lines are drawn, scrolled to keep the cursor in
+// view.
+func Render(buf *eventlog.Buffer, height int) string {
+	text := []rune(buf.Text())
+	start, end := buf.Selection()
+	cursor := buf.Cursor()
+
+	var lines []string
+	var line strings.Builder
+	cursorLine := 0
+	style := ""
+	setStyle := func(s string) {
+		if s != style {
+			line.WriteString(styleReset)
+			line.WriteString(s)
+			style = s
+		}
+	}
+	endLine := func() {
+		setStyle("")
+		lines = append(lines, line.String())
+		line.Reset()
+	}
+
+	for i := 0; i <= len(text); i++ {
+		selected := i >= start && i < end
+		atCursor := i == cursor && start == end
+		if i == cursor {
+			cursorLine = len(lines)
+		}
+		if i == len(text) || text[i] == '\n' {
+			// The cursor, or a selected newline, shows as a space
+			if atCursor || selected {
+				setStyle(styleCursor)
+				line.WriteByte(' ')
+			}
+			endLine()
+			continue
+		}
+
+		switch {
+		case selected || atCursor:
+			setStyle(styleCursor)
+		case buf.Pasted(i):
+			setStyle(stylePaste)
+		default:
+			setStyle("")
+		}
+		if text[i] == '\t' {
+			line.WriteString("    ")
+		} else {
+			line.WriteRune(text[i])
+		}
+	}
+
+	if height > 0 && len(lines) > height {
+		first := cursorLine - height + 1
+		if first < 0 {
+			first = 0
+		}
+		lines = lines[first : first+height]
+	}
+	return strings.Join(lines, "\r\n")
+}
@@ -0,0 +1,102 @@
+package playback
+
+import (
+	"bufio"
+	"io"
+)
+
+// Key is a player command read from the terminal
+type Key int
+
+// Player commands and the keys giving them
+const (
+	KeyNone      Key = iota
+	KeyPause         // space or p
+	KeyStep          // → or .
+	KeyBack          // ← or ,
+	KeyFaster        // + or =
+	KeySlower        // - or _
+	KeySeekAhead     // ↑ or ]
+	KeySeekBack      // ↓ or [
+	KeyStart         // Home or 0
+	KeyEnd           // End or $
+	KeyQuit          // q, Esc or Ctrl-C
+)
+
+// ReadKeys reads key presses from a terminal in raw mode until it fails,
+// then closes the channel
+func ReadKeys(r io.Reader) <-chan Key {
+	keys := make(chan Key)
+	go func() {
+		defer close(keys)
+		reader := bufio.NewReader(r)
+		for {
+			key, err := readKey(reader)
+			if err != nil {
+				return
+			}
+			if key != KeyNone {
+				keys <- key
+			}
+		}
+	}()
+	return keys
+}
+
+// readKey reads one key press, decoding the escape sequences of arrow,
+// Home and End keys
+func readKey(reader *bufio.Reader) (Key, error) {
+	b, err := reader.ReadByte()
+	if err != nil {
+		return KeyNone, err
+	}
+	switch b {
+	case ' ', 'p':
+		return KeyPause, nil
+	case '.':
+		return KeyStep, nil
+	case ',':
+		return KeyBack, nil
+	case '+', '=':
+		return KeyFaster, nil
+	case '-', '_':
+		return KeySlower, nil
+	case ']':
+		return KeySeekAhead, nil
+	case '[':
+		return KeySeekBack, nil
+	case '0':
+		return KeyStart, nil
+	case '$':
+		return KeyEnd, nil
+	case 'q', 'Q', 0x03:
+		return KeyQuit, nil
+	case 0x1b:
+		// A lone Esc quits; CSI sequences name the other keys
+		if reader.Buffered() == 0 {
+			return KeyQuit, nil
+		}
+		if next, _ := reader.ReadByte(); next != '[' && next != 'O' {
+			return KeyNone, nil
+		}
+		code, err := reader.ReadByte()
+		if err != nil {
+			return KeyNone, err
+		}
+		switch code {
+		case 'C':
+			return KeyStep, nil
+		case 'D':
+			return KeyBack, nil
+		case 'A':
+			return KeySeekAhead, nil
+		case 'B':
+			return KeySeekBack, nil
+		case 'H':
+			return KeyStart, nil
+		case 'F':
+			return KeyEnd, nil
+		}
+	}
+	return KeyNone, nil
+}
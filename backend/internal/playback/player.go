@@ -0,0 +1,265 @@
+package playback
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"backend/internal/eventlog"
+)
+
+// Speeds are the playback speeds KeyFaster and KeySlower step through
+var Speeds = []float64{0.25, 0.5, 1, 2, 4, 8, 16, 32}
+
+// SeekMs is how far KeySeekAhead and KeySeekBack move, in log time
+const SeekMs = 10000
+
+// refresh is how often the clock is redrawn while nothing is typed
+const refresh = 250 * time.Millisecond
+
+// Terminal control sequences of the player screen
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l" // alternate screen, cursor hidden
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+	clearScreen = "\x1b[H\x1b[2J"
+)
+
+// Options tunes a Player
+type Options struct {
+	// Title is shown on the status line, such as the exam, student and
+	// question played
+	Title string
+	// Speed multiplies the pace of the log; 1 is real time
+	Speed float64
+	// MaxPauseMs, when above 0, shortens longer pauses to it
+	MaxPauseMs float64
+	// StartMs is the log time playback starts at
+	StartMs float64
+	// Height is the number of terminal rows; 0 draws the whole text
+	Height int
+}
+
+// Player plays an event log back in a terminal at the pace it was typed,
+// honoring the latency of every event and the interval of COMPRESSED
+// segments
+type Player struct {
+	opts     Options
+	replayer *eventlog.Replayer
+	actions  []eventlog.Action
+	out      io.Writer
+	// at is the log time shown, in milliseconds since the first event
+	at     float64
+	paused bool
+}
+
+// NewPlayer creates a player of events drawing to out
+func NewPlayer(events []eventlog.Event, out io.Writer, opts Options) *Player {
+	if opts.Speed <= 0 {
+		opts.Speed = 1
+	}
+	p := &Player{opts: opts, replayer: eventlog.NewReplayer(events), out: out}
+	p.actions = p.replayer.Actions()
+	p.seek(opts.StartMs)
+	return p
+}
+
+// Run plays until keys gives KeyQuit or is closed. Playback pauses at the
+// end, where the seek keys still work.
+func (p *Player) Run(keys <-chan Key) error {
+	if _, err := io.WriteString(p.out, enterScreen); err != nil {
+		return err
+	}
+	defer io.WriteString(p.out, leaveScreen)
+
+	last := time.Now()
+	for {
+		if err := p.draw(); err != nil {
+			return err
+		}
+
+		timer := time.NewTimer(p.wait())
+		select {
+		case <-timer.C:
+			p.advance(time.Since(last))
+		case key, ok := <-keys:
+			timer.Stop()
+			p.advance(time.Since(last))
+			if !ok || key == KeyQuit {
+				return nil
+			}
+			p.handle(key)
+		}
+		last = time.Now()
+	}
+}
+
+// playing reports whether the clock runs
+func (p *Player) playing() bool {
+	return !p.paused && !p.replayer.Done()
+}
+
+// wait returns how long to wait for the next action, or to redraw the clock
+func (p *Player) wait() time.Duration {
+	if !p.playing() {
+		return time.Hour
+	}
+	next := p.actions[p.replayer.Position()].At
+	wait := time.Duration((next - p.at) / p.rate() * float64(time.Millisecond))
+	if wait > refresh {
+		return refresh
+	}
+	return wait
+}
+
+// rate is the log time played per millisecond, faster through pauses
+// shortened to MaxPauseMs
+func (p *Player) rate() float64 {
+	rate := p.opts.Speed
+	if p.opts.MaxPauseMs <= 0 || p.replayer.Done() {
+		return rate
+	}
+	position := p.replayer.Position()
+	prev := 0.0
+	if position > 0 {
+		prev = p.actions[position-1].At
+	}
+	if gap := p.actions[position].At - prev; gap > p.opts.MaxPauseMs {
+		rate *= gap / p.opts.MaxPauseMs
+	}
+	return rate
+}
+
+// advance moves the clock by elapsed wall time and applies the actions it
+// reaches; the clock stops at the next action so none is skipped unseen
+func (p *Player) advance(elapsed time.Duration) {
+	if !p.playing() {
+		return
+	}
+	next := p.actions[p.replayer.Position()].At
+	p.at += float64(elapsed) / float64(time.Millisecond) * p.rate()
+	if p.at < next {
+		return
+	}
+	p.at = next
+	for !p.replayer.Done() && p.actions[p.replayer.Position()].At <= p.at {
+		p.replayer.Step()
+	}
+}
+
+// handle applies a key press
+func (p *Player) handle(key Key) {
+	switch key {
+	case KeyPause:
+		p.paused = !p.paused
+	case KeyStep:
+		p.paused = true
+		if action, ok := p.replayer.Step(); ok {
+			p.at = action.At
+		}
+	case KeyBack:
+		p.paused = true
+		if position := p.replayer.Position(); position > 0 {
+			p.replayer.Seek(position - 1)
+			p.at = p.lastAt()
+		}
+	case KeyFaster:
+		p.opts.Speed = nextSpeed(p.opts.Speed, 1)
+	case KeySlower:
+		p.opts.Speed = nextSpeed(p.opts.Speed, -1)
+	case KeySeekAhead:
+		p.seek(p.at + SeekMs)
+	case KeySeekBack:
+		p.seek(p.at - SeekMs)
+	case KeyStart:
+		p.seek(0)
+	case KeyEnd:
+		p.seek(p.endAt())
+	}
+}
+
+// seek moves to a log time, applying every action up to it
+func (p *Player) seek(atMs float64) {
+	if atMs < 0 {
+		atMs = 0
+	}
+	if end := p.endAt(); atMs > end {
+		atMs = end
+	}
+	p.replayer.SeekTime(atMs)
+	p.at = atMs
+}
+
+// lastAt is the time of the last action applied
+func (p *Player) lastAt() float64 {
+	if position := p.replayer.Position(); position > 0 {
+		return p.actions[position-1].At
+	}
+	return 0
+}
+
+// endAt is the time of the last action of the log
+func (p *Player) endAt() float64 {
+	if len(p.actions) == 0 {
+		return 0
+	}
+	return p.actions[len(p.actions)-1].At
+}
+
+// nextSpeed steps through Speeds from the current speed
+func nextSpeed(speed float64, dir int) float64 {
+	i := 0
+	for i < len(Speeds)-1 && Speeds[i] < speed {
+		i++
+	}
+	if Speeds[i] > speed && dir > 0 {
+		dir = 0
+	}
+	i += dir
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(Speeds) {
+		i = len(Speeds) - 1
+	}
+	return Speeds[i]
+}
+
+// draw redraws the screen: status line, last edit, text and key help
+func (p *Player) draw() error {
+	state := "▶"
+	switch {
+	case p.replayer.Done():
+		state = "■"
+	case p.paused:
+		state = "⏸"
+	}
+
+	lastEdit := ""
+	if position := p.replayer.Position(); position > 0 {
+		lastEdit = describe(p.actions[position-1:position], -1)
+	}
+
+	height := 0
+	if p.opts.Height > 0 {
+		height = p.opts.Height - 4
+		if height < 1 {
+			height = 1
+		}
+	}
+
+	var screen strings.Builder
+	screen.WriteString(clearScreen)
+	fmt.Fprintf(&screen, "%s %s / %s  ×%g  %d/%d actions  %s\r\n", state, FormatTime(p.at), FormatTime(p.endAt()),
+		p.opts.Speed, p.replayer.Position(), len(p.actions), p.opts.Title)
+	fmt.Fprintf(&screen, "%s\r\n\r\n", lastEdit)
+	screen.WriteString(Render(p.replayer.Buffer(), height))
+	if p.opts.Height > 0 {
+		fmt.Fprintf(&screen, "\x1b[%d;1H", p.opts.Height)
+	} else {
+		screen.WriteString("\r\n")
+	}
+	screen.WriteString("space pause  ←/→ step  ↑/↓ seek 10s  +/- speed  0/$ start/end  q quit")
+	_, err := io.WriteString(p.out, screen.String())
+	return err
+}
@@ -0,0 +1,119 @@
+package playback
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"backend/internal/analytics"
+	"backend/internal/eventlog"
+)
+
+// maxQuoted is the number of characters of typed or pasted text quoted in
+// a transcript line
+const maxQuoted = 60
+
+// WriteTranscript replays events and writes one timestamped line per
+// edit. Characters typed without a pause of analytics.BurstGapMs, and
+// repeated presses of a key, are written as one edit. The cursor offset
+// is where the edit started.
+func WriteTranscript(w io.Writer, events []eventlog.Event) error {
+	replayer := eventlog.NewReplayer(events)
+	actions := replayer.Actions()
+	buf := replayer.Buffer()
+
+	for i := 0; i < len(actions); {
+		first := actions[i]
+		cursor := buf.Cursor()
+		j := i + 1
+		for j < len(actions) && sameEdit(first, actions[j-1], actions[j]) {
+			j++
+		}
+		line := describe(actions[i:j], cursor)
+		replayer.Seek(j)
+		if _, err := fmt.Fprintf(w, "%s  %s\n", FormatTime(first.At), line); err != nil {
+			return err
+		}
+		i = j
+	}
+
+	endMs := 0.0
+	if len(actions) > 0 {
+		endMs = actions[len(actions)-1].At
+	}
+	_, err := fmt.Fprintf(w, "%s  %-7s %d characters, %d pasted\n", FormatTime(endMs), "end", buf.Len(), buf.PastedLen())
+	return err
+}
+
+// sameEdit reports whether next continues the edit started by first,
+// right after prev
+func sameEdit(first, prev, next eventlog.Action) bool {
+	if next.At-prev.At >= analytics.BurstGapMs {
+		return false
+	}
+	switch {
+	case typed(first):
+		return typed(next)
+	case first.Op == eventlog.OpKey:
+		return next.Op == eventlog.OpKey && next.Key == first.Key
+	}
+	return false
+}
+
+// typed reports whether an action types a character, Enter included
+func typed(action eventlog.Action) bool {
+	return action.Op == eventlog.OpInsert || (action.Op == eventlog.OpKey && action.Key == eventlog.KeyEnter)
+}
+
+// describe names an edit made of one or more actions, starting at the
+// cursor offset unless it is negative
+func describe(actions []eventlog.Action, cursor int) string {
+	first := actions[0]
+	switch {
+	case typed(first):
+		var text strings.Builder
+		for _, action := range actions {
+			if action.Op == eventlog.OpInsert {
+				text.WriteString(action.Text)
+			} else {
+				text.WriteByte('\n')
+			}
+		}
+		return fmt.Sprintf("%-7s %s%s", "typed", quote(text.String()), offset(cursor))
+	case first.Op == eventlog.OpPaste:
+		return fmt.Sprintf("%-7s %s%s (%d characters)", "pasted", quote(first.Text), offset(cursor), len([]rune(first.Text)))
+	case first.Op == eventlog.OpKey && len(actions) > 1:
+		return fmt.Sprintf("%-7s %s ×%d%s", "key", first.Key, len(actions), offset(cursor))
+	case first.Op == eventlog.OpKey:
+		return fmt.Sprintf("%-7s %s%s", "key", first.Key, offset(cursor))
+	case first.Op == eventlog.OpSelect && first.Start == first.End:
+		return fmt.Sprintf("%-7s %d", "cursor", first.Start)
+	case first.Op == eventlog.OpSelect:
+		return fmt.Sprintf("%-7s %d-%d", "select", first.Start, first.End)
+	}
+	return first.Op.String()
+}
+
+// offset writes where an edit started
+func offset(cursor int) string {
+	if cursor < 0 {
+		return ""
+	}
+	return fmt.Sprintf(" at %d", cursor)
+}
+
+// quote quotes text, shortened to maxQuoted characters
+func quote(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxQuoted {
+		return strconv.Quote(text)
+	}
+	return strconv.Quote(string(runes[:maxQuoted])) + "…"
+}
+
+// FormatTime writes milliseconds as minutes, seconds and milliseconds
+func FormatTime(ms float64) string {
+	total := int64(ms + 0.5)
+	return fmt.Sprintf("%02d:%02d.%03d", total/60000, total/1000%60, total%1000)
+}
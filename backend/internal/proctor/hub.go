@@ -0,0 +1,473 @@
+// Package proctor fans the in-progress answers of exam sessions out to
+// proctors watching an exam live. Answers are rebuilt from the autosaved
+// chunks as they arrive; the hub lives in one server process and only
+// knows sessions that started or autosaved since it started.
+package proctor
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/eventlog"
+	"backend/internal/storage"
+)
+
+// Status is what a student is doing, as shown to proctors
+type Status string
+
+// Student statuses
+const (
+	// StatusStarted: the session started and nothing was typed yet
+	StatusStarted Status = "started"
+	// StatusTyping: the last autosave was recent
+	StatusTyping Status = "typing"
+	// StatusPasting: the last autosave was recent and contained a paste
+	StatusPasting Status = "pasting"
+	// StatusIdle: nothing was autosaved for the idle period
+	StatusIdle Status = "idle"
+	// StatusSubmitted: the session submitted
+	StatusSubmitted Status = "submitted"
+)
+
+// Answer is a question's answer as typed so far
+type Answer struct {
+	QuestionIndex int    `json:"questionIndex"`
+	Text          string `json:"text"`
+	Chunks        int    `json:"chunks"`
+}
+
+// Student is the live state of one exam session
+type Student struct {
+	SessionID    string    `json:"sessionId"`
+	StudentID    string    `json:"studentId"`
+	Status       Status    `json:"status"`
+	StartedAt    time.Time `json:"startedAt"`
+	LastActivity time.Time `json:"lastActivity"`
+	Events       int       `json:"events"`
+	Pastes       int       `json:"pastes"`
+	Answers      []Answer  `json:"answers"`
+}
+
+// UpdateType names an update; it is the event name on the stream
+type UpdateType string
+
+// Update types
+const (
+	UpdateStarted   UpdateType = "started"
+	UpdateChunk     UpdateType = "chunk"
+	UpdateSubmitted UpdateType = "submitted"
+)
+
+// Update is one change to a student of an exam
+type Update struct {
+	Type    UpdateType `json:"type"`
+	ExamID  string     `json:"examId"`
+	Student Student    `json:"student"`
+	// Chunk holds the new events of chunk updates
+	Chunk *ChunkUpdate `json:"chunk,omitempty"`
+}
+
+// ChunkUpdate is an autosaved chunk
+type ChunkUpdate struct {
+	QuestionIndex int          `json:"questionIndex"`
+	Seq           int          `json:"seq"`
+	Events        eventlog.Log `json:"events"`
+}
+
+// Subscriber receives the updates of one exam
+type Subscriber struct {
+	updates chan Update
+	done    chan struct{}
+}
+
+// Updates delivers the exam's updates in order
+func (s *Subscriber) Updates() <-chan Update {
+	return s.updates
+}
+
+// Done is closed when the subscriber fell too far behind or the hub
+// closed; the subscriber then receives nothing more
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Hub tracks the exam sessions in progress and their subscribers
+type Hub struct {
+	store     storage.Store
+	idleAfter time.Duration
+	buffer    int
+
+	mu    sync.Mutex
+	exams map[string]*exam
+	// submitted holds when each forgotten submitted session expires, so
+	// late chunks of it are ignored rather than bringing it back
+	submitted map[string]time.Time
+	closed    bool
+}
+
+// exam is the channel of one exam
+type exam struct {
+	sessions    map[string]*session
+	subscribers map[*Subscriber]bool
+}
+
+// session is the live state of one exam session
+type session struct {
+	id           string
+	studentID    string
+	startedAt    time.Time
+	expiresAt    time.Time
+	lastActivity time.Time
+	// lastPaste is when the last chunk containing a paste arrived
+	lastPaste time.Time
+	submitted bool
+	answers   map[int]*answer
+}
+
+// answer is a question's answer rebuilt from the chunks applied so far
+type answer struct {
+	buf    *eventlog.Buffer
+	chunks int
+	events int
+	pastes int
+}
+
+// NewHub creates a hub reading the chunks it missed from store
+func NewHub(store storage.Store, cfg *config.ProctorConfig) *Hub {
+	return &Hub{
+		store:     store,
+		idleAfter: cfg.IdleAfter,
+		buffer:    cfg.Buffer,
+		exams:     make(map[string]*exam),
+		submitted: make(map[string]time.Time),
+	}
+}
+
+// Started announces a new exam session
+func (h *Hub) Started(es *storage.ExamSession) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.prune(time.Now())
+	s := &session{
+		id:           es.ID,
+		studentID:    es.StudentID,
+		startedAt:    es.StartedAt,
+		expiresAt:    es.ExpiresAt,
+		lastActivity: es.StartedAt,
+		answers:      make(map[int]*answer),
+	}
+	h.exam(es.ExamID).sessions[s.id] = s
+	h.publish(es.ExamID, Update{Type: UpdateStarted, Student: h.student(s, time.Now())})
+}
+
+// Chunk announces a chunk autosaved in a session of a student of examID
+func (h *Hub) Chunk(examID, studentID string, chunk *storage.Chunk) {
+	s := h.lockSession(examID, studentID, chunk.SessionID)
+	if s != nil && !s.add(chunk, nil) {
+		// Missed chunks, e.g. after a restart: rebuild from storage
+		// without holding the lock, then merge unless another chunk of
+		// the question got ahead meanwhile
+		h.mu.Unlock()
+		rebuilt := h.rebuild(chunk)
+		s = h.lockSession(examID, studentID, chunk.SessionID)
+		if s != nil {
+			s.add(chunk, rebuilt)
+		}
+	}
+	defer h.mu.Unlock()
+	if s == nil {
+		return
+	}
+
+	s.lastActivity = chunk.ReceivedAt
+	for _, event := range chunk.Events {
+		if event.EventType() == eventlog.TypeRawPaste {
+			s.lastPaste = chunk.ReceivedAt
+			break
+		}
+	}
+
+	h.publish(examID, Update{
+		Type:    UpdateChunk,
+		Student: h.student(s, time.Now()),
+		Chunk:   &ChunkUpdate{QuestionIndex: chunk.QuestionIndex, Seq: chunk.Seq, Events: chunk.Events},
+	})
+}
+
+// Submitted announces the submission of a session. The session is
+// published as submitted once and then forgotten, as its answers are
+// stored with the submission.
+func (h *Hub) Submitted(examID, studentID, sessionID string, at time.Time) {
+	s := h.lockSession(examID, studentID, sessionID)
+	defer h.mu.Unlock()
+	if s == nil {
+		return
+	}
+
+	h.submitted[sessionID] = s.expiresAt
+	s.submitted = true
+	s.lastActivity = at
+	h.publish(examID, Update{Type: UpdateSubmitted, Student: h.student(s, time.Now())})
+
+	e := h.exams[examID]
+	delete(e.sessions, sessionID)
+	h.dropIfUnused(examID, e)
+}
+
+// Subscribe registers a subscriber for the updates of an exam and returns
+// the exam's students as of the subscription
+func (h *Hub) Subscribe(examID string) (*Subscriber, []Student) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &Subscriber{updates: make(chan Update, h.buffer), done: make(chan struct{})}
+	if h.closed {
+		close(sub.done)
+	} else {
+		h.exam(examID).subscribers[sub] = true
+	}
+	return sub, h.students(examID)
+}
+
+// Unsubscribe removes a subscriber
+func (h *Hub) Unsubscribe(examID string, sub *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if e, ok := h.exams[examID]; ok && e.subscribers[sub] {
+		delete(e.subscribers, sub)
+		close(sub.done)
+		h.dropIfUnused(examID, e)
+	}
+}
+
+// Students returns the students of an exam ordered by student ID
+func (h *Hub) Students(examID string) []Student {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.students(examID)
+}
+
+// Close ends every subscription, for server shutdown
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, e := range h.exams {
+		for sub := range e.subscribers {
+			close(sub.done)
+		}
+		e.subscribers = make(map[*Subscriber]bool)
+	}
+}
+
+// exam returns the channel of an exam, creating it
+func (h *Hub) exam(examID string) *exam {
+	e, ok := h.exams[examID]
+	if !ok {
+		e = &exam{sessions: make(map[string]*session), subscribers: make(map[*Subscriber]bool)}
+		h.exams[examID] = e
+	}
+	return e
+}
+
+// dropIfUnused forgets an exam left without sessions and subscribers
+func (h *Hub) dropIfUnused(examID string, e *exam) {
+	if len(e.sessions) == 0 && len(e.subscribers) == 0 {
+		delete(h.exams, examID)
+	}
+}
+
+// prune forgets the expired sessions of every exam, and the exams left
+// unused
+func (h *Hub) prune(now time.Time) {
+	for id, expiresAt := range h.submitted {
+		if now.After(expiresAt) {
+			delete(h.submitted, id)
+		}
+	}
+	for examID, e := range h.exams {
+		for id, s := range e.sessions {
+			if s.expired(now) {
+				delete(e.sessions, id)
+			}
+		}
+		h.dropIfUnused(examID, e)
+	}
+}
+
+// lockSession locks the hub and returns a session of an exam, or nil for
+// a session that was submitted. A session the hub has not seen start is
+// loaded from the store before taking the lock, so no database query
+// holds up the other sessions.
+func (h *Hub) lockSession(examID, studentID, id string) *session {
+	var loaded *session
+	for {
+		h.mu.Lock()
+		if _, ok := h.submitted[id]; ok {
+			return nil
+		}
+		if e, ok := h.exams[examID]; ok && e.sessions[id] != nil {
+			return e.sessions[id]
+		}
+		if loaded != nil {
+			h.prune(time.Now())
+			h.exam(examID).sessions[id] = loaded
+			return loaded
+		}
+		h.mu.Unlock()
+		loaded = h.load(studentID, id)
+	}
+}
+
+// load reads a session the hub has not seen start from the store
+func (h *Hub) load(studentID, id string) *session {
+	s := &session{id: id, studentID: studentID, answers: make(map[int]*answer)}
+	if es, err := h.store.GetExamSession(id); err == nil {
+		s.startedAt, s.expiresAt, s.lastActivity = es.StartedAt, es.ExpiresAt, es.StartedAt
+	} else {
+		log.Printf("Error retrieving exam session %s for proctoring: %v", id, err)
+	}
+	return s
+}
+
+// expired reports whether the session's token has expired at now
+func (s *session) expired(now time.Time) bool {
+	return !s.expiresAt.IsZero() && now.After(s.expiresAt)
+}
+
+// add applies the next chunk of a question, or takes rebuilt, the
+// question replayed from storage, when chunks were missed. It reports
+// false when chunks were missed and rebuilt is nil.
+func (s *session) add(chunk *storage.Chunk, rebuilt *answer) bool {
+	a := s.answers[chunk.QuestionIndex]
+	switch {
+	case a == nil && chunk.Seq == 1:
+		a = &answer{buf: eventlog.NewBuffer()}
+		a.apply(chunk.Events)
+		s.answers[chunk.QuestionIndex] = a
+	case a != nil && a.chunks == chunk.Seq-1:
+		a.apply(chunk.Events)
+	case a != nil && a.chunks >= chunk.Seq:
+		// Already replayed by a concurrent rebuild that read the chunk
+	case rebuilt != nil:
+		s.answers[chunk.QuestionIndex] = rebuilt
+	default:
+		return false
+	}
+	return true
+}
+
+// rebuild replays a question's stored chunks up to chunk
+func (h *Hub) rebuild(chunk *storage.Chunk) *answer {
+	a := &answer{buf: eventlog.NewBuffer()}
+	chunks, err := h.store.ListQuestionChunks(chunk.SessionID, chunk.QuestionIndex)
+	if err != nil {
+		log.Printf("Error retrieving chunks of exam session %s for proctoring: %v", chunk.SessionID, err)
+		a.apply(chunk.Events)
+		return a
+	}
+	for _, stored := range chunks {
+		if stored.Seq <= chunk.Seq {
+			a.apply(stored.Events)
+		}
+	}
+	return a
+}
+
+// apply replays the events of the next chunk
+func (a *answer) apply(events eventlog.Log) {
+	for _, action := range eventlog.Expand(events) {
+		a.buf.Apply(action)
+	}
+	for _, event := range events {
+		if event.EventType() == eventlog.TypeRawPaste {
+			a.pastes++
+		}
+	}
+	a.chunks++
+	a.events += len(events)
+}
+
+// publish queues an update for every subscriber of the exam, closing
+// those whose queue is full rather than waiting for them
+func (h *Hub) publish(examID string, update Update) {
+	update.ExamID = examID
+	e, ok := h.exams[examID]
+	if !ok {
+		return
+	}
+	for sub := range e.subscribers {
+		select {
+		case sub.updates <- update:
+		default:
+			delete(e.subscribers, sub)
+			close(sub.done)
+			log.Printf("⚠️  Proctor stream of exam %s fell %d updates behind and was closed", examID, h.buffer)
+		}
+	}
+}
+
+// students lists the sessions of an exam, dropping expired ones
+func (h *Hub) students(examID string) []Student {
+	e, ok := h.exams[examID]
+	if !ok {
+		return []Student{}
+	}
+
+	now := time.Now()
+	students := make([]Student, 0, len(e.sessions))
+	for id, s := range e.sessions {
+		if s.expired(now) {
+			delete(e.sessions, id)
+			continue
+		}
+		students = append(students, h.student(s, now))
+	}
+	sort.Slice(students, func(i, j int) bool {
+		if students[i].StudentID != students[j].StudentID {
+			return students[i].StudentID < students[j].StudentID
+		}
+		return students[i].SessionID < students[j].SessionID
+	})
+	return students
+}
+
+// student is the proctor's view of a session at now
+func (h *Hub) student(s *session, now time.Time) Student {
+	student := Student{
+		SessionID:    s.id,
+		StudentID:    s.studentID,
+		StartedAt:    s.startedAt,
+		LastActivity: s.lastActivity,
+		Answers:      make([]Answer, 0, len(s.answers)),
+	}
+	for questionIndex, a := range s.answers {
+		student.Events += a.events
+		student.Pastes += a.pastes
+		student.Answers = append(student.Answers, Answer{QuestionIndex: questionIndex, Text: a.buf.Text(), Chunks: a.chunks})
+	}
+	sort.Slice(student.Answers, func(i, j int) bool {
+		return student.Answers[i].QuestionIndex < student.Answers[j].QuestionIndex
+	})
+
+	switch {
+	case s.submitted:
+		student.Status = StatusSubmitted
+	case now.Sub(s.lastActivity) >= h.idleAfter:
+		student.Status = StatusIdle
+	case !s.lastPaste.IsZero() && s.lastPaste.Equal(s.lastActivity):
+		student.Status = StatusPasting
+	case student.Events == 0:
+		student.Status = StatusStarted
+	default:
+		student.Status = StatusTyping
+	}
+	return student
+}
@@ -0,0 +1,192 @@
+package proctor
+
+import (
+	"testing"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/eventlog"
+	"backend/internal/storage"
+)
+
+// slowStore blocks ListQuestionChunks until release is closed, announcing
+// each call on listing
+type slowStore struct {
+	*storage.MemoryStorage
+	listing chan struct{}
+	release chan struct{}
+}
+
+func (s *slowStore) ListQuestionChunks(sessionID string, questionIndex int) ([]storage.Chunk, error) {
+	s.listing <- struct{}{}
+	<-s.release
+	return s.MemoryStorage.ListQuestionChunks(sessionID, questionIndex)
+}
+
+func newTestHub(store storage.Store) *Hub {
+	return NewHub(store, &config.ProctorConfig{IdleAfter: time.Minute, Buffer: 16})
+}
+
+// startSession registers a session of EXAM-1 expiring after ttl
+func startSession(t *testing.T, store storage.Store, id string, ttl time.Duration) *storage.ExamSession {
+	t.Helper()
+	now := time.Now().UTC()
+	es := &storage.ExamSession{ID: id, ExamID: "EXAM-1", StudentID: "student-" + id, Questions: []int{1}, Versions: []int{1}, StartedAt: now, ExpiresAt: now.Add(ttl)}
+	if err := store.CreateExamSession(es); err != nil {
+		t.Fatal(err)
+	}
+	return es
+}
+
+// saveChunk stores the next chunk of question 1, typing s
+func saveChunk(t *testing.T, store storage.Store, sessionID string, seq int, s string) *storage.Chunk {
+	t.Helper()
+	chunk := &storage.Chunk{SessionID: sessionID, QuestionIndex: 1, Seq: seq, Events: eventlog.Log{&eventlog.Compressed{String: s}}, ReceivedAt: time.Now().UTC()}
+	if err := store.SaveChunk(chunk); err != nil {
+		t.Fatal(err)
+	}
+	return chunk
+}
+
+func TestChunkRebuildsMissedChunks(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	es := startSession(t, store, "s1", time.Hour)
+	saveChunk(t, store, es.ID, 1, "hello")
+	chunk := saveChunk(t, store, es.ID, 2, " world")
+
+	// The hub never saw the session start nor its first chunk
+	hub := newTestHub(store)
+	hub.Chunk(es.ExamID, es.StudentID, chunk)
+
+	students := hub.Students(es.ExamID)
+	if len(students) != 1 {
+		t.Fatalf("students = %+v, want one", students)
+	}
+	if got := students[0]; got.StartedAt.IsZero() || len(got.Answers) != 1 || got.Answers[0].Text != "hello world" || got.Answers[0].Chunks != 2 {
+		t.Errorf("student = %+v, want started with answer %q of 2 chunks", got, "hello world")
+	}
+}
+
+func TestChunkRebuildsWithoutHoldingTheLock(t *testing.T) {
+	store := &slowStore{MemoryStorage: storage.NewMemoryStorage(), listing: make(chan struct{}), release: make(chan struct{})}
+	es := startSession(t, store, "s1", time.Hour)
+	other := startSession(t, store, "s2", time.Hour)
+	saveChunk(t, store, es.ID, 1, "hello")
+	chunk := saveChunk(t, store, es.ID, 2, " world")
+
+	hub := newTestHub(store)
+	hub.Started(es)
+	hub.Started(other)
+
+	done := make(chan struct{})
+	go func() {
+		hub.Chunk(es.ExamID, es.StudentID, chunk)
+		close(done)
+	}()
+	<-store.listing
+
+	// Other sessions and proctors carry on while the chunks are read
+	otherChunk := saveChunk(t, store, other.ID, 1, "hi")
+	carriedOn := make(chan []Student)
+	go func() {
+		hub.Chunk(other.ExamID, other.StudentID, otherChunk)
+		carriedOn <- hub.Students(es.ExamID)
+	}()
+	select {
+	case students := <-carriedOn:
+		if len(students) != 2 {
+			t.Errorf("students while rebuilding = %+v, want two", students)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the hub stayed locked while reading chunks")
+	}
+
+	close(store.release)
+	<-done
+	for _, student := range hub.Students(es.ExamID) {
+		want := map[string]string{"s1": "hello world", "s2": "hi"}[student.SessionID]
+		if len(student.Answers) != 1 || student.Answers[0].Text != want {
+			t.Errorf("session %s answers = %+v, want %q", student.SessionID, student.Answers, want)
+		}
+	}
+}
+
+func TestSubmittedSessionsAreForgotten(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	es := startSession(t, store, "s1", time.Hour)
+	hub := newTestHub(store)
+	hub.Started(es)
+	hub.Chunk(es.ExamID, es.StudentID, saveChunk(t, store, es.ID, 1, "done"))
+
+	sub, _ := hub.Subscribe(es.ExamID)
+	hub.Submitted(es.ExamID, es.StudentID, es.ID, time.Now())
+	update := <-sub.Updates()
+	if update.Type != UpdateSubmitted || update.Student.Status != StatusSubmitted || update.Student.Answers[0].Text != "done" {
+		t.Errorf("update = %+v, want the submitted answer", update)
+	}
+	if students := hub.Students(es.ExamID); len(students) != 0 {
+		t.Errorf("students after submit = %+v, want none", students)
+	}
+
+	hub.Unsubscribe(es.ExamID, sub)
+	if len(hub.exams) != 0 {
+		t.Errorf("exams after the last subscriber left = %d, want 0", len(hub.exams))
+	}
+}
+
+func TestChunksAfterSubmitAreIgnored(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	es := startSession(t, store, "s1", time.Hour)
+	hub := newTestHub(store)
+	hub.Started(es)
+	hub.Chunk(es.ExamID, es.StudentID, saveChunk(t, store, es.ID, 1, "done"))
+	hub.Submitted(es.ExamID, es.StudentID, es.ID, time.Now())
+
+	// A chunk that passed its checks before the submission arrives late
+	sub, _ := hub.Subscribe(es.ExamID)
+	hub.Chunk(es.ExamID, es.StudentID, saveChunk(t, store, es.ID, 2, " late"))
+	if students := hub.Students(es.ExamID); len(students) != 0 {
+		t.Errorf("students after a late chunk = %+v, want none", students)
+	}
+	select {
+	case update := <-sub.Updates():
+		t.Errorf("late chunk published %+v", update)
+	default:
+	}
+}
+
+func TestSubmittedSessionsAreForgottenOnExpiry(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	hub := newTestHub(store)
+	expired := startSession(t, store, "s1", -time.Minute)
+	hub.Submitted(expired.ExamID, expired.StudentID, expired.ID, time.Now())
+	submitted := startSession(t, store, "s2", time.Hour)
+	hub.Submitted(submitted.ExamID, submitted.StudentID, submitted.ID, time.Now())
+
+	hub.Started(startSession(t, store, "s3", time.Hour))
+	if _, ok := hub.submitted[expired.ID]; ok {
+		t.Errorf("expired session %s is still remembered as submitted", expired.ID)
+	}
+	if _, ok := hub.submitted[submitted.ID]; !ok {
+		t.Errorf("session %s is no longer remembered as submitted", submitted.ID)
+	}
+}
+
+func TestExpiredSessionsArePruned(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	hub := newTestHub(store)
+	expired := startSession(t, store, "s1", -time.Minute)
+	hub.Started(expired)
+
+	// Starting another exam's session prunes every exam
+	es := startSession(t, store, "s2", time.Hour)
+	es.ExamID = "EXAM-2"
+	hub.Started(es)
+
+	if _, ok := hub.exams[expired.ExamID]; ok {
+		t.Errorf("exam %s of an expired session is still tracked", expired.ExamID)
+	}
+	if students := hub.Students(es.ExamID); len(students) != 1 {
+		t.Errorf("students of %s = %+v, want one", es.ExamID, students)
+	}
+}
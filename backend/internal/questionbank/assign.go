@@ -0,0 +1,62 @@
+package questionbank
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand"
+
+	"backend/internal/storage"
+)
+
+// Mode selects how questions are assigned to exam sessions
+type Mode string
+
+// Assignment modes
+const (
+	// Seeded picks depend only on the exam, the student and the bank, so
+	// a student starting the exam again gets the same questions
+	Seeded Mode = "seeded"
+	// Random picks differ for every session
+	Random Mode = "random"
+)
+
+// Modes lists the valid assignment modes
+var Modes = []Mode{Seeded, Random}
+
+// Valid reports whether m is a known assignment mode
+func (m Mode) Valid() bool {
+	for _, mode := range Modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// Assign picks up to count distinct active questions from bank, which
+// must be in ID order for seeded picks to be stable
+func Assign(mode Mode, bank []storage.BankQuestion, count int, examID, studentID string) []storage.BankQuestion {
+	var pool []storage.BankQuestion
+	for _, q := range bank {
+		if q.Active {
+			pool = append(pool, q)
+		}
+	}
+	if count > len(pool) {
+		count = len(pool)
+	}
+
+	var perm []int
+	if mode == Seeded {
+		sum := sha256.Sum256([]byte(examID + "\x00" + studentID))
+		perm = rand.New(rand.NewSource(int64(binary.BigEndian.Uint64(sum[:8])))).Perm(len(pool))
+	} else {
+		perm = rand.Perm(len(pool))
+	}
+
+	assigned := make([]storage.BankQuestion, count)
+	for i := range assigned {
+		assigned[i] = pool[perm[i]]
+	}
+	return assigned
+}
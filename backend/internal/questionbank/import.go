@@ -0,0 +1,83 @@
+package questionbank
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"backend/internal/storage"
+)
+
+// entry is one question of a questions.json file
+type entry struct {
+	// ID defaults to the position in the file, which is the questionIndex
+	// the exam page used to submit
+	ID            *int   `json:"id"`
+	QuestionTitle string `json:"question_title"`
+	Question      string `json:"question"`
+}
+
+// Parse reads a questions.json file: an array of objects with
+// question_title, question and an optional id. Other fields are ignored.
+func Parse(data []byte) ([]storage.BankQuestion, error) {
+	var entries []entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse questions: %w", err)
+	}
+
+	questions := make([]storage.BankQuestion, 0, len(entries))
+	seen := make(map[int]bool, len(entries))
+	for i, e := range entries {
+		id := i
+		if e.ID != nil {
+			id = *e.ID
+		}
+		if id < 0 {
+			return nil, fmt.Errorf("question %d: id must not be negative", i)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("question %d: duplicate id %d", i, id)
+		}
+		seen[id] = true
+
+		if strings.TrimSpace(e.Question) == "" {
+			return nil, fmt.Errorf("question %d: question must be a non-empty string", i)
+		}
+		questions = append(questions, storage.BankQuestion{ID: id, Title: e.QuestionTitle, Text: e.Question})
+	}
+	return questions, nil
+}
+
+// Result counts what Import did
+type Result struct {
+	Added     int `json:"added"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Import stores questions in the bank: new IDs are added, changed titles
+// or texts become a new version, identical ones are left alone. Questions
+// missing from the import are kept.
+func Import(store storage.Store, questions []storage.BankQuestion) (Result, error) {
+	var result Result
+	for _, q := range questions {
+		current, err := store.GetQuestion(q.ID, 0)
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			result.Added++
+		case err != nil:
+			return result, err
+		case current.Title == q.Title && current.Text == q.Text:
+			result.Unchanged++
+			continue
+		default:
+			result.Updated++
+		}
+
+		if err := store.SaveQuestion(&q); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
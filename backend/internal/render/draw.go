@@ -0,0 +1,212 @@
+package render
+
+import (
+	"image"
+	"image/color"
+
+	"backend/internal/eventlog"
+	"backend/internal/playback"
+)
+
+// Colors of frames, as indices into palette
+const (
+	colorBackground uint8 = iota
+	colorText
+	colorDim
+	colorPaste // pasted text background
+	colorSelection
+	colorCursor
+	colorBar
+	colorProgress
+	colorPause // pauses of at least PauseMarkMs
+	colorCut   // pauses shortened by MaxPauseMs
+)
+
+// palette holds the only colors frames are drawn with, so GIF frames need
+// no quantization
+var palette = color.Palette{
+	colorBackground: color.RGBA{0x1e, 0x1e, 0x1e, 0xff},
+	colorText:       color.RGBA{0xd4, 0xd4, 0xd4, 0xff},
+	colorDim:        color.RGBA{0x85, 0x85, 0x85, 0xff},
+	colorPaste:      color.RGBA{0xe5, 0xc0, 0x7b, 0xff},
+	colorSelection:  color.RGBA{0x26, 0x4f, 0x78, 0xff},
+	colorCursor:     color.RGBA{0xff, 0xff, 0xff, 0xff},
+	colorBar:        color.RGBA{0x3c, 0x3c, 0x3c, 0xff},
+	colorProgress:   color.RGBA{0x4e, 0x94, 0xce, 0xff},
+	colorPause:      color.RGBA{0xe0, 0x6c, 0x75, 0xff},
+	colorCut:        color.RGBA{0xc6, 0x78, 0xdd, 0xff},
+}
+
+// Layout of a frame, in pixels
+const (
+	margin     = 8
+	lineHeight = 15
+	tabWidth   = 4
+	// statusHeight holds the clock line and the timeline bar
+	statusHeight = 2*lineHeight + 12
+	barHeight    = 6
+)
+
+// cell is one character on screen
+type cell struct {
+	r        rune
+	pasted   bool
+	selected bool
+}
+
+// canvas draws frames of one size
+type canvas struct {
+	opts Options
+	// cols and rows are the size of the text area in characters
+	cols, rows int
+	advance    int
+}
+
+func newCanvas(opts Options) *canvas {
+	return &canvas{
+		opts:    opts,
+		cols:    (opts.Width - 2*margin) / glyphAdvance,
+		rows:    (opts.Height - 2*margin - statusHeight) / lineHeight,
+		advance: glyphAdvance,
+	}
+}
+
+// draw renders the buffer with the status line for the given rendering
+// and log times
+func (c *canvas) draw(buf *eventlog.Buffer, t *timeline, at, logAt float64) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, c.opts.Width, c.opts.Height), palette)
+
+	lines, cursorLine, cursorCol := c.layout(buf)
+	first := 0
+	if len(lines) > c.rows && cursorLine >= c.rows {
+		first = cursorLine - c.rows + 1
+	}
+	for row := 0; row < c.rows && first+row < len(lines); row++ {
+		y := margin + row*lineHeight
+		for col, ch := range lines[first+row] {
+			x := margin + col*c.advance
+			fg := colorText
+			switch {
+			case ch.selected:
+				c.fill(img, x, y, c.advance, lineHeight, colorSelection)
+			case ch.pasted:
+				c.fill(img, x, y, c.advance, lineHeight, colorPaste)
+				fg = colorBackground
+			}
+			c.text(img, x, y, string(ch.r), fg)
+		}
+	}
+	if start, end := buf.Selection(); start == end && cursorLine >= first && cursorLine < first+c.rows {
+		c.fill(img, margin+cursorCol*c.advance, margin+(cursorLine-first)*lineHeight, 2, lineHeight, colorCursor)
+	}
+
+	c.status(img, t, at, logAt)
+	return img
+}
+
+// layout wraps the buffer into lines of cells and finds the cursor
+func (c *canvas) layout(buf *eventlog.Buffer) (lines [][]cell, cursorLine, cursorCol int) {
+	text := []rune(buf.Text())
+	start, end := buf.Selection()
+	cursor := buf.Cursor()
+
+	line := []cell{}
+	for i := 0; i <= len(text); i++ {
+		if i == cursor {
+			if len(line) >= c.cols {
+				lines, line = append(lines, line), []cell{}
+			}
+			cursorLine, cursorCol = len(lines), len(line)
+		}
+		if i == len(text) {
+			break
+		}
+
+		r := text[i]
+		if r == '\n' {
+			lines, line = append(lines, line), []cell{}
+			continue
+		}
+		width := 1
+		if r == '\t' {
+			r, width = ' ', tabWidth
+		}
+		for n := 0; n < width; n++ {
+			if len(line) >= c.cols {
+				lines, line = append(lines, line), []cell{}
+			}
+			line = append(line, cell{r: r, pasted: buf.Pasted(i), selected: i >= start && i < end})
+		}
+	}
+	return append(lines, line), cursorLine, cursorCol
+}
+
+// status draws the clock, the title and the timeline bar with its pause
+// and paste markers
+func (c *canvas) status(img *image.Paletted, t *timeline, at, logAt float64) {
+	top := c.opts.Height - margin - statusHeight
+	c.fill(img, 0, top, c.opts.Width, 1, colorBar)
+
+	clock := playback.FormatTime(logAt) + " / " + playback.FormatTime(t.logEndMs)
+	c.text(img, margin, top+6, clock, colorText)
+	if title := c.opts.Title; title != "" {
+		maxChars := c.cols - len(clock) - 2
+		if runes := []rune(title); len(runes) > maxChars && maxChars > 3 {
+			title = string(runes[:maxChars-3]) + "..."
+		}
+		if len([]rune(title)) <= maxChars {
+			c.text(img, c.opts.Width-margin-len([]rune(title))*c.advance, top+6, title, colorDim)
+		}
+	}
+
+	// Pauses are spans above the bar, pastes ticks across it
+	barTop := top + lineHeight + 14
+	barWidth := c.opts.Width - 2*margin
+	c.fill(img, margin, barTop, barWidth, barHeight, colorBar)
+	position := func(ms float64) int {
+		if t.endMs <= 0 {
+			return 0
+		}
+		return int(ms / t.endMs * float64(barWidth-1))
+	}
+	c.fill(img, margin, barTop, position(at)+1, barHeight, colorProgress)
+	for _, p := range t.pauses {
+		spanColor := colorPause
+		if p.cut {
+			spanColor = colorCut
+		}
+		c.fill(img, margin+position(p.start), barTop-5, position(p.end)-position(p.start)+1, 3, spanColor)
+	}
+	for _, paste := range t.pastes {
+		c.fill(img, margin+position(paste)-1, barTop-2, 2, barHeight+4, colorPaste)
+	}
+}
+
+// fill paints a rectangle, clipped to the image
+func (c *canvas) fill(img *image.Paletted, x, y, w, h int, col uint8) {
+	r := image.Rect(x, y, x+w, y+h).Intersect(img.Rect)
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		row := img.Pix[img.PixOffset(r.Min.X, py):img.PixOffset(r.Max.X, py)]
+		for i := range row {
+			row[i] = col
+		}
+	}
+}
+
+// text draws a string with its top left corner at x, y, one glyph of the
+// bitmap font per rune, clipped to the image
+func (c *canvas) text(img *image.Paletted, x, y int, s string, col uint8) {
+	for _, r := range s {
+		// Glyphs sit one pixel below the top of their line
+		for row, bits := range glyph(r) {
+			py := y + 1 + row
+			for column := 0; column < glyphWidth; column++ {
+				px := x + column
+				if bits&(1<<(glyphWidth-1-column)) != 0 && image.Pt(px, py).In(img.Rect) {
+					img.Pix[img.PixOffset(px, py)] = col
+				}
+			}
+		}
+		x += glyphAdvance
+	}
+}
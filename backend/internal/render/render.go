@@ -0,0 +1,203 @@
+// Package render draws replays of answers as images: animated GIFs of the
+// answer as it was typed and PNG stills of a moment of it. Frames show the
+// text with pasted characters highlighted, the cursor or selection, a
+// clock and a timeline bar marking pauses and pastes. Drawing uses only a
+// fixed palette and a bitmap font, so no fonts or tools need installing.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+
+	"backend/internal/eventlog"
+)
+
+// Image sizes in pixels: the default, the smallest and the largest
+// accepted
+const (
+	DefaultWidth  = 800
+	DefaultHeight = 480
+	MinWidth      = 160
+	MinHeight     = 120
+	MaxWidth      = 1920
+	MaxHeight     = 1080
+)
+
+// PauseMarkMs is the shortest gap between two actions marked on the
+// timeline bar, the gap after which typing stops counting as active
+const PauseMarkMs = eventlog.ActiveGapMs
+
+// Frame pacing of animations
+const (
+	// frameMs is the shortest time between two frames; actions closer
+	// together are drawn in one frame
+	frameMs = 100.0
+	// maxFrames bounds the frames of an animation; longer logs are drawn
+	// with proportionally fewer frames per second
+	maxFrames = 1500
+	// endHoldMs is how long the last frame shows before the animation loops
+	endHoldMs = 3000.0
+)
+
+// Options tunes a rendering
+type Options struct {
+	// Title is shown on the status line, such as the exam, student and
+	// question rendered
+	Title string
+	// Width and Height are the image size in pixels
+	Width  int
+	Height int
+	// MaxPauseMs, when above 0, shortens longer pauses to it
+	MaxPauseMs float64
+	// Speed multiplies the pace of animations; 1 is real time
+	Speed float64
+}
+
+// Frame is one image of an animation, shown for DelayMs
+type Frame struct {
+	Image   *image.Paletted
+	DelayMs float64
+}
+
+// CheckSize checks an image size against the minimum and maximum sizes
+func CheckSize(width, height int) error {
+	if width < MinWidth || width > MaxWidth {
+		return fmt.Errorf("width must be between %d and %d", MinWidth, MaxWidth)
+	}
+	if height < MinHeight || height > MaxHeight {
+		return fmt.Errorf("height must be between %d and %d", MinHeight, MaxHeight)
+	}
+	return nil
+}
+
+// Frames draws events as an animation, calling fn with each frame in
+// order. Identical consecutive frames are merged; the last one is held
+// for a few seconds.
+func Frames(events []eventlog.Event, opts Options, fn func(Frame) error) error {
+	if err := CheckSize(opts.Width, opts.Height); err != nil {
+		return err
+	}
+	if opts.Speed <= 0 {
+		opts.Speed = 1
+	}
+
+	replayer := eventlog.NewReplayer(events)
+	t := newTimeline(replayer.Actions(), opts.MaxPauseMs)
+	canvas := newCanvas(opts)
+
+	step := frameMs * opts.Speed
+	if limit := t.endMs / maxFrames; step < limit {
+		step = limit
+	}
+	positions := append([]int{0}, t.frames(step)...)
+
+	var pending *Frame
+	for i, next := range positions {
+		replayer.Seek(next)
+		at, logAt := t.position(next)
+		img := canvas.draw(replayer.Buffer(), t, at, logAt)
+
+		delay := endHoldMs
+		if i+1 < len(positions) {
+			nextAt, _ := t.position(positions[i+1])
+			delay = (nextAt - at) / opts.Speed
+		}
+		if pending != nil && bytes.Equal(pending.Image.Pix, img.Pix) {
+			pending.DelayMs += delay
+			continue
+		}
+		if pending != nil {
+			if err := fn(*pending); err != nil {
+				return err
+			}
+		}
+		pending = &Frame{Image: img, DelayMs: delay}
+	}
+	return fn(*pending)
+}
+
+// GIF writes events as a looping animated GIF. Every frame after the
+// first only holds the area that changed.
+func GIF(w io.Writer, events []eventlog.Event, opts Options) error {
+	anim := &gif.GIF{
+		Config: image.Config{ColorModel: palette, Width: opts.Width, Height: opts.Height},
+	}
+	var prev *image.Paletted
+	shownMs := 0.0
+	err := Frames(events, opts, func(frame Frame) error {
+		img := frame.Image
+		if prev != nil {
+			img = changed(prev, frame.Image)
+		}
+		// Delays are in hundredths of a second; rounding the running
+		// total keeps long animations from drifting
+		start := math.Round(shownMs / 10)
+		shownMs += frame.DelayMs
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, int(math.Round(shownMs/10)-start))
+		anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+		prev = frame.Image
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// PNG writes the answer as it was atMs milliseconds of log time after the
+// first event
+func PNG(w io.Writer, events []eventlog.Event, atMs float64, opts Options) error {
+	if err := CheckSize(opts.Width, opts.Height); err != nil {
+		return err
+	}
+
+	replayer := eventlog.NewReplayer(events)
+	t := newTimeline(replayer.Actions(), opts.MaxPauseMs)
+	n := t.seek(atMs)
+	replayer.Seek(n)
+	at, logAt := t.position(n)
+	if atMs > t.logEndMs {
+		atMs = t.logEndMs
+	}
+	if atMs > logAt {
+		logAt = atMs
+	}
+	return png.Encode(w, newCanvas(opts).draw(replayer.Buffer(), t, at, logAt))
+}
+
+// changed copies the bounding box of the pixels of next differing from
+// prev, both starting at the origin; an unchanged frame keeps a single
+// pixel, as GIF frames may not be empty
+func changed(prev, next *image.Paletted) *image.Paletted {
+	width, height := next.Rect.Dx(), next.Rect.Dy()
+	minX, minY, maxX, maxY := width, height, -1, -1
+	for y := 0; y < height; y++ {
+		row := y * next.Stride
+		if bytes.Equal(prev.Pix[row:row+width], next.Pix[row:row+width]) {
+			continue
+		}
+		for x := 0; x < width; x++ {
+			if prev.Pix[row+x] != next.Pix[row+x] {
+				minX = min(minX, x)
+				maxX = max(maxX, x)
+			}
+		}
+		minY = min(minY, y)
+		maxY = y
+	}
+	box := image.Rect(minX, minY, maxX+1, maxY+1)
+	if maxY < 0 {
+		box = image.Rect(0, 0, 1, 1)
+	}
+	crop := image.NewPaletted(box, palette)
+	for y := box.Min.Y; y < box.Max.Y; y++ {
+		copy(crop.Pix[crop.PixOffset(box.Min.X, y):], next.Pix[next.PixOffset(box.Min.X, y):next.PixOffset(box.Max.X, y)])
+	}
+	return crop
+}
@@ -0,0 +1,85 @@
+package render
+
+import "backend/internal/eventlog"
+
+// timeline maps the time of the event log to the time of the rendering,
+// in which pauses longer than the idle limit are shortened to it
+type timeline struct {
+	actions []eventlog.Action
+	// at is the rendering time of each action
+	at []float64
+	// endMs is the rendering time of the last action, logEndMs its log time
+	endMs    float64
+	logEndMs float64
+	pauses   []pause
+	// pastes are the rendering times of the pastes
+	pastes []float64
+}
+
+// pause is a gap of at least PauseMarkMs between two actions
+type pause struct {
+	// start and end are the rendering times the pause starts and ends
+	start, end float64
+	// cut reports whether the pause was shortened
+	cut bool
+}
+
+// newTimeline lays actions out in rendering time, shortening pauses
+// longer than maxPauseMs when it is above 0
+func newTimeline(actions []eventlog.Action, maxPauseMs float64) *timeline {
+	t := &timeline{actions: actions, at: make([]float64, len(actions))}
+	clock, prev := 0.0, 0.0
+	for i, action := range actions {
+		gap := action.At - prev
+		cut := maxPauseMs > 0 && gap > maxPauseMs
+		if cut {
+			gap = maxPauseMs
+		}
+		clock += gap
+		t.at[i] = clock
+		prev = action.At
+
+		if i > 0 && action.At-actions[i-1].At >= PauseMarkMs {
+			t.pauses = append(t.pauses, pause{start: clock - gap, end: clock, cut: cut})
+		}
+		if action.Op == eventlog.OpPaste {
+			t.pastes = append(t.pastes, clock)
+		}
+	}
+	if len(actions) > 0 {
+		t.endMs = t.at[len(actions)-1]
+		t.logEndMs = actions[len(actions)-1].At
+	}
+	return t
+}
+
+// frames groups the actions into frames at least frameMs of rendering
+// time apart, returning the index of the first action after each frame
+func (t *timeline) frames(frameMs float64) []int {
+	var frames []int
+	for i := 0; i < len(t.actions); {
+		start := t.at[i]
+		for i < len(t.actions) && t.at[i]-start < frameMs {
+			i++
+		}
+		frames = append(frames, i)
+	}
+	return frames
+}
+
+// seek returns the number of actions at or before a log time
+func (t *timeline) seek(logMs float64) int {
+	n := 0
+	for n < len(t.actions) && t.actions[n].At <= logMs {
+		n++
+	}
+	return n
+}
+
+// position returns the rendering and log time after n actions
+func (t *timeline) position(n int) (at, logAt float64) {
+	if n == 0 {
+		return 0, 0
+	}
+	return t.at[n-1], t.actions[n-1].At
+}
@@ -0,0 +1,278 @@
+// Package similarity looks for plagiarism and collusion within an exam: it
+// compares the final answers students gave to the same question, and the
+// content students pasted, and ranks the pairs of students that look alike.
+// Texts are compared by winnowing, so changing whitespace or letter case does
+// not hide a copy and a shared boilerplate line does not make one.
+package similarity
+
+import (
+	"sort"
+	"strings"
+
+	"backend/internal/eventlog"
+	"backend/internal/storage"
+)
+
+// Options tunes the comparison
+type Options struct {
+	// K is the number of tokens hashed together; shorter matches are not
+	// detected
+	K int
+	// Window is the winnowing window: a match of at least K+Window-1
+	// tokens is always detected
+	Window int
+	// Threshold is the lowest score (0 to 1) of a reported pair; it also
+	// selects the answer and paste matches given as evidence
+	Threshold float64
+	// CommonShare drops passages found in more than this share of the
+	// answers to a question, which the students were likely all given
+	CommonShare float64
+	// MinPaste is the shortest paste, in characters, that is compared
+	MinPaste int
+}
+
+// DefaultOptions returns the options used when none are given
+func DefaultOptions() Options {
+	return Options{K: 5, Window: 4, Threshold: 0.5, CommonShare: 0.5, MinPaste: 20}
+}
+
+// Report ranks the pairs of students of an exam whose work looks alike
+type Report struct {
+	ExamID string `json:"examId"`
+	// Submissions is the number of submissions compared
+	Submissions int     `json:"submissions"`
+	Threshold   float64 `json:"threshold"`
+	// Pairs are ordered by score, highest first
+	Pairs []Pair `json:"pairs"`
+}
+
+// Pair is two students whose work looks alike, with the evidence
+type Pair struct {
+	StudentA string `json:"studentA"`
+	StudentB string `json:"studentB"`
+	// Score is the highest answer similarity or paste containment
+	Score   float64       `json:"score"`
+	Answers []AnswerMatch `json:"answers"`
+	Pastes  []PasteMatch  `json:"pastes"`
+}
+
+// AnswerMatch is a question the two students answered alike
+type AnswerMatch struct {
+	QuestionIndex int    `json:"questionIndex"`
+	KeyA          string `json:"keyA"`
+	KeyB          string `json:"keyB"`
+	// Similarity is the share of fingerprints the answers have in common
+	Similarity float64    `json:"similarity"`
+	Fragments  []Fragment `json:"fragments"`
+}
+
+// Fragment is a passage found in both answers, as each student wrote it
+type Fragment struct {
+	A string `json:"a"`
+	B string `json:"b"`
+}
+
+// PasteMatch is content both students pasted, in any question
+type PasteMatch struct {
+	KeyA     string `json:"keyA"`
+	KeyB     string `json:"keyB"`
+	ContentA string `json:"contentA"`
+	ContentB string `json:"contentB"`
+	// Containment is the share of the shorter paste found in the other
+	Containment float64 `json:"containment"`
+}
+
+// Evidence limits: fragments kept per answer match and characters kept of
+// a pasted content
+const (
+	maxFragments = 5
+	maxPasteText = 500
+)
+
+// entry is one answer of a submission, fingerprinted
+type entry struct {
+	key    string
+	doc    *document
+	hashes map[uint64]int
+}
+
+// paste is one distinct pasted content of a submission, fingerprinted
+type paste struct {
+	key     string
+	content string
+	hashes  map[uint64]int
+}
+
+// Analyze compares the current submissions of an exam, one per student
+func Analyze(examID string, subs []*storage.Submission, opts Options) *Report {
+	report := &Report{ExamID: examID, Submissions: len(subs), Threshold: opts.Threshold, Pairs: []Pair{}}
+
+	answers := fingerprintAnswers(subs, opts)
+	pastes := make([][]paste, len(subs))
+	for i, sub := range subs {
+		pastes[i] = collectPastes(sub, opts)
+	}
+
+	indexes := sortedIndexes(answers)
+	for i := range subs {
+		for j := i + 1; j < len(subs); j++ {
+			pair := Pair{StudentA: subs[i].StudentID, StudentB: subs[j].StudentID, Answers: []AnswerMatch{}, Pastes: []PasteMatch{}}
+			for _, questionIndex := range indexes {
+				a, b := answers[questionIndex][i], answers[questionIndex][j]
+				if a == nil || b == nil {
+					continue
+				}
+				if similarity := jaccard(a.hashes, b.hashes); similarity >= opts.Threshold && similarity > 0 {
+					pair.Answers = append(pair.Answers, AnswerMatch{
+						QuestionIndex: questionIndex,
+						KeyA:          a.key,
+						KeyB:          b.key,
+						Similarity:    similarity,
+						Fragments:     fragments(a.doc, b.doc, a.hashes, b.hashes, opts.K, maxFragments),
+					})
+					pair.Score = maxFloat(pair.Score, similarity)
+				}
+			}
+			for _, a := range pastes[i] {
+				for _, b := range pastes[j] {
+					if share := pasteContainment(a, b); share >= opts.Threshold && share > 0 {
+						pair.Pastes = append(pair.Pastes, PasteMatch{
+							KeyA:        a.key,
+							KeyB:        b.key,
+							ContentA:    truncate(a.content, maxPasteText),
+							ContentB:    truncate(b.content, maxPasteText),
+							Containment: share,
+						})
+						pair.Score = maxFloat(pair.Score, share)
+					}
+				}
+			}
+			if len(pair.Answers) > 0 || len(pair.Pastes) > 0 {
+				report.Pairs = append(report.Pairs, pair)
+			}
+		}
+	}
+
+	sort.SliceStable(report.Pairs, func(i, j int) bool {
+		return report.Pairs[i].Score > report.Pairs[j].Score
+	})
+	return report
+}
+
+// fingerprintAnswers fingerprints the final answers of each question, by
+// question index and then submission index. Passages of the question text,
+// and passages common to most answers, are left out.
+func fingerprintAnswers(subs []*storage.Submission, opts Options) map[int][]*entry {
+	answers := make(map[int][]*entry)
+	questionText := make(map[int][]string)
+	for i, sub := range subs {
+		for _, q := range sub.Questions {
+			if answers[q.QuestionIndex] == nil {
+				answers[q.QuestionIndex] = make([]*entry, len(subs))
+			}
+			answers[q.QuestionIndex][i] = &entry{key: q.Key, doc: newDocument(q.FinalAnswer, opts.K, opts.Window)}
+			questionText[q.QuestionIndex] = append(questionText[q.QuestionIndex], q.Question)
+		}
+	}
+
+	for questionIndex, entries := range answers {
+		ignore := make(map[uint64]bool)
+		for _, text := range questionText[questionIndex] {
+			for hash := range newDocument(text, opts.K, 1).hashes(nil) {
+				ignore[hash] = true
+			}
+		}
+
+		counts := make(map[uint64]int)
+		answered := 0
+		for _, e := range entries {
+			if e != nil {
+				answered++
+				for hash := range e.doc.hashes(nil) {
+					counts[hash]++
+				}
+			}
+		}
+		if answered >= 4 {
+			for hash, count := range counts {
+				if float64(count) > opts.CommonShare*float64(answered) {
+					ignore[hash] = true
+				}
+			}
+		}
+
+		for _, e := range entries {
+			if e != nil {
+				e.hashes = e.doc.hashes(ignore)
+			}
+		}
+	}
+	return answers
+}
+
+// collectPastes gathers the distinct pastes of a submission long enough to
+// compare, leaving out pastes of the question text itself
+func collectPastes(sub *storage.Submission, opts Options) []paste {
+	var pastes []paste
+	seen := make(map[string]bool)
+	for _, q := range sub.Questions {
+		question := normalize(q.Question)
+		for _, event := range q.EventLog {
+			p, ok := event.(*eventlog.RawPaste)
+			if !ok {
+				continue
+			}
+			content := normalize(p.Content)
+			if len([]rune(content)) < opts.MinPaste || seen[content] || strings.Contains(question, content) {
+				continue
+			}
+			seen[content] = true
+			pastes = append(pastes, paste{
+				key:     q.Key,
+				content: content,
+				hashes:  newDocument(content, opts.K, opts.Window).hashes(nil),
+			})
+		}
+	}
+	return pastes
+}
+
+// pasteContainment scores two pastes: 1 when equal, otherwise the share of
+// the shorter one found in the other
+func pasteContainment(a, b paste) float64 {
+	if a.content == b.content {
+		return 1
+	}
+	return containment(a.hashes, b.hashes)
+}
+
+// normalize collapses runs of whitespace into one space
+func normalize(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// truncate cuts text to at most max characters
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max]) + "…"
+}
+
+// sortedIndexes lists the question indexes of answers in order
+func sortedIndexes(answers map[int][]*entry) []int {
+	indexes := make([]int, 0, len(answers))
+	for questionIndex := range answers {
+		indexes = append(indexes, questionIndex)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,104 @@
+package similarity
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"backend/internal/eventlog"
+	"backend/internal/storage"
+)
+
+// answered builds a submission answering question 1 with answer
+func answered(studentID, answer string) *storage.Submission {
+	return &storage.Submission{
+		ExamID:    "EXAM-1",
+		StudentID: studentID,
+		Questions: []storage.Question{{Key: "q1", QuestionIndex: 1, Question: "Write a letter.", FinalAnswer: answer}},
+	}
+}
+
+const letter = `Dear customer, your flight to Lisbon on 12 March departs at 09:40 from
+gate B7. Please arrive two hours early and bring the card used for booking.`
+
+func TestAnalyzeIdenticalAnswers(t *testing.T) {
+	// Reformatting and letter case do not hide the copy
+	copied := strings.ToUpper(strings.Join(strings.Fields(letter), "  "))
+	report := Analyze("EXAM-1", []*storage.Submission{answered("s1", letter), answered("s2", copied)}, DefaultOptions())
+
+	if len(report.Pairs) != 1 {
+		t.Fatalf("pairs = %+v, want one", report.Pairs)
+	}
+	pair := report.Pairs[0]
+	if pair.StudentA != "s1" || pair.StudentB != "s2" || pair.Score != 1 {
+		t.Errorf("pair = %s/%s scoring %v, want s1/s2 scoring 1", pair.StudentA, pair.StudentB, pair.Score)
+	}
+	if len(pair.Answers) != 1 || pair.Answers[0].Similarity != 1 || len(pair.Answers[0].Fragments) != 1 {
+		t.Fatalf("answers = %+v, want question 1 alike in one fragment", pair.Answers)
+	}
+	// Winnowing may leave the last few tokens uncovered
+	fragment := pair.Answers[0].Fragments[0]
+	if !strings.HasPrefix(letter, fragment.A) || !strings.EqualFold(normalize(fragment.A), normalize(fragment.B)) || len(fragment.A) < len(letter)/2 {
+		t.Errorf("fragment = %+v, want the same passage from the start of both answers", fragment)
+	}
+}
+
+func TestAnalyzeDisjointAnswers(t *testing.T) {
+	other := `Hello team, the quarterly report is attached; revenue grew by eight
+percent while costs fell, so we can hire three more engineers next year.`
+	report := Analyze("EXAM-1", []*storage.Submission{answered("s1", letter), answered("s2", other)}, DefaultOptions())
+
+	if report.Submissions != 2 || len(report.Pairs) != 0 {
+		t.Errorf("report = %+v, want 2 submissions and no pairs", report)
+	}
+}
+
+func TestAnalyzeSuppressesBoilerplate(t *testing.T) {
+	// Every student starts with the same greeting and sign-off they were
+	// given, then writes a body of their own
+	const greeting = "Dear Sir or Madam, thank you for your message of last week about the order."
+	const signOff = "Yours faithfully, the customer service team of the company."
+	bodies := []string{
+		"The parcel left our warehouse on Monday and should reach you by Thursday.",
+		"We are sorry the blender arrived broken; a replacement is on its way today.",
+		"Your refund of forty euros was approved and will show within five days.",
+		"The size you asked for is back in stock, so we changed your order to it.",
+	}
+	var subs []*storage.Submission
+	for i, body := range bodies {
+		subs = append(subs, answered(fmt.Sprintf("s%d", i+1), greeting+"\n"+body+"\n"+signOff))
+	}
+
+	opts := DefaultOptions()
+	opts.Threshold = 0.2
+	if report := Analyze("EXAM-1", subs, opts); len(report.Pairs) != 0 {
+		t.Errorf("pairs = %+v, want none once the shared passages are dropped", report.Pairs)
+	}
+
+	// Keeping passages common to every answer makes them all look alike
+	opts.CommonShare = 1
+	if report := Analyze("EXAM-1", subs, opts); len(report.Pairs) != 6 {
+		t.Errorf("pairs with CommonShare 1 = %d, want all 6", len(report.Pairs))
+	}
+}
+
+func TestAnalyzePastes(t *testing.T) {
+	pasted := func(studentID, content string) *storage.Submission {
+		sub := answered(studentID, "")
+		sub.Questions[0].EventLog = eventlog.Log{&eventlog.RawPaste{Content: content}}
+		return sub
+	}
+	subs := []*storage.Submission{
+		pasted("s1", letter),
+		pasted("s2", "  "+letter+"\n"),
+		pasted("s3", "Write a letter."),
+	}
+
+	report := Analyze("EXAM-1", subs, DefaultOptions())
+	if len(report.Pairs) != 1 {
+		t.Fatalf("pairs = %+v, want s1 and s2", report.Pairs)
+	}
+	if pair := report.Pairs[0]; pair.StudentB != "s2" || len(pair.Pastes) != 1 || pair.Pastes[0].Containment != 1 {
+		t.Errorf("pair = %+v, want s1 and s2 sharing one paste", pair)
+	}
+}
@@ -0,0 +1,198 @@
+package similarity
+
+import (
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// token is a word, number or punctuation character of a text, lowercased,
+// with its byte range in the text
+type token struct {
+	text       string
+	start, end int
+}
+
+// tokenize splits text into tokens, skipping whitespace, so that
+// reformatting or changing letter case does not hide a copy
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			tokens = append(tokens, token{text: strings.ToLower(text[start:end]), start: start, end: end})
+			start = -1
+		}
+	}
+
+	for i, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			if start < 0 {
+				start = i
+			}
+		case unicode.IsSpace(r):
+			flush(i)
+		default:
+			flush(i)
+			end := i + len(string(r))
+			tokens = append(tokens, token{text: text[i:end], start: i, end: end})
+		}
+	}
+	flush(len(text))
+	return tokens
+}
+
+// fingerprint is a selected k-gram hash and the index of its first token
+type fingerprint struct {
+	hash uint64
+	pos  int
+}
+
+// document is a tokenized text and its winnowed fingerprints
+type document struct {
+	text         string
+	tokens       []token
+	fingerprints []fingerprint
+}
+
+// newDocument fingerprints text by winnowing: hashing every k tokens and
+// keeping the smallest hash of each window of w consecutive hashes. Texts
+// shorter than k tokens get no fingerprints.
+func newDocument(text string, k, w int) *document {
+	doc := &document{text: text, tokens: tokenize(text)}
+	if len(doc.tokens) < k {
+		return doc
+	}
+
+	hashes := make([]uint64, len(doc.tokens)-k+1)
+	for i := range hashes {
+		h := fnv.New64a()
+		for _, t := range doc.tokens[i : i+k] {
+			h.Write([]byte(t.text))
+			h.Write([]byte{0})
+		}
+		hashes[i] = h.Sum64()
+	}
+
+	if w > len(hashes) {
+		w = len(hashes)
+	}
+	last := -1
+	for start := 0; start+w <= len(hashes); start++ {
+		end := start + w
+		// The rightmost minimum, so a window sliding over a repeated
+		// minimum keeps selecting the same k-gram
+		min := start
+		for i := start; i < end; i++ {
+			if hashes[i] <= hashes[min] {
+				min = i
+			}
+		}
+		if min != last {
+			doc.fingerprints = append(doc.fingerprints, fingerprint{hash: hashes[min], pos: min})
+			last = min
+		}
+	}
+	return doc
+}
+
+// hashes returns the set of the document's fingerprint hashes, leaving
+// out those in ignore
+func (d *document) hashes(ignore map[uint64]bool) map[uint64]int {
+	set := make(map[uint64]int, len(d.fingerprints))
+	for _, fp := range d.fingerprints {
+		if _, ok := set[fp.hash]; !ok && !ignore[fp.hash] {
+			set[fp.hash] = fp.pos
+		}
+	}
+	return set
+}
+
+// jaccard is the size of the intersection of two sets over their union
+func jaccard(a, b map[uint64]int) float64 {
+	shared := intersection(a, b)
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// containment is the share of the smaller set found in the other one
+func containment(a, b map[uint64]int) float64 {
+	smaller := len(a)
+	if len(b) < smaller {
+		smaller = len(b)
+	}
+	if smaller == 0 {
+		return 0
+	}
+	return float64(intersection(a, b)) / float64(smaller)
+}
+
+// intersection counts the hashes two sets share
+func intersection(a, b map[uint64]int) int {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	shared := 0
+	for hash := range a {
+		if _, ok := b[hash]; ok {
+			shared++
+		}
+	}
+	return shared
+}
+
+// fragments returns the passages of a and b covered by their shared
+// fingerprints, longest first: each shared k-gram marks k tokens, and
+// k-grams that follow each other in both texts are merged into one passage
+func fragments(a, b *document, setA, setB map[uint64]int, k, max int) []Fragment {
+	type span struct{ aStart, aEnd, bStart, bEnd int }
+	var spans []span
+	for hash, posA := range setA {
+		if posB, ok := setB[hash]; ok {
+			spans = append(spans, span{posA, posA + k, posB, posB + k})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].aStart != spans[j].aStart {
+			return spans[i].aStart < spans[j].aStart
+		}
+		return spans[i].bStart < spans[j].bStart
+	})
+
+	var merged []span
+	for _, s := range spans {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if s.aStart <= last.aEnd && s.bStart >= last.bStart && s.bStart <= last.bEnd {
+				if s.aEnd > last.aEnd {
+					last.aEnd = s.aEnd
+				}
+				if s.bEnd > last.bEnd {
+					last.bEnd = s.bEnd
+				}
+				continue
+			}
+		}
+		merged = append(merged, s)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].aEnd-merged[i].aStart > merged[j].aEnd-merged[j].aStart
+	})
+
+	var result []Fragment
+	for _, s := range merged {
+		if len(result) == max {
+			break
+		}
+		result = append(result, Fragment{
+			A: a.text[a.tokens[s.aStart].start:a.tokens[s.aEnd-1].end],
+			B: b.text[b.tokens[s.bStart].start:b.tokens[s.bEnd-1].end],
+		})
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"backend/internal/eventlog"
+)
+
+// Chunk is one batch of a question's event log, autosaved during an exam
+// session. Seq numbers a question's chunks from 1 in the order their
+// events were typed.
+type Chunk struct {
+	SessionID     string
+	QuestionIndex int
+	Seq           int
+	Events        eventlog.Log
+	ReceivedAt    time.Time
+}
+
+// SaveChunk stores an autosaved chunk, or returns ErrExists when the
+// question already has a chunk with its Seq
+func (s *sqlStore) SaveChunk(chunk *Chunk) error {
+	events, err := json.Marshal(chunk.Events)
+	if err != nil {
+		return fmt.Errorf("failed to encode events: %w", err)
+	}
+	if chunk.ReceivedAt.IsZero() {
+		chunk.ReceivedAt = time.Now().UTC()
+	}
+
+	result, err := s.db.Exec(s.dialect.rebind(`
+	INSERT INTO autosave_chunks (session_id, question_index, seq, events_json, received_at)
+	VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT(session_id, question_index, seq) DO NOTHING
+	`), chunk.SessionID, chunk.QuestionIndex, chunk.Seq, string(events), chunk.ReceivedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save chunk: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrExists
+	}
+	return nil
+}
+
+// ListChunks retrieves the chunks of an exam session by question and Seq
+func (s *sqlStore) ListChunks(sessionID string) ([]Chunk, error) {
+	return s.queryChunks(`WHERE session_id = ? ORDER BY question_index, seq`, sessionID)
+}
+
+// ListQuestionChunks retrieves the chunks of a question of an exam session
+// by Seq
+func (s *sqlStore) ListQuestionChunks(sessionID string, questionIndex int) ([]Chunk, error) {
+	return s.queryChunks(`WHERE session_id = ? AND question_index = ? ORDER BY seq`, sessionID, questionIndex)
+}
+
+// LastChunk retrieves the chunk with the highest Seq of a question of an
+// exam session
+func (s *sqlStore) LastChunk(sessionID string, questionIndex int) (*Chunk, error) {
+	chunks, err := s.queryChunks(`WHERE session_id = ? AND question_index = ? ORDER BY seq DESC LIMIT 1`, sessionID, questionIndex)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, ErrNotFound
+	}
+	return &chunks[0], nil
+}
+
+// queryChunks retrieves the chunks selected by where
+func (s *sqlStore) queryChunks(where string, args ...interface{}) ([]Chunk, error) {
+	rows, err := s.db.Query(s.dialect.rebind(`
+	SELECT session_id, question_index, seq, events_json, received_at
+	FROM autosave_chunks `+where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var chunks []Chunk
+	for rows.Next() {
+		var chunk Chunk
+		var events string
+		if err := rows.Scan(&chunk.SessionID, &chunk.QuestionIndex, &chunk.Seq, &events, &chunk.ReceivedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(events), &chunk.Events); err != nil {
+			return nil, fmt.Errorf("failed to decode chunk %d of question %d: %w", chunk.Seq, chunk.QuestionIndex, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
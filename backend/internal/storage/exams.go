@@ -0,0 +1,278 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInUse is returned when deleting a record others still refer to
+var ErrInUse = errors.New("in use")
+
+// Enforcement selects what happens to submissions outside an exam's limits
+type Enforcement string
+
+// Enforcement modes
+const (
+	// EnforceFlag accepts the submission and records its timing flags
+	EnforceFlag Enforcement = "flag"
+	// EnforceReject refuses the submission
+	EnforceReject Enforcement = "reject"
+)
+
+// Valid reports whether e is a known enforcement mode
+func (e Enforcement) Valid() bool {
+	return e == EnforceFlag || e == EnforceReject
+}
+
+// TimingFlag marks a submission made outside its exam's limits
+type TimingFlag string
+
+// Timing flags
+const (
+	// TimingEarly: received before the exam opened
+	TimingEarly TimingFlag = "early"
+	// TimingLate: received after the exam closed
+	TimingLate TimingFlag = "late"
+	// TimingOvertime: received after the duration limit, counted from the
+	// server-side start of the exam session
+	TimingOvertime TimingFlag = "overtime"
+	// TimingAttempts: more attempts than the exam allows
+	TimingAttempts TimingFlag = "attempts"
+)
+
+// TimingFlags lists the valid timing flags
+var TimingFlags = []TimingFlag{TimingEarly, TimingLate, TimingOvertime, TimingAttempts}
+
+// TimingAny selects submissions with any timing flag in SubmissionFilter
+const TimingAny TimingFlag = "any"
+
+// Valid reports whether f is a known timing flag
+func (f TimingFlag) Valid() bool {
+	for _, flag := range TimingFlags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// Exam defines an exam. Exams are also registered, with no limits, the
+// first time a submission names them.
+type Exam struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// QuestionIDs restricts exam sessions to these bank questions; empty
+	// means the whole bank
+	QuestionIDs []int `json:"questionIds"`
+	// OpensAt and ClosesAt bound when submissions are accepted; nil means
+	// no bound
+	OpensAt  *time.Time `json:"opensAt,omitempty"`
+	ClosesAt *time.Time `json:"closesAt,omitempty"`
+	// DurationSeconds limits the time from starting an exam session to
+	// submitting; 0 means no limit
+	DurationSeconds int `json:"durationSeconds"`
+	// MaxAttempts limits the submissions per student; 0 means no limit
+	MaxAttempts int         `json:"maxAttempts"`
+	Enforcement Enforcement `json:"enforcement"`
+	CreatedAt   time.Time   `json:"createdAt"`
+}
+
+// Duration returns the duration limit, 0 when there is none
+func (e *Exam) Duration() time.Duration {
+	return time.Duration(e.DurationSeconds) * time.Second
+}
+
+// Open reports whether now is inside the exam's window
+func (e *Exam) Open(now time.Time) bool {
+	return (e.OpensAt == nil || !now.Before(*e.OpensAt)) && (e.ClosesAt == nil || now.Before(*e.ClosesAt))
+}
+
+// Deadline returns when a session started at startedAt must submit by:
+// the earlier of the end of its duration and the exam's close, or nil
+// without either limit
+func (e *Exam) Deadline(startedAt time.Time) *time.Time {
+	deadline := e.ClosesAt
+	if e.DurationSeconds > 0 {
+		end := startedAt.Add(e.Duration())
+		if deadline == nil || end.Before(*deadline) {
+			deadline = &end
+		}
+	}
+	return deadline
+}
+
+// Check returns the limits a submission received at receivedAt breaks.
+// startedAt is the server-side start of its exam session, zero without a
+// session; attempt numbers the submission among the student's attempts.
+func (e *Exam) Check(receivedAt, startedAt time.Time, attempt int) []TimingFlag {
+	var flags []TimingFlag
+	if e.OpensAt != nil && receivedAt.Before(*e.OpensAt) {
+		flags = append(flags, TimingEarly)
+	}
+	if e.ClosesAt != nil && !receivedAt.Before(*e.ClosesAt) {
+		flags = append(flags, TimingLate)
+	}
+	if e.DurationSeconds > 0 && !startedAt.IsZero() && receivedAt.Sub(startedAt) > e.Duration() {
+		flags = append(flags, TimingOvertime)
+	}
+	if e.MaxAttempts > 0 && attempt > e.MaxAttempts {
+		flags = append(flags, TimingAttempts)
+	}
+	return flags
+}
+
+// joinFlags encodes timing flags for the timing_flags column
+func joinFlags(flags []TimingFlag) string {
+	parts := make([]string, len(flags))
+	for i, flag := range flags {
+		parts[i] = string(flag)
+	}
+	return strings.Join(parts, ",")
+}
+
+// splitFlags decodes the timing_flags column
+func splitFlags(column string) []TimingFlag {
+	if column == "" {
+		return nil
+	}
+	var flags []TimingFlag
+	for _, part := range strings.Split(column, ",") {
+		flags = append(flags, TimingFlag(part))
+	}
+	return flags
+}
+
+// SaveExam creates or replaces an exam definition, keeping its CreatedAt
+// when it exists
+func (s *sqlStore) SaveExam(exam *Exam) error {
+	exam.QuestionIDs = exam.questionIDs()
+	questionIDs, err := json.Marshal(exam.QuestionIDs)
+	if err != nil {
+		return fmt.Errorf("failed to encode questions: %w", err)
+	}
+
+	err = s.db.QueryRow(s.dialect.rebind(`
+	INSERT INTO exams (id, title, question_ids, opens_at, closes_at, duration_seconds, max_attempts, enforcement, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(id) DO UPDATE SET
+		title = excluded.title,
+		question_ids = excluded.question_ids,
+		opens_at = excluded.opens_at,
+		closes_at = excluded.closes_at,
+		duration_seconds = excluded.duration_seconds,
+		max_attempts = excluded.max_attempts,
+		enforcement = excluded.enforcement
+	RETURNING created_at
+	`), exam.ID, exam.Title, string(questionIDs), nullTimePtr(exam.OpensAt), nullTimePtr(exam.ClosesAt),
+		exam.DurationSeconds, exam.MaxAttempts, string(exam.Enforcement), time.Now().UTC()).Scan(&exam.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save exam: %w", err)
+	}
+	return nil
+}
+
+// GetExam retrieves an exam by id
+func (s *sqlStore) GetExam(id string) (*Exam, error) {
+	exams, err := s.queryExams(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(exams) == 0 {
+		return nil, ErrNotFound
+	}
+	return &exams[0], nil
+}
+
+// ListExams retrieves every exam ordered by id
+func (s *sqlStore) ListExams() ([]Exam, error) {
+	return s.queryExams(`ORDER BY id`)
+}
+
+// DeleteExam removes an exam no submission refers to, or returns ErrInUse
+func (s *sqlStore) DeleteExam(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var used bool
+	err = tx.QueryRow(s.dialect.rebind(`SELECT EXISTS (SELECT 1 FROM submissions WHERE exam_id = ?)`), id).Scan(&used)
+	if err != nil {
+		return fmt.Errorf("failed to check exam: %w", err)
+	}
+	if used {
+		return ErrInUse
+	}
+
+	result, err := tx.Exec(s.dialect.rebind(`DELETE FROM exams WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete exam: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit exam: %w", err)
+	}
+	return nil
+}
+
+// queryExams selects exams with a WHERE and/or ORDER BY clause
+func (s *sqlStore) queryExams(clause string, args ...interface{}) ([]Exam, error) {
+	rows, err := s.db.Query(s.dialect.rebind(`
+	SELECT id, title, question_ids, opens_at, closes_at, duration_seconds, max_attempts, enforcement, created_at
+	FROM exams `+clause), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exams: %w", err)
+	}
+	defer rows.Close()
+
+	var exams []Exam
+	for rows.Next() {
+		var exam Exam
+		var questionIDs, enforcement string
+		var opensAt, closesAt sql.NullTime
+		if err := rows.Scan(&exam.ID, &exam.Title, &questionIDs, &opensAt, &closesAt,
+			&exam.DurationSeconds, &exam.MaxAttempts, &enforcement, &exam.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(questionIDs), &exam.QuestionIDs); err != nil {
+			return nil, fmt.Errorf("failed to decode questions: %w", err)
+		}
+		exam.OpensAt, exam.ClosesAt = timePtr(opensAt), timePtr(closesAt)
+		exam.Enforcement = Enforcement(enforcement)
+		exams = append(exams, exam)
+	}
+	return exams, rows.Err()
+}
+
+// questionIDs returns QuestionIDs, never nil
+func (e *Exam) questionIDs() []int {
+	if e.QuestionIDs == nil {
+		return []int{}
+	}
+	return e.QuestionIDs
+}
+
+// nullTimePtr stores a nil time as NULL
+func nullTimePtr(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// timePtr reads a nullable time column
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time.UTC()
+	return &v
+}
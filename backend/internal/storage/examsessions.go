@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ExamSession is a student's registered attempt at an exam, started by
+// the server. Submissions carry its signed token.
+type ExamSession struct {
+	ID        string
+	ExamID    string
+	StudentID string
+	// Questions holds the question indexes assigned to the student and
+	// Versions the question bank version served for each of them
+	Questions []int
+	Versions  []int
+	StartedAt time.Time
+	ExpiresAt time.Time
+	ClientIP  string
+}
+
+// CreateExamSession registers an exam session
+func (s *sqlStore) CreateExamSession(session *ExamSession) error {
+	questions, err := json.Marshal(session.Questions)
+	if err != nil {
+		return fmt.Errorf("failed to encode questions: %w", err)
+	}
+	versions, err := json.Marshal(session.Versions)
+	if err != nil {
+		return fmt.Errorf("failed to encode question versions: %w", err)
+	}
+
+	_, err = s.db.Exec(s.dialect.rebind(`
+	INSERT INTO exam_sessions (id, exam_id, student_id, question_indexes, question_versions, started_at, expires_at, client_ip)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`), session.ID, session.ExamID, session.StudentID, string(questions), string(versions),
+		session.StartedAt.UTC(), session.ExpiresAt.UTC(), session.ClientIP)
+	if err != nil {
+		return fmt.Errorf("failed to save exam session: %w", err)
+	}
+	return nil
+}
+
+// GetExamSession retrieves a registered exam session by id
+func (s *sqlStore) GetExamSession(id string) (*ExamSession, error) {
+	return s.queryExamSession(`WHERE id = ?`, id)
+}
+
+// FirstExamSession retrieves the earliest started exam session of a
+// student for an exam
+func (s *sqlStore) FirstExamSession(examID, studentID string) (*ExamSession, error) {
+	return s.queryExamSession(`WHERE exam_id = ? AND student_id = ? ORDER BY started_at, id LIMIT 1`, examID, studentID)
+}
+
+// ExamSessionSubmitted reports whether a revision was submitted in an exam
+// session
+func (s *sqlStore) ExamSessionSubmitted(id string) (bool, error) {
+	var submitted bool
+	err := s.db.QueryRow(s.dialect.rebind(`SELECT EXISTS (SELECT 1 FROM submission_revisions WHERE session_id = ?)`), id).Scan(&submitted)
+	if err != nil {
+		return false, fmt.Errorf("failed to check exam session: %w", err)
+	}
+	return submitted, nil
+}
+
+// queryExamSession retrieves the first exam session selected by where
+func (s *sqlStore) queryExamSession(where string, args ...interface{}) (*ExamSession, error) {
+	var session ExamSession
+	var questions, versions string
+	err := s.db.QueryRow(s.dialect.rebind(`
+	SELECT id, exam_id, student_id, question_indexes, question_versions, started_at, expires_at, client_ip
+	FROM exam_sessions `+where), args...).Scan(&session.ID, &session.ExamID, &session.StudentID, &questions, &versions,
+		&session.StartedAt, &session.ExpiresAt, &session.ClientIP)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve exam session: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(questions), &session.Questions); err != nil {
+		return nil, fmt.Errorf("failed to decode questions: %w", err)
+	}
+	if err := json.Unmarshal([]byte(versions), &session.Versions); err != nil {
+		return nil, fmt.Errorf("failed to decode question versions: %w", err)
+	}
+	return &session, nil
+}
@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Mark is an evaluator's verdict on one answer
+type Mark string
+
+// Marks an evaluator can give
+const (
+	MarkCopied  Mark = "COPIED"
+	MarkWrong   Mark = "WRONG"
+	MarkCorrect Mark = "CORRECT"
+	MarkOK      Mark = "OK"
+)
+
+// Marks lists the valid marks
+var Marks = []Mark{MarkCopied, MarkWrong, MarkCorrect, MarkOK}
+
+// Valid reports whether m is one of Marks
+func (m Mark) Valid() bool {
+	for _, known := range Marks {
+		if m == known {
+			return true
+		}
+	}
+	return false
+}
+
+// QuestionMark is one mark given to a question of a submission. Marks are
+// never changed: marking a question again adds a newer one, and the
+// latest is the question's current mark.
+type QuestionMark struct {
+	QuestionKey string `json:"questionKey"`
+	Mark        Mark   `json:"mark"`
+	Comment     string `json:"comment"`
+	Evaluator   string `json:"evaluator"`
+	// Revision is the submission revision that was current when marked
+	Revision int       `json:"revision"`
+	MarkedAt time.Time `json:"markedAt"`
+}
+
+// currentMarks is the subquery condition selecting, for a question_marks
+// row aliased m, only the latest mark of its question
+const currentMarks = `m.id = (
+			SELECT MAX(id) FROM question_marks
+			WHERE submission_id = m.submission_id AND question_key = m.question_key
+		)`
+
+// SaveMark records mark as the current mark of a question of the current
+// revision of a student's submission, setting mark.Revision and, when
+// zero, mark.MarkedAt. It returns ErrNotFound if the submission or the
+// question does not exist.
+func (s *sqlStore) SaveMark(examID, studentID string, mark *QuestionMark) error {
+	if mark.MarkedAt.IsZero() {
+		mark.MarkedAt = time.Now().UTC()
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var submissionID int64
+	err = tx.QueryRow(s.dialect.rebind(`
+	SELECT s.id, r.revision
+	`+submissionsFrom+`
+	JOIN submission_questions q ON q.submission_id = s.id
+	WHERE s.exam_id = ? AND s.student_id = ? AND q.question_key = ?
+	`), examID, studentID, mark.QuestionKey).Scan(&submissionID, &mark.Revision)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find question: %w", err)
+	}
+
+	_, err = tx.Exec(s.dialect.rebind(`
+	INSERT INTO question_marks (submission_id, question_key, revision, mark, comment, evaluator, marked_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	`), submissionID, mark.QuestionKey, mark.Revision, string(mark.Mark), mark.Comment, mark.Evaluator, mark.MarkedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save mark: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit mark: %w", err)
+	}
+	return nil
+}
+
+// ListMarks retrieves the current mark of every marked question of a
+// student's submission, in question key order
+func (s *sqlStore) ListMarks(examID, studentID string) ([]QuestionMark, error) {
+	var submissionID int64
+	err := s.db.QueryRow(s.dialect.rebind(`SELECT id FROM submissions WHERE exam_id = ? AND student_id = ?`), examID, studentID).Scan(&submissionID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find submission: %w", err)
+	}
+
+	marks, err := s.loadMarks([]int64{submissionID})
+	if err != nil {
+		return nil, err
+	}
+	return marks[submissionID], nil
+}
+
+// ListExamMarks retrieves the current marks of the submissions of the
+// given students to an exam, keyed by student ID, in question key order
+func (s *sqlStore) ListExamMarks(examID string, studentIDs []string) (map[string][]QuestionMark, error) {
+	byStudent := make(map[string][]QuestionMark, len(studentIDs))
+	if len(studentIDs) == 0 {
+		return byStudent, nil
+	}
+
+	placeholders, args := inList(studentIDs)
+	rows, err := s.db.Query(s.dialect.rebind(`
+	SELECT id, student_id FROM submissions
+	WHERE exam_id = ? AND student_id IN (`+placeholders+`)
+	`), append([]interface{}{examID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query submissions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	students := make(map[int64]string, len(studentIDs))
+	for rows.Next() {
+		var id int64
+		var studentID string
+		if err := rows.Scan(&id, &studentID); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		ids = append(ids, id)
+		students[id] = studentID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	marks, err := s.loadMarks(ids)
+	if err != nil {
+		return nil, err
+	}
+	for id, list := range marks {
+		byStudent[students[id]] = list
+	}
+	return byStudent, nil
+}
+
+// MarkHistory retrieves every mark given to a question of a student's
+// submission, oldest first
+func (s *sqlStore) MarkHistory(examID, studentID, questionKey string) ([]QuestionMark, error) {
+	query := `
+	SELECT m.question_key, m.mark, m.comment, m.evaluator, m.revision, m.marked_at
+	FROM question_marks m
+	JOIN submissions s ON s.id = m.submission_id
+	WHERE s.exam_id = ? AND s.student_id = ? AND m.question_key = ?
+	ORDER BY m.id
+	`
+
+	rows, err := s.db.Query(s.dialect.rebind(query), examID, studentID, questionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query marks: %w", err)
+	}
+	defer rows.Close()
+
+	var marks []QuestionMark
+	for rows.Next() {
+		var mark QuestionMark
+		var value string
+		if err := rows.Scan(&mark.QuestionKey, &value, &mark.Comment, &mark.Evaluator, &mark.Revision, &mark.MarkedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		mark.Mark = Mark(value)
+		marks = append(marks, mark)
+	}
+	return marks, rows.Err()
+}
+
+// loadMarks loads the current marks of the given submissions, keyed by
+// submission id, in question key order
+func (s *sqlStore) loadMarks(ids []int64) (map[int64][]QuestionMark, error) {
+	marks := make(map[int64][]QuestionMark, len(ids))
+	if len(ids) == 0 {
+		return marks, nil
+	}
+
+	placeholders, args := inList(ids)
+	query := `
+	SELECT m.submission_id, m.question_key, m.mark, m.comment, m.evaluator, m.revision, m.marked_at
+	FROM question_marks m
+	WHERE m.submission_id IN (` + placeholders + `)
+		AND ` + currentMarks + `
+	ORDER BY m.submission_id, m.question_key
+	`
+
+	rows, err := s.db.Query(s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query marks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var mark QuestionMark
+		var value string
+		if err := rows.Scan(&id, &mark.QuestionKey, &value, &mark.Comment, &mark.Evaluator, &mark.Revision, &mark.MarkedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		mark.Mark = Mark(value)
+		marks[id] = append(marks[id], mark)
+	}
+	return marks, rows.Err()
+}
@@ -0,0 +1,945 @@
+package storage
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/eventlog"
+)
+
+// MemoryStorage keeps submissions in process memory. It has no schema and
+// loses everything on Close, so it is meant for tests and local experiments.
+type MemoryStorage struct {
+	mu sync.RWMutex
+	// revisions holds every stored attempt per exam/student key, oldest first
+	revisions map[memoryKey][]*Submission
+	// marks holds every mark per exam/student key and question key, oldest first
+	marks map[memoryKey]map[string][]QuestionMark
+	// exams holds the exams by id
+	exams map[string]Exam
+	// examSessions holds the registered exam sessions by id
+	examSessions map[string]ExamSession
+	// chunks holds the autosaved chunks per exam session, by question
+	// index and Seq
+	chunks map[string][]Chunk
+	// questions holds every version of each bank question by ID, oldest
+	// first, and questionActive whether the question is active
+	questions      map[int][]BankQuestion
+	questionActive map[int]bool
+	// users holds the accounts by username and sessions the sessions by
+	// token hash
+	users    map[string]User
+	sessions map[string]Session
+}
+
+// NewMemoryStorage creates an empty in-memory store
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		revisions:      make(map[memoryKey][]*Submission),
+		marks:          make(map[memoryKey]map[string][]QuestionMark),
+		exams:          make(map[string]Exam),
+		examSessions:   make(map[string]ExamSession),
+		chunks:         make(map[string][]Chunk),
+		questions:      make(map[int][]BankQuestion),
+		questionActive: make(map[int]bool),
+		users:          make(map[string]User),
+		sessions:       make(map[string]Session),
+	}
+}
+
+// memoryKey identifies a student's submission for an exam. Ids may
+// contain any character, so they are kept apart rather than joined.
+type memoryKey struct {
+	examID, studentID string
+}
+
+// less orders keys by exam, then student
+func (k memoryKey) less(other memoryKey) bool {
+	if k.examID != other.examID {
+		return k.examID < other.examID
+	}
+	return k.studentID < other.studentID
+}
+
+// SaveSubmission stores a copy of sub as its student's next revision
+func (m *MemoryStorage) SaveSubmission(sub *Submission) error {
+	if sub.ReceivedAt.IsZero() {
+		sub.ReceivedAt = time.Now().UTC()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.exams[sub.ExamID]; !ok {
+		m.exams[sub.ExamID] = Exam{ID: sub.ExamID, QuestionIDs: []int{}, Enforcement: EnforceFlag, CreatedAt: sub.ReceivedAt}
+	}
+
+	key := memoryKey{sub.ExamID, sub.StudentID}
+	sub.Revision = len(m.revisions[key]) + 1
+	m.revisions[key] = append(m.revisions[key], copySubmission(sub))
+	return nil
+}
+
+// GetSubmission retrieves the current revision of a student's submission
+func (m *MemoryStorage) GetSubmission(examID, studentID string) (*Submission, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	revisions := m.revisions[memoryKey{examID, studentID}]
+	if len(revisions) == 0 {
+		return nil, ErrNotFound
+	}
+	return copySubmission(revisions[len(revisions)-1]), nil
+}
+
+// ListSubmissions retrieves one page of the current revisions matching
+// filter and the total number of matches
+func (m *MemoryStorage) ListSubmissions(filter SubmissionFilter, page Page) ([]*Submission, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matches := m.match(filter, page)
+	submissions := make([]*Submission, 0, len(matches))
+	for _, sub := range page.window(matches) {
+		submissions = append(submissions, copySubmission(sub))
+	}
+	return submissions, len(matches), nil
+}
+
+// ListMetadataKeys retrieves the metadata fields of the current
+// submissions of an exam in alphabetical order
+func (m *MemoryStorage) ListMetadataKeys(examID string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seen := map[string]bool{}
+	keys := []string{}
+	for key, revisions := range m.revisions {
+		if key.examID != examID || len(revisions) == 0 {
+			continue
+		}
+		for field := range revisions[len(revisions)-1].Metadata {
+			if !seen[field] {
+				seen[field] = true
+				keys = append(keys, field)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// ListSummaries retrieves one page of the current revisions matching
+// filter, as summaries, and the total number of matches
+func (m *MemoryStorage) ListSummaries(filter SubmissionFilter, page Page) ([]Summary, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matches := m.match(filter, page)
+	summaries := make([]Summary, 0, len(matches))
+	for _, sub := range page.window(matches) {
+		summary := Summary{
+			ExamID:         sub.ExamID,
+			StudentID:      sub.StudentID,
+			StudentName:    sub.StudentName(),
+			SubmissionTime: sub.SubmissionTime,
+			Revision:       sub.Revision,
+			ReceivedAt:     sub.ReceivedAt,
+			TimingFlags:    sub.TimingFlags,
+		}
+		for _, question := range sub.Questions {
+			if summary.Stats == nil {
+				summary.Stats = make(map[string]eventlog.Stats)
+				summary.Suspicion = make(map[string]Suspicion)
+			}
+			summary.Stats[question.Key] = question.Stats
+			summary.Suspicion[question.Key] = question.Suspicion
+
+			if question.Integrity.Verdict == "" {
+				continue
+			}
+			if summary.Integrity == nil {
+				summary.Integrity = make(map[string]eventlog.Integrity)
+			}
+			summary.Integrity[question.Key] = question.Integrity
+		}
+		for key, marks := range m.marks[memoryKey{sub.ExamID, sub.StudentID}] {
+			if summary.Marks == nil {
+				summary.Marks = make(map[string]QuestionMark)
+			}
+			summary.Marks[key] = marks[len(marks)-1]
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, len(matches), nil
+}
+
+// match returns the current revisions matching filter in page order.
+// The caller must hold m.mu.
+func (m *MemoryStorage) match(filter SubmissionFilter, page Page) []*Submission {
+	var matches []*Submission
+	for key, revisions := range m.revisions {
+		current := revisions[len(revisions)-1]
+		if filter.matches(current) && (filter.Mark == "" || m.hasMark(key, filter.Mark)) {
+			matches = append(matches, current)
+		}
+	}
+
+	less := sortLess[page.Sort]
+	if less == nil {
+		less = sortLess[SortSubmissionTime]
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if page.Descending {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		// Break ties by exam and student so that pages never overlap
+		return memoryKey{a.ExamID, a.StudentID}.less(memoryKey{b.ExamID, b.StudentID})
+	})
+	return matches
+}
+
+// sortLess orders submissions by each sort field
+var sortLess = map[SortField]func(a, b *Submission) bool{
+	SortSubmissionTime: func(a, b *Submission) bool { return a.SubmissionTime.Before(b.SubmissionTime) },
+	SortReceivedAt:     func(a, b *Submission) bool { return a.ReceivedAt.Before(b.ReceivedAt) },
+	SortStudentName:    func(a, b *Submission) bool { return a.StudentName() < b.StudentName() },
+	SortStudentID:      func(a, b *Submission) bool { return a.StudentID < b.StudentID },
+	SortExamID:         func(a, b *Submission) bool { return a.ExamID < b.ExamID },
+	SortSuspicion:      func(a, b *Submission) bool { return maxSuspicion(a) < maxSuspicion(b) },
+}
+
+// window returns the part of the sorted matches selected by the page
+func (p Page) window(matches []*Submission) []*Submission {
+	if p.Limit <= 0 {
+		return matches
+	}
+	if p.Offset >= len(matches) {
+		return nil
+	}
+	end := p.Offset + p.Limit
+	if end > len(matches) {
+		end = len(matches)
+	}
+	return matches[p.Offset:end]
+}
+
+// matches reports whether sub is selected by the filter
+func (f SubmissionFilter) matches(sub *Submission) bool {
+	if f.ExamID != "" && sub.ExamID != f.ExamID {
+		return false
+	}
+	if f.StudentID != "" && sub.StudentID != f.StudentID {
+		return false
+	}
+	if f.StudentIDAfter != "" && sub.StudentID <= f.StudentIDAfter {
+		return false
+	}
+	if f.NameContains != "" && !strings.Contains(strings.ToLower(sub.StudentName()), strings.ToLower(f.NameContains)) {
+		return false
+	}
+	if !f.SubmittedFrom.IsZero() && sub.SubmissionTime.Before(f.SubmittedFrom) {
+		return false
+	}
+	if !f.SubmittedTo.IsZero() && !sub.SubmissionTime.Before(f.SubmittedTo) {
+		return false
+	}
+	if f.PasteUsed != nil && pasteUsed(sub) != *f.PasteUsed {
+		return false
+	}
+	if f.Verdict != "" && !hasVerdict(sub, f.Verdict) {
+		return false
+	}
+	if f.Timing != "" && !hasTimingFlag(sub, f.Timing) {
+		return false
+	}
+	if f.MinSuspicion > 0 && maxSuspicion(sub) < f.MinSuspicion {
+		return false
+	}
+	return true
+}
+
+// maxSuspicion returns the highest suspicion score of any scored question,
+// or -1 when none is scored, as the SQL stores sort
+func maxSuspicion(sub *Submission) float64 {
+	max := -1.0
+	for _, question := range sub.Questions {
+		if !question.Suspicion.Unscored && question.Suspicion.Score > max {
+			max = question.Suspicion.Score
+		}
+	}
+	return max
+}
+
+// hasTimingFlag reports whether the submission has the timing flag, or
+// any flag for TimingAny
+func hasTimingFlag(sub *Submission, flag TimingFlag) bool {
+	for _, f := range sub.TimingFlags {
+		if flag == TimingAny || f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// pasteUsed reports whether any question contains a paste
+func pasteUsed(sub *Submission) bool {
+	for _, question := range sub.Questions {
+		if question.Stats.PasteUsed() {
+			return true
+		}
+	}
+	return false
+}
+
+// hasVerdict reports whether any question has the integrity verdict
+func hasVerdict(sub *Submission, verdict eventlog.Verdict) bool {
+	for _, question := range sub.Questions {
+		if question.Integrity.Verdict == verdict {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMark reports whether any question of the submission stored under key
+// has mark as its current mark. The caller must hold m.mu.
+func (m *MemoryStorage) hasMark(key memoryKey, mark Mark) bool {
+	for _, marks := range m.marks[key] {
+		if marks[len(marks)-1].Mark == mark {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteSubmission removes a student's submission, all its revisions and
+// their marks
+func (m *MemoryStorage) DeleteSubmission(examID, studentID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := memoryKey{examID, studentID}
+	if _, ok := m.revisions[key]; !ok {
+		return ErrNotFound
+	}
+	delete(m.revisions, key)
+	delete(m.marks, key)
+	return nil
+}
+
+// ListRevisions retrieves every revision of a student's submission, oldest first
+func (m *MemoryStorage) ListRevisions(examID, studentID string) ([]Revision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored := m.revisions[memoryKey{examID, studentID}]
+	if len(stored) == 0 {
+		return nil, ErrNotFound
+	}
+
+	revisions := make([]Revision, 0, len(stored))
+	for i, sub := range stored {
+		revisions = append(revisions, revisionOf(sub, i == len(stored)-1))
+	}
+	return revisions, nil
+}
+
+// GetRevision retrieves one revision of a student's submission
+func (m *MemoryStorage) GetRevision(examID, studentID string, revision int) (*Revision, *Submission, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored := m.revisions[memoryKey{examID, studentID}]
+	if revision <= 0 || revision > len(stored) {
+		return nil, nil, ErrNotFound
+	}
+
+	sub := stored[revision-1]
+	rev := revisionOf(sub, revision == len(stored))
+	return &rev, copySubmission(sub), nil
+}
+
+// SaveMark records mark as the current mark of a question of the current
+// revision of a student's submission
+func (m *MemoryStorage) SaveMark(examID, studentID string, mark *QuestionMark) error {
+	if mark.MarkedAt.IsZero() {
+		mark.MarkedAt = time.Now().UTC()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := memoryKey{examID, studentID}
+	revisions := m.revisions[key]
+	if len(revisions) == 0 {
+		return ErrNotFound
+	}
+	current := revisions[len(revisions)-1]
+	if current.Question(mark.QuestionKey) == nil {
+		return ErrNotFound
+	}
+	mark.Revision = current.Revision
+
+	if m.marks[key] == nil {
+		m.marks[key] = make(map[string][]QuestionMark)
+	}
+	m.marks[key][mark.QuestionKey] = append(m.marks[key][mark.QuestionKey], *mark)
+	return nil
+}
+
+// SaveSuspicion replaces the suspicion of questions of the current
+// revision of a student's submission
+func (m *MemoryStorage) SaveSuspicion(examID, studentID string, suspicions map[string]Suspicion) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	revisions := m.revisions[memoryKey{examID, studentID}]
+	if len(revisions) == 0 {
+		return ErrNotFound
+	}
+	current := revisions[len(revisions)-1]
+	for key := range suspicions {
+		if current.Question(key) == nil {
+			return ErrNotFound
+		}
+	}
+	for key, suspicion := range suspicions {
+		current.Question(key).Suspicion = suspicion
+	}
+	return nil
+}
+
+// ListMarks retrieves the current mark of every marked question of a
+// student's submission, in question key order
+func (m *MemoryStorage) ListMarks(examID, studentID string) ([]QuestionMark, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key := memoryKey{examID, studentID}
+	if len(m.revisions[key]) == 0 {
+		return nil, ErrNotFound
+	}
+
+	var marks []QuestionMark
+	for _, history := range m.marks[key] {
+		marks = append(marks, history[len(history)-1])
+	}
+	sort.Slice(marks, func(i, j int) bool { return marks[i].QuestionKey < marks[j].QuestionKey })
+	return marks, nil
+}
+
+// ListExamMarks retrieves the current marks of the submissions of the
+// given students to an exam, keyed by student ID, in question key order
+func (m *MemoryStorage) ListExamMarks(examID string, studentIDs []string) (map[string][]QuestionMark, error) {
+	byStudent := make(map[string][]QuestionMark, len(studentIDs))
+	for _, studentID := range studentIDs {
+		marks, err := m.ListMarks(examID, studentID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(marks) > 0 {
+			byStudent[studentID] = marks
+		}
+	}
+	return byStudent, nil
+}
+
+// MarkHistory retrieves every mark given to a question of a student's
+// submission, oldest first
+func (m *MemoryStorage) MarkHistory(examID, studentID, questionKey string) ([]QuestionMark, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := m.marks[memoryKey{examID, studentID}][questionKey]
+	return append([]QuestionMark(nil), history...), nil
+}
+
+// CreateUser stores a new user, returning ErrExists if the username is taken
+func (m *MemoryStorage) CreateUser(user *User) error {
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now().UTC()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[user.Username]; ok {
+		return ErrExists
+	}
+	m.users[user.Username] = *user
+	return nil
+}
+
+// GetUser retrieves a user by username
+func (m *MemoryStorage) GetUser(username string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[username]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+// ListUsers retrieves every user ordered by username
+func (m *MemoryStorage) ListUsers() ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var users []User
+	for _, user := range m.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
+
+// UpdateUser replaces the password hash and role of an existing user and
+// signs it out everywhere
+func (m *MemoryStorage) UpdateUser(user *User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.users[user.Username]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.PasswordHash, stored.Role = user.PasswordHash, user.Role
+	m.users[user.Username] = stored
+	m.endSessions(user.Username)
+	return nil
+}
+
+// DeleteUser removes a user together with its sessions
+func (m *MemoryStorage) DeleteUser(username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[username]; !ok {
+		return ErrNotFound
+	}
+	delete(m.users, username)
+	m.endSessions(username)
+	return nil
+}
+
+// endSessions removes every session of a user. The caller must hold m.mu.
+func (m *MemoryStorage) endSessions(username string) {
+	for hash, session := range m.sessions {
+		if session.User.Username == username {
+			delete(m.sessions, hash)
+		}
+	}
+}
+
+// CreateSession stores a session for session.User, discarding every
+// expired session on the way
+func (m *MemoryStorage) CreateSession(session *Session) error {
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now().UTC()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hash, stored := range m.sessions {
+		if !stored.ExpiresAt.After(session.CreatedAt) {
+			delete(m.sessions, hash)
+		}
+	}
+
+	if _, ok := m.users[session.User.Username]; !ok {
+		return ErrNotFound
+	}
+	m.sessions[session.TokenHash] = *session
+	return nil
+}
+
+// GetSession retrieves an unexpired session and its user by token hash
+func (m *MemoryStorage) GetSession(tokenHash string) (*Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	session, ok := m.sessions[tokenHash]
+	if !ok || !session.ExpiresAt.After(time.Now()) {
+		return nil, ErrNotFound
+	}
+	session.User = m.users[session.User.Username]
+	return &session, nil
+}
+
+// DeleteSession removes a session; removing an unknown one is not an error
+func (m *MemoryStorage) DeleteSession(tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, tokenHash)
+	return nil
+}
+
+// SaveExam creates or replaces an exam definition
+func (m *MemoryStorage) SaveExam(exam *Exam) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.exams[exam.ID]; ok {
+		exam.CreatedAt = existing.CreatedAt
+	} else {
+		exam.CreatedAt = time.Now().UTC()
+	}
+	exam.QuestionIDs = exam.questionIDs()
+	m.exams[exam.ID] = copyExam(*exam)
+	return nil
+}
+
+// GetExam retrieves an exam by id
+func (m *MemoryStorage) GetExam(id string) (*Exam, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	exam, ok := m.exams[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	exam = copyExam(exam)
+	return &exam, nil
+}
+
+// ListExams retrieves every exam ordered by id
+func (m *MemoryStorage) ListExams() ([]Exam, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	exams := make([]Exam, 0, len(m.exams))
+	for _, exam := range m.exams {
+		exams = append(exams, copyExam(exam))
+	}
+	sort.Slice(exams, func(i, j int) bool { return exams[i].ID < exams[j].ID })
+	return exams, nil
+}
+
+// DeleteExam removes an exam without submissions
+func (m *MemoryStorage) DeleteExam(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.exams[id]; !ok {
+		return ErrNotFound
+	}
+	for _, revisions := range m.revisions {
+		if revisions[0].ExamID == id {
+			return ErrInUse
+		}
+	}
+	delete(m.exams, id)
+	return nil
+}
+
+// copyExam copies the question IDs of an exam
+func copyExam(exam Exam) Exam {
+	exam.QuestionIDs = append([]int{}, exam.QuestionIDs...)
+	return exam
+}
+
+// CreateExamSession registers an exam session
+func (m *MemoryStorage) CreateExamSession(session *ExamSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.examSessions[session.ID]; ok {
+		return ErrExists
+	}
+	stored := *session
+	stored.Questions = append([]int(nil), session.Questions...)
+	stored.Versions = append([]int(nil), session.Versions...)
+	m.examSessions[session.ID] = stored
+	return nil
+}
+
+// GetExamSession retrieves a registered exam session by id
+func (m *MemoryStorage) GetExamSession(id string) (*ExamSession, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	session, ok := m.examSessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	session.Questions = append([]int(nil), session.Questions...)
+	session.Versions = append([]int(nil), session.Versions...)
+	return &session, nil
+}
+
+// FirstExamSession retrieves the earliest started exam session of a
+// student for an exam
+func (m *MemoryStorage) FirstExamSession(examID, studentID string) (*ExamSession, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var first *ExamSession
+	for _, session := range m.examSessions {
+		if session.ExamID != examID || session.StudentID != studentID {
+			continue
+		}
+		if first == nil || session.StartedAt.Before(first.StartedAt) ||
+			(session.StartedAt.Equal(first.StartedAt) && session.ID < first.ID) {
+			session := session
+			first = &session
+		}
+	}
+	if first == nil {
+		return nil, ErrNotFound
+	}
+	first.Questions = append([]int(nil), first.Questions...)
+	first.Versions = append([]int(nil), first.Versions...)
+	return first, nil
+}
+
+// ExamSessionSubmitted reports whether a revision was submitted in an exam
+// session
+func (m *MemoryStorage) ExamSessionSubmitted(id string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, revisions := range m.revisions {
+		for _, revision := range revisions {
+			if revision.SessionID == id {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// SaveChunk stores a copy of an autosaved chunk, or returns ErrExists
+// when the question already has a chunk with its Seq
+func (m *MemoryStorage) SaveChunk(chunk *Chunk) error {
+	if chunk.ReceivedAt.IsZero() {
+		chunk.ReceivedAt = time.Now().UTC()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	chunks := m.chunks[chunk.SessionID]
+	i := sort.Search(len(chunks), func(i int) bool {
+		c := chunks[i]
+		return c.QuestionIndex > chunk.QuestionIndex || (c.QuestionIndex == chunk.QuestionIndex && c.Seq >= chunk.Seq)
+	})
+	if i < len(chunks) && chunks[i].QuestionIndex == chunk.QuestionIndex && chunks[i].Seq == chunk.Seq {
+		return ErrExists
+	}
+
+	stored := *chunk
+	stored.Events = append(eventlog.Log(nil), chunk.Events...)
+	chunks = append(chunks, Chunk{})
+	copy(chunks[i+1:], chunks[i:])
+	chunks[i] = stored
+	m.chunks[chunk.SessionID] = chunks
+	return nil
+}
+
+// ListChunks retrieves the chunks of an exam session by question and Seq
+func (m *MemoryStorage) ListChunks(sessionID string) ([]Chunk, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	chunks := make([]Chunk, len(m.chunks[sessionID]))
+	for i, chunk := range m.chunks[sessionID] {
+		chunk.Events = append(eventlog.Log(nil), chunk.Events...)
+		chunks[i] = chunk
+	}
+	return chunks, nil
+}
+
+// ListQuestionChunks retrieves the chunks of a question of an exam session
+// by Seq
+func (m *MemoryStorage) ListQuestionChunks(sessionID string, questionIndex int) ([]Chunk, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var chunks []Chunk
+	for _, chunk := range m.chunks[sessionID] {
+		if chunk.QuestionIndex == questionIndex {
+			chunk.Events = append(eventlog.Log(nil), chunk.Events...)
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks, nil
+}
+
+// LastChunk retrieves the chunk with the highest Seq of a question of an
+// exam session
+func (m *MemoryStorage) LastChunk(sessionID string, questionIndex int) (*Chunk, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	chunks := m.chunks[sessionID]
+	i := sort.Search(len(chunks), func(i int) bool {
+		return chunks[i].QuestionIndex > questionIndex
+	})
+	if i == 0 || chunks[i-1].QuestionIndex != questionIndex {
+		return nil, ErrNotFound
+	}
+	chunk := chunks[i-1]
+	chunk.Events = append(eventlog.Log(nil), chunk.Events...)
+	return &chunk, nil
+}
+
+// SaveQuestion stores q as the next version of question q.ID
+func (m *MemoryStorage) SaveQuestion(q *BankQuestion) error {
+	if q.CreatedAt.IsZero() {
+		q.CreatedAt = time.Now().UTC()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	versions := m.questions[q.ID]
+	if len(versions) == 0 {
+		m.questionActive[q.ID] = true
+	}
+	q.Version = len(versions) + 1
+	q.Active = m.questionActive[q.ID]
+	m.questions[q.ID] = append(versions, *q)
+	return nil
+}
+
+// GetQuestion retrieves one version of a question, or its current version
+// when version is 0
+func (m *MemoryStorage) GetQuestion(id, version int) (*BankQuestion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	versions := m.questions[id]
+	if version == 0 {
+		version = len(versions)
+	}
+	if version < 1 || version > len(versions) {
+		return nil, ErrNotFound
+	}
+	q := versions[version-1]
+	q.Active = m.questionActive[id]
+	return &q, nil
+}
+
+// ListQuestions retrieves the current version of every question in ID order
+func (m *MemoryStorage) ListQuestions() ([]BankQuestion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	questions := make([]BankQuestion, 0, len(m.questions))
+	for id, versions := range m.questions {
+		q := versions[len(versions)-1]
+		q.Active = m.questionActive[id]
+		questions = append(questions, q)
+	}
+	sort.Slice(questions, func(i, j int) bool { return questions[i].ID < questions[j].ID })
+	return questions, nil
+}
+
+// QuestionVersions retrieves every version of a question, oldest first
+func (m *MemoryStorage) QuestionVersions(id int) ([]BankQuestion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	versions := m.questions[id]
+	if len(versions) == 0 {
+		return nil, ErrNotFound
+	}
+	questions := make([]BankQuestion, len(versions))
+	for i, q := range versions {
+		q.Active = m.questionActive[id]
+		questions[i] = q
+	}
+	return questions, nil
+}
+
+// SetQuestionActive retires or restores a question
+func (m *MemoryStorage) SetQuestionActive(id int, active bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.questions[id]) == 0 {
+		return ErrNotFound
+	}
+	m.questionActive[id] = active
+	return nil
+}
+
+// Migrate does nothing: the memory store has no schema
+func (m *MemoryStorage) Migrate() ([]MigrationStatus, error) {
+	return nil, nil
+}
+
+// Migrations reports no migrations: the memory store has no schema
+func (m *MemoryStorage) Migrations() ([]MigrationStatus, error) {
+	return nil, nil
+}
+
+// Close discards every stored submission
+func (m *MemoryStorage) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revisions = make(map[memoryKey][]*Submission)
+	m.marks = make(map[memoryKey]map[string][]QuestionMark)
+	m.users = make(map[string]User)
+	m.sessions = make(map[string]Session)
+	m.examSessions = make(map[string]ExamSession)
+	return nil
+}
+
+// revisionOf describes a stored submission as a revision
+func revisionOf(sub *Submission, current bool) Revision {
+	rev := Revision{
+		Revision:       sub.Revision,
+		SubmissionTime: sub.SubmissionTime,
+		ReceivedAt:     sub.ReceivedAt,
+		ClientIP:       sub.ClientIP,
+		SessionID:      sub.SessionID,
+		TimingFlags:    sub.TimingFlags,
+		Current:        current,
+	}
+	if !sub.StartedAt.IsZero() {
+		started := sub.StartedAt
+		rev.StartedAt = &started
+	}
+	return rev
+}
+
+// copySubmission copies the parts of a submission callers may modify.
+// Events are never mutated after decoding, so event logs are shared.
+func copySubmission(sub *Submission) *Submission {
+	c := *sub
+
+	if sub.Metadata != nil {
+		c.Metadata = make(map[string]string, len(sub.Metadata))
+		for k, v := range sub.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+
+	if sub.Questions != nil {
+		c.Questions = make([]Question, len(sub.Questions))
+		copy(c.Questions, sub.Questions)
+	}
+	c.TimingFlags = append([]TimingFlag(nil), sub.TimingFlags...)
+
+	return &c
+}
@@ -0,0 +1,607 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/internal/eventlog"
+)
+
+// migration is a numbered schema change applied once, in order
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// MigrationStatus describes a known migration and whether it was applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// execSQL returns a migration step that runs a fixed SQL script
+func execSQL(query string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
+
+// sqliteMigrations lists every SQLite schema change in order. Never edit an
+// applied migration; append a new one instead.
+var sqliteMigrations = []migration{
+	{
+		version: 1,
+		name:    "create submissions",
+		// IF NOT EXISTS lets databases created before migrations adopt this step
+		up: execSQL(`
+		CREATE TABLE IF NOT EXISTS submissions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			exam_id TEXT NOT NULL,
+			student_id TEXT NOT NULL,
+			student_name TEXT NOT NULL,
+			submission_time DATETIME NOT NULL,
+			payload_json TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(exam_id, student_id)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_exam_id ON submissions(exam_id);
+		CREATE INDEX IF NOT EXISTS idx_student_id ON submissions(student_id);
+		CREATE INDEX IF NOT EXISTS idx_submission_time ON submissions(submission_time);
+		`),
+	},
+	{
+		version: 2,
+		name:    "create submission integrity",
+		up: execSQL(`
+		CREATE TABLE IF NOT EXISTS submission_integrity (
+			exam_id TEXT NOT NULL,
+			student_id TEXT NOT NULL,
+			question_key TEXT NOT NULL,
+			verdict TEXT NOT NULL,
+			diff_offset INTEGER,
+			detail TEXT NOT NULL DEFAULT '',
+			checked_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY(exam_id, student_id, question_key)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_integrity_verdict ON submission_integrity(verdict);
+		`),
+	},
+	{
+		version: 3,
+		name:    "normalize exams, questions and events",
+		up:      normalizeSubmissions,
+	},
+	{
+		version: 4,
+		name:    "keep submission revisions",
+		up: execSQL(`
+		CREATE TABLE submission_revisions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			submission_id INTEGER NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+			revision INTEGER NOT NULL,
+			submission_time DATETIME NOT NULL,
+			received_at DATETIME NOT NULL,
+			client_ip TEXT NOT NULL DEFAULT '',
+			payload_json TEXT NOT NULL,
+			UNIQUE(submission_id, revision)
+		);
+
+		CREATE INDEX idx_revisions_received_at ON submission_revisions(received_at);
+
+		-- Revisions are an audit trail: never rewrite one
+		CREATE TRIGGER submission_revisions_immutable
+		BEFORE UPDATE ON submission_revisions
+		BEGIN
+			SELECT RAISE(ABORT, 'submission revisions are immutable');
+		END;
+
+		ALTER TABLE submissions ADD COLUMN current_revision_id INTEGER REFERENCES submission_revisions(id);
+
+		-- Existing rows become revision 1, received when they were stored
+		INSERT INTO submission_revisions (submission_id, revision, submission_time, received_at, payload_json)
+		SELECT id, 1, submission_time, COALESCE(created_at, CURRENT_TIMESTAMP), payload_json FROM submissions;
+
+		UPDATE submissions SET current_revision_id = (
+			SELECT r.id FROM submission_revisions r
+			WHERE r.submission_id = submissions.id AND r.revision = 1
+		);
+		`),
+	},
+	{
+		version: 5,
+		name:    "index submission listing",
+		up: execSQL(`
+		CREATE INDEX idx_student_name ON submissions(student_name);
+		CREATE INDEX idx_exam_submission_time ON submissions(exam_id, submission_time);
+		`),
+	},
+	{
+		version: 6,
+		name:    "add question statistics",
+		up:      addQuestionStats(sqliteDialect),
+	},
+	{
+		version: 7,
+		name:    "create question marks",
+		up: execSQL(`
+		CREATE TABLE question_marks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			submission_id INTEGER NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+			question_key TEXT NOT NULL,
+			revision INTEGER NOT NULL,
+			mark TEXT NOT NULL,
+			comment TEXT NOT NULL DEFAULT '',
+			evaluator TEXT NOT NULL,
+			marked_at DATETIME NOT NULL
+		);
+
+		CREATE INDEX idx_marks_question ON question_marks(submission_id, question_key, id);
+		CREATE INDEX idx_marks_mark ON question_marks(mark);
+
+		-- Marks are a history: a new mark supersedes, never rewrites, the last
+		CREATE TRIGGER question_marks_immutable
+		BEFORE UPDATE ON question_marks
+		BEGIN
+			SELECT RAISE(ABORT, 'question marks are immutable');
+		END;
+		`),
+	},
+	{
+		version: 8,
+		name:    "create users and sessions",
+		up: execSQL(`
+		CREATE TABLE users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			username TEXT NOT NULL UNIQUE,
+			password_hash TEXT NOT NULL,
+			role TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		);
+
+		CREATE TABLE sessions (
+			token_hash TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			created_at DATETIME NOT NULL,
+			expires_at DATETIME NOT NULL
+		);
+
+		CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+		CREATE INDEX idx_sessions_expires_at ON sessions(expires_at);
+		`),
+	},
+	{
+		version: 9,
+		name:    "create exam sessions",
+		up: execSQL(`
+		CREATE TABLE exam_sessions (
+			id TEXT PRIMARY KEY,
+			exam_id TEXT NOT NULL,
+			student_id TEXT NOT NULL,
+			question_indexes TEXT NOT NULL,
+			started_at DATETIME NOT NULL,
+			expires_at DATETIME NOT NULL,
+			client_ip TEXT NOT NULL DEFAULT ''
+		);
+
+		CREATE INDEX idx_exam_sessions_student ON exam_sessions(exam_id, student_id);
+
+		ALTER TABLE submission_revisions ADD COLUMN session_id TEXT NOT NULL DEFAULT '';
+		ALTER TABLE submission_revisions ADD COLUMN started_at DATETIME;
+		`),
+	},
+	{
+		version: 10,
+		name:    "create question bank",
+		up: execSQL(`
+		CREATE TABLE bank_questions (
+			id INTEGER PRIMARY KEY,
+			active BOOLEAN NOT NULL DEFAULT 1,
+			created_at DATETIME NOT NULL
+		);
+
+		CREATE TABLE bank_question_versions (
+			question_id INTEGER NOT NULL REFERENCES bank_questions(id) ON DELETE CASCADE,
+			version INTEGER NOT NULL,
+			title TEXT NOT NULL,
+			body TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (question_id, version)
+		);
+
+		-- Sessions keep serving the version they were assigned
+		CREATE TRIGGER bank_question_versions_immutable
+		BEFORE UPDATE ON bank_question_versions
+		BEGIN
+			SELECT RAISE(ABORT, 'question versions are immutable');
+		END;
+
+		ALTER TABLE exam_sessions ADD COLUMN question_versions TEXT NOT NULL DEFAULT '[]';
+		`),
+	},
+	{
+		version: 11,
+		name:    "define exams",
+		up: execSQL(`
+		ALTER TABLE exams ADD COLUMN title TEXT NOT NULL DEFAULT '';
+		ALTER TABLE exams ADD COLUMN question_ids TEXT NOT NULL DEFAULT '[]';
+		ALTER TABLE exams ADD COLUMN opens_at DATETIME;
+		ALTER TABLE exams ADD COLUMN closes_at DATETIME;
+		ALTER TABLE exams ADD COLUMN duration_seconds INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE exams ADD COLUMN max_attempts INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE exams ADD COLUMN enforcement TEXT NOT NULL DEFAULT 'flag';
+
+		ALTER TABLE submission_revisions ADD COLUMN timing_flags TEXT NOT NULL DEFAULT '';
+		`),
+	},
+	{
+		version: 12,
+		name:    "create autosave chunks",
+		up: execSQL(`
+		CREATE TABLE autosave_chunks (
+			session_id TEXT NOT NULL REFERENCES exam_sessions(id) ON DELETE CASCADE,
+			question_index INTEGER NOT NULL,
+			seq INTEGER NOT NULL,
+			events_json TEXT NOT NULL,
+			received_at DATETIME NOT NULL,
+			PRIMARY KEY (session_id, question_index, seq)
+		);
+		`),
+	},
+	{
+		version: 13,
+		name:    "add question suspicion",
+		up: execSQL(`
+		-- Stored answers stay unscored (NULL) until drkka rescore
+		ALTER TABLE submission_questions ADD COLUMN suspicion_score REAL;
+		ALTER TABLE submission_questions ADD COLUMN suspicion_rules TEXT NOT NULL DEFAULT '';
+
+		CREATE INDEX idx_questions_suspicion ON submission_questions(suspicion_score);
+		`),
+	},
+	{
+		version: 14,
+		name:    "index revisions by exam session",
+		up: execSQL(`
+		CREATE INDEX idx_revisions_session ON submission_revisions(session_id);
+		`),
+	},
+}
+
+// normalizeSubmissions creates the exams, submission_questions and events
+// tables, backfills them from payload_json and folds the integrity
+// verdicts into submission_questions
+func normalizeSubmissions(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE exams (
+		id TEXT PRIMARY KEY,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE submission_questions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		submission_id INTEGER NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+		question_key TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		question_id TEXT NOT NULL DEFAULT '',
+		question_index INTEGER NOT NULL,
+		question_title TEXT NOT NULL DEFAULT '',
+		final_answer TEXT NOT NULL,
+		start_time_ms REAL NOT NULL DEFAULT 0,
+		end_time_ms REAL NOT NULL DEFAULT 0,
+		duration_ms REAL NOT NULL DEFAULT 0,
+		event_count INTEGER NOT NULL DEFAULT 0,
+		integrity_verdict TEXT NOT NULL DEFAULT '',
+		integrity_diff_offset INTEGER,
+		integrity_detail TEXT NOT NULL DEFAULT '',
+		UNIQUE(submission_id, question_key)
+	);
+
+	CREATE INDEX idx_questions_question_index ON submission_questions(question_index);
+	CREATE INDEX idx_questions_integrity ON submission_questions(integrity_verdict);
+
+	CREATE TABLE events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		submission_question_id INTEGER NOT NULL REFERENCES submission_questions(id) ON DELETE CASCADE,
+		seq INTEGER NOT NULL,
+		type TEXT NOT NULL,
+		key TEXT,
+		content TEXT,
+		string TEXT,
+		sel_start INTEGER,
+		sel_end INTEGER,
+		latency_ms REAL NOT NULL DEFAULT 0,
+		interval_ms REAL,
+		offset_ms REAL NOT NULL DEFAULT 0,
+		UNIQUE(submission_question_id, seq)
+	);
+
+	CREATE INDEX idx_events_type ON events(type);
+
+	INSERT INTO exams (id) SELECT DISTINCT exam_id FROM submissions;
+	`)
+	if err != nil {
+		return err
+	}
+
+	stored, err := loadPayloads(tx)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range stored {
+		var sub Submission
+		if err := json.Unmarshal([]byte(row.payload), &sub); err != nil {
+			// The payload stays in payload_json; it just won't be queryable
+			log.Printf("⚠️  Skipping normalization of submission %d: %v", row.id, err)
+			continue
+		}
+		if err := normalizeQuestions(tx, row.id, &sub); err != nil {
+			return fmt.Errorf("failed to normalize submission %d: %w", row.id, err)
+		}
+	}
+
+	_, err = tx.Exec(`
+	UPDATE submission_questions SET
+		integrity_verdict = i.verdict,
+		integrity_diff_offset = i.diff_offset,
+		integrity_detail = i.detail
+	FROM submissions s
+	JOIN submission_integrity i
+		ON i.exam_id = s.exam_id AND i.student_id = s.student_id
+	WHERE s.id = submission_questions.submission_id
+		AND i.question_key = submission_questions.question_key;
+
+	DROP TABLE submission_integrity;
+	`)
+	return err
+}
+
+// normalizeQuestions writes the questions and events of a submission with
+// the columns submission_questions had in migration 3. Unlike
+// insertQuestions, it must never change with later schema changes, which
+// add their own backfills.
+func normalizeQuestions(tx *sql.Tx, submissionID int64, sub *Submission) error {
+	questionQuery := `
+	INSERT INTO submission_questions (
+		submission_id, question_key, position, question_id, question_index, question_title,
+		final_answer, start_time_ms, end_time_ms, duration_ms, event_count
+	)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	RETURNING id
+	`
+
+	eventStmt, err := tx.Prepare(`
+	INSERT INTO events (
+		submission_question_id, seq, type, key, content, string,
+		sel_start, sel_end, latency_ms, interval_ms, offset_ms
+	)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare event insert: %w", err)
+	}
+	defer eventStmt.Close()
+
+	for position, question := range sub.Questions {
+		var questionID int64
+		err := tx.QueryRow(questionQuery,
+			submissionID, question.Key, position, question.QuestionID, question.QuestionIndex, question.QuestionTitle,
+			question.FinalAnswer, question.StartTimeMs, question.EndTimeMs, question.EndTimeMs-question.StartTimeMs, len(question.EventLog),
+		).Scan(&questionID)
+		if err != nil {
+			return fmt.Errorf("failed to save question %s: %w", question.Key, err)
+		}
+
+		offset := 0.0
+		for seq, event := range question.EventLog {
+			offset += event.Latency()
+			row := eventRow(event)
+			_, err := eventStmt.Exec(questionID, seq, string(event.EventType()), row.key, row.content, row.str,
+				row.selStart, row.selEnd, event.Latency(), row.interval, offset)
+			if err != nil {
+				return fmt.Errorf("failed to save event %d of question %s: %w", seq, question.Key, err)
+			}
+			// The next event's latency is measured from this segment's last character
+			if c, ok := event.(*eventlog.Compressed); ok && len(c.String) > 0 {
+				offset += c.IntervalMs * float64(len([]rune(c.String))-1)
+			}
+		}
+	}
+	return nil
+}
+
+// storedPayload is the current payload of a stored submission
+type storedPayload struct {
+	id      int64
+	payload string
+}
+
+// loadPayloads reads the current payload of every stored submission
+func loadPayloads(tx *sql.Tx) ([]storedPayload, error) {
+	rows, err := tx.Query(`SELECT id, payload_json FROM submissions`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stored []storedPayload
+	for rows.Next() {
+		var row storedPayload
+		if err := rows.Scan(&row.id, &row.payload); err != nil {
+			return nil, err
+		}
+		stored = append(stored, row)
+	}
+	return stored, rows.Err()
+}
+
+// addQuestionStats returns the migration adding the typing statistics
+// columns to submission_questions and computing them for stored submissions
+func addQuestionStats(d dialect) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		columns := []string{
+			"paste_count INTEGER NOT NULL DEFAULT 0",
+			"pasted_chars INTEGER NOT NULL DEFAULT 0",
+			"paste_share " + d.floatType() + " NOT NULL DEFAULT 0",
+			"backspace_count INTEGER NOT NULL DEFAULT 0",
+			"selection_changes INTEGER NOT NULL DEFAULT 0",
+			"active_typing_ms " + d.floatType() + " NOT NULL DEFAULT 0",
+			"longest_pause_ms " + d.floatType() + " NOT NULL DEFAULT 0",
+		}
+		for _, column := range columns {
+			if _, err := tx.Exec(`ALTER TABLE submission_questions ADD COLUMN ` + column); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.Exec(`
+		CREATE INDEX idx_questions_paste_count ON submission_questions(paste_count);
+		CREATE INDEX idx_questions_paste_share ON submission_questions(paste_share);
+		`)
+		if err != nil {
+			return err
+		}
+
+		stored, err := loadPayloads(tx)
+		if err != nil {
+			return err
+		}
+
+		update := d.rebind(`
+		UPDATE submission_questions SET
+			paste_count = ?, pasted_chars = ?, paste_share = ?, backspace_count = ?,
+			selection_changes = ?, active_typing_ms = ?, longest_pause_ms = ?
+		WHERE submission_id = ? AND question_key = ?
+		`)
+		for _, row := range stored {
+			var sub Submission
+			if err := json.Unmarshal([]byte(row.payload), &sub); err != nil {
+				// normalizeSubmissions skipped these too; they have no questions
+				log.Printf("⚠️  Skipping statistics of submission %d: %v", row.id, err)
+				continue
+			}
+			for _, question := range sub.Questions {
+				stats := eventlog.ComputeStats(question.EventLog)
+				_, err := tx.Exec(update, stats.PasteCount, stats.PastedChars, stats.PasteShare, stats.BackspaceCount,
+					stats.SelectionChanges, stats.ActiveTypingMs, stats.LongestPauseMs, row.id, question.Key)
+				if err != nil {
+					return fmt.Errorf("failed to compute statistics of submission %d: %w", row.id, err)
+				}
+			}
+		}
+
+		return nil
+	}
+}
+
+// Migrate applies every pending migration, each in its own transaction,
+// and returns the ones that were applied
+func (s *sqlStore) Migrate() ([]MigrationStatus, error) {
+	if err := s.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []MigrationStatus
+	for _, m := range s.migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+
+		if err := s.applyMigration(m); err != nil {
+			return ran, fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+		ran = append(ran, MigrationStatus{Version: m.version, Name: m.name, Applied: true, AppliedAt: time.Now()})
+	}
+
+	return ran, nil
+}
+
+// Migrations reports every known migration and whether it has been applied
+func (s *sqlStore) Migrations() ([]MigrationStatus, error) {
+	if err := s.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(s.migrations))
+	for _, m := range s.migrations {
+		appliedAt, ok := applied[m.version]
+		statuses = append(statuses, MigrationStatus{Version: m.version, Name: m.name, Applied: ok, AppliedAt: appliedAt})
+	}
+
+	return statuses, nil
+}
+
+// ensureMigrationsTable creates the schema_migrations bookkeeping table
+func (s *sqlStore) ensureMigrationsTable() error {
+	_, err := s.db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at ` + s.dialect.timestampType() + ` DEFAULT CURRENT_TIMESTAMP
+	)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// appliedMigrations returns the applied migration versions and their times
+func (s *sqlStore) appliedMigrations() (map[int]time.Time, error) {
+	rows, err := s.db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// applyMigration runs one migration and records it atomically
+func (s *sqlStore) applyMigration(m migration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(s.dialect.rebind(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`), m.version, m.name)
+	if err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// BankQuestion is one version of a question in the question bank. ID is
+// stable across versions and is the questionIndex submissions refer to.
+type BankQuestion struct {
+	ID      int    `json:"id"`
+	Version int    `json:"version"`
+	Title   string `json:"title"`
+	Text    string `json:"text"`
+	// Active reports whether the question is currently assigned to new
+	// exam sessions; it belongs to the question, not to the version
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// currentQuestionVersion is the subquery condition selecting, for a
+// bank_question_versions row aliased v, only the latest version
+const currentQuestionVersion = `v.version = (
+			SELECT MAX(version) FROM bank_question_versions WHERE question_id = v.question_id
+		)`
+
+// bankQuestionsFrom joins every question version to its question
+const bankQuestionsFrom = `
+	SELECT v.question_id, v.version, v.title, v.body, q.active, v.created_at
+	FROM bank_question_versions v
+	JOIN bank_questions q ON q.id = v.question_id`
+
+// SaveQuestion stores q as the next version of question q.ID, creating
+// the question, active, if it does not exist yet. It sets q.Version,
+// q.Active and, when zero, q.CreatedAt.
+func (s *sqlStore) SaveQuestion(q *BankQuestion) error {
+	if q.CreatedAt.IsZero() {
+		q.CreatedAt = time.Now().UTC()
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var version int
+	err = tx.QueryRow(s.dialect.rebind(`SELECT COALESCE(MAX(version), 0) FROM bank_question_versions WHERE question_id = ?`), q.ID).Scan(&version)
+	if err != nil {
+		return fmt.Errorf("failed to find question: %w", err)
+	}
+
+	if version == 0 {
+		q.Active = true
+		_, err = tx.Exec(s.dialect.rebind(`INSERT INTO bank_questions (id, active, created_at) VALUES (?, ?, ?)`), q.ID, q.Active, q.CreatedAt.UTC())
+	} else {
+		err = tx.QueryRow(s.dialect.rebind(`SELECT active FROM bank_questions WHERE id = ?`), q.ID).Scan(&q.Active)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save question: %w", err)
+	}
+
+	q.Version = version + 1
+	_, err = tx.Exec(s.dialect.rebind(`
+	INSERT INTO bank_question_versions (question_id, version, title, body, created_at)
+	VALUES (?, ?, ?, ?, ?)
+	`), q.ID, q.Version, q.Title, q.Text, q.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save question version: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit question: %w", err)
+	}
+	return nil
+}
+
+// GetQuestion retrieves one version of a question, or its current version
+// when version is 0
+func (s *sqlStore) GetQuestion(id, version int) (*BankQuestion, error) {
+	query := bankQuestionsFrom + ` WHERE v.question_id = ? AND v.version = ?`
+	args := []interface{}{id, version}
+	if version == 0 {
+		query = bankQuestionsFrom + ` WHERE v.question_id = ? AND ` + currentQuestionVersion
+		args = args[:1]
+	}
+
+	questions, err := s.queryQuestions(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(questions) == 0 {
+		return nil, ErrNotFound
+	}
+	return &questions[0], nil
+}
+
+// ListQuestions retrieves the current version of every question, active
+// or not, in ID order
+func (s *sqlStore) ListQuestions() ([]BankQuestion, error) {
+	return s.queryQuestions(bankQuestionsFrom + ` WHERE ` + currentQuestionVersion + ` ORDER BY v.question_id`)
+}
+
+// QuestionVersions retrieves every version of a question, oldest first
+func (s *sqlStore) QuestionVersions(id int) ([]BankQuestion, error) {
+	questions, err := s.queryQuestions(bankQuestionsFrom+` WHERE v.question_id = ? ORDER BY v.version`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(questions) == 0 {
+		return nil, ErrNotFound
+	}
+	return questions, nil
+}
+
+// SetQuestionActive retires (false) or restores (true) a question
+func (s *sqlStore) SetQuestionActive(id int, active bool) error {
+	result, err := s.db.Exec(s.dialect.rebind(`UPDATE bank_questions SET active = ? WHERE id = ?`), active, id)
+	if err != nil {
+		return fmt.Errorf("failed to update question: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// queryQuestions runs a bankQuestionsFrom query
+func (s *sqlStore) queryQuestions(query string, args ...interface{}) ([]BankQuestion, error) {
+	rows, err := s.db.Query(s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query questions: %w", err)
+	}
+	defer rows.Close()
+
+	var questions []BankQuestion
+	for rows.Next() {
+		var q BankQuestion
+		if err := rows.Scan(&q.ID, &q.Version, &q.Title, &q.Text, &q.Active, &q.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		questions = append(questions, q)
+	}
+	return questions, rows.Err()
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Revision describes one immutable stored attempt of a submission
+type Revision struct {
+	Revision       int       `json:"revision"`
+	SubmissionTime time.Time `json:"submissionTime"`
+	ReceivedAt     time.Time `json:"receivedAt"`
+	ClientIP       string    `json:"clientIp"`
+	// SessionID and StartedAt describe the exam session of the revision
+	SessionID string     `json:"sessionId,omitempty"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	// TimingFlags lists the exam limits the revision was received outside of
+	TimingFlags []TimingFlag `json:"timingFlags,omitempty"`
+	Current     bool         `json:"current"`
+}
+
+// setStartedAt sets StartedAt from a nullable column
+func (r *Revision) setStartedAt(t sql.NullTime) {
+	if t.Valid {
+		started := t.Time
+		r.StartedAt = &started
+	}
+}
+
+// ListRevisions retrieves every revision of a student's submission, oldest first
+func (s *sqlStore) ListRevisions(examID, studentID string) ([]Revision, error) {
+	query := `
+	SELECT r.revision, r.submission_time, r.received_at, r.client_ip, r.session_id, r.started_at, r.timing_flags,
+		r.id = s.current_revision_id
+	FROM submission_revisions r
+	JOIN submissions s ON s.id = r.submission_id
+	WHERE s.exam_id = ? AND s.student_id = ?
+	ORDER BY r.revision
+	`
+
+	rows, err := s.db.Query(s.dialect.rebind(query), examID, studentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []Revision
+	for rows.Next() {
+		var rev Revision
+		var startedAt sql.NullTime
+		var timingFlags string
+		if err := rows.Scan(&rev.Revision, &rev.SubmissionTime, &rev.ReceivedAt, &rev.ClientIP,
+			&rev.SessionID, &startedAt, &timingFlags, &rev.Current); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		rev.setStartedAt(startedAt)
+		rev.TimingFlags = splitFlags(timingFlags)
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(revisions) == 0 {
+		return nil, ErrNotFound
+	}
+	return revisions, nil
+}
+
+// GetRevision retrieves the payload of one revision of a student's submission
+func (s *sqlStore) GetRevision(examID, studentID string, revision int) (*Revision, *Submission, error) {
+	query := `
+	SELECT r.revision, r.submission_time, r.received_at, r.client_ip, r.session_id, r.started_at, r.timing_flags,
+		r.id = s.current_revision_id, r.payload_json
+	FROM submission_revisions r
+	JOIN submissions s ON s.id = r.submission_id
+	WHERE s.exam_id = ? AND s.student_id = ? AND r.revision = ?
+	`
+
+	var rev Revision
+	var startedAt sql.NullTime
+	var timingFlags, payloadJSON string
+	err := s.db.QueryRow(s.dialect.rebind(query), examID, studentID, revision).
+		Scan(&rev.Revision, &rev.SubmissionTime, &rev.ReceivedAt, &rev.ClientIP, &rev.SessionID, &startedAt, &timingFlags, &rev.Current, &payloadJSON)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve revision: %w", err)
+	}
+
+	var sub Submission
+	if err := json.Unmarshal([]byte(payloadJSON), &sub); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+	rev.setStartedAt(startedAt)
+	rev.TimingFlags = splitFlags(timingFlags)
+
+	sub.Revision, sub.ReceivedAt, sub.ClientIP = rev.Revision, rev.ReceivedAt, rev.ClientIP
+	sub.SessionID, sub.StartedAt = rev.SessionID, startedAt.Time
+	sub.TimingFlags = rev.TimingFlags
+
+	return &rev, &sub, nil
+}
@@ -0,0 +1,671 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/eventlog"
+)
+
+// dialect identifies the SQL flavour a sqlStore talks to
+type dialect int
+
+const (
+	sqliteDialect dialect = iota
+	postgresDialect
+)
+
+// rebind rewrites the ? placeholders of query for the dialect
+func (d dialect) rebind(query string) string {
+	if d != postgresDialect || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+	return b.String()
+}
+
+// timestampType is the column type used for timestamps
+func (d dialect) timestampType() string {
+	if d == postgresDialect {
+		return "TIMESTAMPTZ"
+	}
+	return "DATETIME"
+}
+
+// metadataKeys is the query listing the distinct metadata fields of the
+// current payloads of an exam's submissions
+func (d dialect) metadataKeys() string {
+	if d == postgresDialect {
+		return `
+	SELECT DISTINCT k.key
+	FROM submissions s,
+		jsonb_object_keys(CASE WHEN jsonb_typeof(s.payload_json::jsonb -> 'metadata') = 'object'
+			THEN s.payload_json::jsonb -> 'metadata' ELSE '{}' END) AS k(key)
+	WHERE s.exam_id = ?
+	`
+	}
+	return `
+	SELECT DISTINCT j.key
+	FROM submissions s, json_each(s.payload_json, '$.metadata') j
+	WHERE s.exam_id = ? AND j.key IS NOT NULL
+	`
+}
+
+// floatType is the column type used for double precision numbers
+func (d dialect) floatType() string {
+	if d == postgresDialect {
+		return "DOUBLE PRECISION"
+	}
+	return "REAL"
+}
+
+// sqlStore implements Store on top of database/sql. Queries are written
+// with ? placeholders and rebound for the dialect.
+type sqlStore struct {
+	db         *sql.DB
+	dialect    dialect
+	migrations []migration
+}
+
+// SaveSubmission saves a submission as a new immutable revision and makes
+// it the current one, replacing the queryable questions and events of any
+// earlier revision. sub.Revision is set to the stored revision number.
+func (s *sqlStore) SaveSubmission(sub *Submission) error {
+	if sub.ReceivedAt.IsZero() {
+		sub.ReceivedAt = time.Now().UTC()
+	}
+
+	// Convert submission to JSON
+	payloadJSON, err := json.Marshal(sub)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	// Insert into database (replace if exists). Times are stored in UTC so
+	// that they compare and sort correctly as SQLite text.
+	query := `
+	INSERT INTO submissions (exam_id, student_id, student_name, submission_time, payload_json)
+	VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT(exam_id, student_id) DO UPDATE SET
+		student_name = excluded.student_name,
+		submission_time = excluded.submission_time,
+		payload_json = excluded.payload_json,
+		created_at = CURRENT_TIMESTAMP
+	RETURNING id
+	`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(s.dialect.rebind(`INSERT INTO exams (id) VALUES (?) ON CONFLICT(id) DO NOTHING`), sub.ExamID)
+	if err != nil {
+		return fmt.Errorf("failed to register exam: %w", err)
+	}
+
+	var submissionID int64
+	err = tx.QueryRow(s.dialect.rebind(query), sub.ExamID, sub.StudentID, sub.StudentName(), sub.SubmissionTime.UTC(), string(payloadJSON)).Scan(&submissionID)
+	if err != nil {
+		return fmt.Errorf("failed to save submission: %w", err)
+	}
+
+	// Append the attempt as the next revision and point the row at it
+	err = tx.QueryRow(s.dialect.rebind(`SELECT COALESCE(MAX(revision), 0) + 1 FROM submission_revisions WHERE submission_id = ?`), submissionID).Scan(&sub.Revision)
+	if err != nil {
+		return fmt.Errorf("failed to number revision: %w", err)
+	}
+
+	var revisionID int64
+	err = tx.QueryRow(s.dialect.rebind(`
+	INSERT INTO submission_revisions (submission_id, revision, submission_time, received_at, client_ip, session_id, started_at, timing_flags, payload_json)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	RETURNING id
+	`), submissionID, sub.Revision, sub.SubmissionTime.UTC(), sub.ReceivedAt, sub.ClientIP,
+		sub.SessionID, nullTime(sub.StartedAt), joinFlags(sub.TimingFlags), string(payloadJSON)).Scan(&revisionID)
+	if err != nil {
+		return fmt.Errorf("failed to save revision: %w", err)
+	}
+
+	_, err = tx.Exec(s.dialect.rebind(`UPDATE submissions SET current_revision_id = ? WHERE id = ?`), revisionID, submissionID)
+	if err != nil {
+		return fmt.Errorf("failed to update current revision: %w", err)
+	}
+
+	// Replace the questions (and, by cascade, events) of any earlier revision
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM submission_questions WHERE submission_id = ?`), submissionID); err != nil {
+		return fmt.Errorf("failed to clear questions: %w", err)
+	}
+
+	if err := insertQuestions(tx, s.dialect, submissionID, sub); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit submission: %w", err)
+	}
+
+	return nil
+}
+
+// insertQuestions writes the normalized questions and events of a submission
+func insertQuestions(tx *sql.Tx, d dialect, submissionID int64, sub *Submission) error {
+	questionQuery := d.rebind(`
+	INSERT INTO submission_questions (
+		submission_id, question_key, position, question_id, question_index, question_title,
+		final_answer, start_time_ms, end_time_ms, duration_ms, event_count,
+		integrity_verdict, integrity_diff_offset, integrity_detail,
+		paste_count, pasted_chars, paste_share, backspace_count, selection_changes,
+		active_typing_ms, longest_pause_ms, suspicion_score, suspicion_rules
+	)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	RETURNING id
+	`)
+
+	eventStmt, err := tx.Prepare(d.rebind(`
+	INSERT INTO events (
+		submission_question_id, seq, type, key, content, string,
+		sel_start, sel_end, latency_ms, interval_ms, offset_ms
+	)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`))
+	if err != nil {
+		return fmt.Errorf("failed to prepare event insert: %w", err)
+	}
+	defer eventStmt.Close()
+
+	for position, question := range sub.Questions {
+		var questionID int64
+		err := tx.QueryRow(questionQuery,
+			submissionID, question.Key, position, question.QuestionID, question.QuestionIndex, question.QuestionTitle,
+			question.FinalAnswer, question.StartTimeMs, question.EndTimeMs, question.EndTimeMs-question.StartTimeMs, len(question.EventLog),
+			string(question.Integrity.Verdict), question.Integrity.DiffOffset, question.Integrity.Detail,
+			question.Stats.PasteCount, question.Stats.PastedChars, question.Stats.PasteShare, question.Stats.BackspaceCount,
+			question.Stats.SelectionChanges, question.Stats.ActiveTypingMs, question.Stats.LongestPauseMs,
+			question.Suspicion.column(), strings.Join(question.Suspicion.Rules, ","),
+		).Scan(&questionID)
+		if err != nil {
+			return fmt.Errorf("failed to save question %s: %w", question.Key, err)
+		}
+
+		offset := 0.0
+		for seq, event := range question.EventLog {
+			offset += event.Latency()
+			row := eventRow(event)
+			_, err := eventStmt.Exec(questionID, seq, string(event.EventType()), row.key, row.content, row.str,
+				row.selStart, row.selEnd, event.Latency(), row.interval, offset)
+			if err != nil {
+				return fmt.Errorf("failed to save event %d of question %s: %w", seq, question.Key, err)
+			}
+			// The next event's latency is measured from this segment's last character
+			if c, ok := event.(*eventlog.Compressed); ok && len(c.String) > 0 {
+				offset += c.IntervalMs * float64(len([]rune(c.String))-1)
+			}
+		}
+	}
+
+	return nil
+}
+
+// nullTime stores the zero time as NULL
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC()
+}
+
+// eventColumns holds the nullable, type-specific columns of an events row
+type eventColumns struct {
+	key      *string
+	content  *string
+	str      *string
+	selStart *int
+	selEnd   *int
+	interval *float64
+}
+
+// eventRow maps a typed event onto the events table columns
+func eventRow(event eventlog.Event) eventColumns {
+	switch e := event.(type) {
+	case *eventlog.Compressed:
+		return eventColumns{str: &e.String, interval: &e.IntervalMs}
+	case *eventlog.RawKey:
+		return eventColumns{key: &e.Key}
+	case *eventlog.RawSpecial:
+		return eventColumns{key: &e.Key}
+	case *eventlog.RawPaste:
+		return eventColumns{content: &e.Content}
+	case *eventlog.SelectionChange:
+		return eventColumns{selStart: &e.Start, selEnd: &e.End}
+	default:
+		return eventColumns{}
+	}
+}
+
+// GetSubmission retrieves a submission by exam ID and student ID
+func (s *sqlStore) GetSubmission(examID, studentID string) (*Submission, error) {
+	submissions, _, err := s.ListSubmissions(SubmissionFilter{ExamID: examID, StudentID: studentID}, Page{Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(submissions) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return submissions[0], nil
+}
+
+// ListSubmissions retrieves one page of the submissions matching filter
+// and the total number of matches
+func (s *sqlStore) ListSubmissions(filter SubmissionFilter, page Page) ([]*Submission, int, error) {
+	where, args := filter.where()
+
+	total, err := s.countSubmissions(where, args)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+	SELECT s.id, s.payload_json, r.revision, r.received_at, r.client_ip, r.session_id, r.started_at, r.timing_flags
+	` + submissionsFrom + where + page.orderBy()
+	query, args = page.limit(query, args)
+
+	rows, err := s.db.Query(s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query submissions: %w", err)
+	}
+	defer rows.Close()
+
+	var submissions []*Submission
+	byID := make(map[int64]*Submission)
+	for rows.Next() {
+		var id int64
+		var payloadJSON string
+		var receipt Submission
+		var startedAt sql.NullTime
+		var timingFlags string
+		if err := rows.Scan(&id, &payloadJSON, &receipt.Revision, &receipt.ReceivedAt, &receipt.ClientIP,
+			&receipt.SessionID, &startedAt, &timingFlags); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		var sub Submission
+		if err := json.Unmarshal([]byte(payloadJSON), &sub); err != nil {
+			return nil, 0, fmt.Errorf("failed to unmarshal payload: %w", err)
+		}
+		sub.Revision, sub.ReceivedAt, sub.ClientIP = receipt.Revision, receipt.ReceivedAt, receipt.ClientIP
+		sub.SessionID, sub.StartedAt = receipt.SessionID, startedAt.Time
+		sub.TimingFlags = splitFlags(timingFlags)
+
+		submissions = append(submissions, &sub)
+		byID[id] = &sub
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	results, err := s.loadQuestionResults(submissionIDs(byID))
+	if err != nil {
+		return nil, 0, err
+	}
+	for id, byKey := range results {
+		for key, result := range byKey {
+			if question := byID[id].Question(key); question != nil {
+				question.Integrity, question.Stats, question.Suspicion = result.integrity, result.stats, result.suspicion
+			}
+		}
+	}
+
+	return submissions, total, nil
+}
+
+// ListSummaries retrieves one page of the submissions matching filter, as
+// summaries read from the columns rather than the payload
+func (s *sqlStore) ListSummaries(filter SubmissionFilter, page Page) ([]Summary, int, error) {
+	where, args := filter.where()
+
+	total, err := s.countSubmissions(where, args)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+	SELECT s.id, s.exam_id, s.student_id, s.student_name, s.submission_time, r.revision, r.received_at, r.timing_flags
+	` + submissionsFrom + where + page.orderBy()
+	query, args = page.limit(query, args)
+
+	rows, err := s.db.Query(s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query submissions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	var summaries []Summary
+	for rows.Next() {
+		var id int64
+		var summary Summary
+		var timingFlags string
+		if err := rows.Scan(&id, &summary.ExamID, &summary.StudentID, &summary.StudentName,
+			&summary.SubmissionTime, &summary.Revision, &summary.ReceivedAt, &timingFlags); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+		summary.TimingFlags = splitFlags(timingFlags)
+		ids = append(ids, id)
+		summaries = append(summaries, summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	results, err := s.loadQuestionResults(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i, id := range ids {
+		for key, result := range results[id] {
+			if result.integrity.Verdict != "" {
+				if summaries[i].Integrity == nil {
+					summaries[i].Integrity = make(map[string]eventlog.Integrity)
+				}
+				summaries[i].Integrity[key] = result.integrity
+			}
+			if summaries[i].Stats == nil {
+				summaries[i].Stats = make(map[string]eventlog.Stats)
+				summaries[i].Suspicion = make(map[string]Suspicion)
+			}
+			summaries[i].Stats[key] = result.stats
+			summaries[i].Suspicion[key] = result.suspicion
+		}
+	}
+
+	marks, err := s.loadMarks(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i, id := range ids {
+		for _, mark := range marks[id] {
+			if summaries[i].Marks == nil {
+				summaries[i].Marks = make(map[string]QuestionMark)
+			}
+			summaries[i].Marks[mark.QuestionKey] = mark
+		}
+	}
+
+	return summaries, total, nil
+}
+
+// ListMetadataKeys retrieves the metadata fields of the current submissions
+// of an exam in alphabetical order
+func (s *sqlStore) ListMetadataKeys(examID string) ([]string, error) {
+	rows, err := s.db.Query(s.dialect.rebind(s.dialect.metadataKeys()), examID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metadata keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	// Sorted here, as the databases may collate differently
+	sort.Strings(keys)
+	return keys, rows.Err()
+}
+
+// submissionsFrom joins submissions (aliased s) with their current
+// revision (aliased r)
+const submissionsFrom = `
+	FROM submissions s
+	JOIN submission_revisions r ON r.id = s.current_revision_id
+	`
+
+// countSubmissions counts the submissions matching a where clause
+func (s *sqlStore) countSubmissions(where string, args []interface{}) (int, error) {
+	var total int
+	err := s.db.QueryRow(s.dialect.rebind(`SELECT COUNT(*)`+submissionsFrom+where), args...).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count submissions: %w", err)
+	}
+	return total, nil
+}
+
+// where returns the WHERE clause selecting the filtered submissions
+// (aliased s, current revision aliased r) and its arguments
+func (f SubmissionFilter) where() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if f.ExamID != "" {
+		conditions = append(conditions, "s.exam_id = ?")
+		args = append(args, f.ExamID)
+	}
+	if f.StudentID != "" {
+		conditions = append(conditions, "s.student_id = ?")
+		args = append(args, f.StudentID)
+	}
+	if f.StudentIDAfter != "" {
+		conditions = append(conditions, "s.student_id > ?")
+		args = append(args, f.StudentIDAfter)
+	}
+	if f.NameContains != "" {
+		conditions = append(conditions, `LOWER(s.student_name) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(strings.ToLower(f.NameContains))+"%")
+	}
+	if !f.SubmittedFrom.IsZero() {
+		conditions = append(conditions, "s.submission_time >= ?")
+		args = append(args, f.SubmittedFrom.UTC())
+	}
+	if !f.SubmittedTo.IsZero() {
+		conditions = append(conditions, "s.submission_time < ?")
+		args = append(args, f.SubmittedTo.UTC())
+	}
+	if f.PasteUsed != nil {
+		pasted := `EXISTS (
+		SELECT 1 FROM submission_questions q
+		WHERE q.submission_id = s.id AND q.paste_count > 0
+	)`
+		if !*f.PasteUsed {
+			pasted = "NOT " + pasted
+		}
+		conditions = append(conditions, pasted)
+	}
+	if f.Verdict != "" {
+		conditions = append(conditions, `EXISTS (
+		SELECT 1 FROM submission_questions q
+		WHERE q.submission_id = s.id AND q.integrity_verdict = ?
+	)`)
+		args = append(args, string(f.Verdict))
+	}
+	if f.Mark != "" {
+		conditions = append(conditions, `EXISTS (
+		SELECT 1 FROM question_marks m
+		WHERE m.submission_id = s.id AND m.mark = ?
+		AND `+currentMarks+`
+	)`)
+		args = append(args, string(f.Mark))
+	}
+	if f.Timing == TimingAny {
+		conditions = append(conditions, "r.timing_flags <> ''")
+	} else if f.Timing != "" {
+		conditions = append(conditions, "',' || r.timing_flags || ',' LIKE ?")
+		args = append(args, "%,"+string(f.Timing)+",%")
+	}
+	if f.MinSuspicion > 0 {
+		conditions = append(conditions, `EXISTS (
+		SELECT 1 FROM submission_questions q
+		WHERE q.submission_id = s.id AND q.suspicion_score >= ?
+	)`)
+		args = append(args, f.MinSuspicion)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conditions, "\n\tAND ") + "\n\t", args
+}
+
+// escapeLike escapes the LIKE wildcards in s, using \ as escape character
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// sortColumns maps sort fields to the columns they order by
+var sortColumns = map[SortField]string{
+	SortSubmissionTime: "s.submission_time",
+	SortReceivedAt:     "r.received_at",
+	SortStudentName:    "s.student_name",
+	SortStudentID:      "s.student_id",
+	SortExamID:         "s.exam_id",
+	// Unscored answers (NULL) sort below every score on both databases
+	SortSuspicion: "COALESCE((SELECT MAX(q.suspicion_score) FROM submission_questions q WHERE q.submission_id = s.id), -1)",
+}
+
+// orderBy returns the ORDER BY clause of the page, breaking ties by row
+// id so that pages never overlap
+func (p Page) orderBy() string {
+	column, ok := sortColumns[p.Sort]
+	if !ok {
+		column = sortColumns[SortSubmissionTime]
+	}
+
+	direction := "ASC"
+	if p.Descending {
+		direction = "DESC"
+	}
+	return "ORDER BY " + column + " " + direction + ", s.id " + direction + "\n\t"
+}
+
+// limit appends the page window to query and args
+func (p Page) limit(query string, args []interface{}) (string, []interface{}) {
+	if p.Limit <= 0 {
+		return query, args
+	}
+	return query + "LIMIT ? OFFSET ?\n\t", append(args, p.Limit, p.Offset)
+}
+
+// questionResult holds the server-computed columns of a stored question
+type questionResult struct {
+	integrity eventlog.Integrity
+	stats     eventlog.Stats
+	suspicion Suspicion
+}
+
+// loadQuestionResults loads the stored integrity verdicts, statistics and
+// suspicion scores of the given submissions, keyed by submission id and question key
+func (s *sqlStore) loadQuestionResults(ids []int64) (map[int64]map[string]questionResult, error) {
+	results := make(map[int64]map[string]questionResult, len(ids))
+	if len(ids) == 0 {
+		return results, nil
+	}
+
+	placeholders, args := inList(ids)
+	query := `
+	SELECT submission_id, question_key, integrity_verdict, integrity_diff_offset, integrity_detail,
+		paste_count, pasted_chars, paste_share, backspace_count, selection_changes,
+		active_typing_ms, longest_pause_ms, suspicion_score, suspicion_rules
+	FROM submission_questions
+	WHERE submission_id IN (` + placeholders + `)
+	`
+
+	rows, err := s.db.Query(s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query question results: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var questionKey, verdict, detail, rules string
+		var diffOffset sql.NullInt64
+		var score sql.NullFloat64
+		var stats eventlog.Stats
+		var suspicion Suspicion
+		if err := rows.Scan(&id, &questionKey, &verdict, &diffOffset, &detail,
+			&stats.PasteCount, &stats.PastedChars, &stats.PasteShare, &stats.BackspaceCount, &stats.SelectionChanges,
+			&stats.ActiveTypingMs, &stats.LongestPauseMs, &score, &rules); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		suspicion.Score, suspicion.Unscored = score.Float64, !score.Valid
+		suspicion.Rules = splitRules(rules)
+
+		result := questionResult{stats: stats, suspicion: suspicion}
+		if verdict != "" {
+			result.integrity = eventlog.Integrity{Verdict: eventlog.Verdict(verdict), Detail: detail}
+			if diffOffset.Valid {
+				offset := int(diffOffset.Int64)
+				result.integrity.DiffOffset = &offset
+			}
+		}
+
+		if results[id] == nil {
+			results[id] = make(map[string]questionResult)
+		}
+		results[id][questionKey] = result
+	}
+
+	return results, rows.Err()
+}
+
+// inList returns the placeholders and arguments of an IN (...) list of ids
+func inList[T any](values []T) (string, []interface{}) {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", "), args
+}
+
+// submissionIDs returns the keys of a map of submissions by id
+func submissionIDs(byID map[int64]*Submission) []int64 {
+	ids := make([]int64, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// DeleteSubmission removes a submission together with its revisions,
+// questions and events
+func (s *sqlStore) DeleteSubmission(examID, studentID string) error {
+	result, err := s.db.Exec(s.dialect.rebind(`DELETE FROM submissions WHERE exam_id = ? AND student_id = ?`), examID, studentID)
+	if err != nil {
+		return fmt.Errorf("failed to delete submission: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete submission: %w", err)
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Close closes the database connection
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"backend/internal/eventlog"
+)
+
+// ErrNotFound is returned when a requested record does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrExists is returned when creating a record that already exists
+var ErrExists = errors.New("already exists")
+
+// Drivers accepted by Open
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMemory   = "memory"
+)
+
+// Store persists submissions and their revisions
+type Store interface {
+	// SaveSubmission stores sub as a new revision and makes it current,
+	// setting sub.Revision to the stored revision number
+	SaveSubmission(sub *Submission) error
+	// GetSubmission returns the current revision of a student's submission
+	GetSubmission(examID, studentID string) (*Submission, error)
+	// ListSubmissions returns one page of the current revisions matching
+	// filter and the number of submissions matching it in total
+	ListSubmissions(filter SubmissionFilter, page Page) ([]*Submission, int, error)
+	// ListSummaries is ListSubmissions without decoding payloads
+	ListSummaries(filter SubmissionFilter, page Page) ([]Summary, int, error)
+	// ListMetadataKeys returns the metadata fields of the current
+	// submissions of an exam in alphabetical order
+	ListMetadataKeys(examID string) ([]string, error)
+	// DeleteSubmission removes a student's submission and all its revisions
+	DeleteSubmission(examID, studentID string) error
+
+	// ListRevisions returns every revision of a student's submission, oldest first
+	ListRevisions(examID, studentID string) ([]Revision, error)
+	// GetRevision returns one revision of a student's submission and its payload
+	GetRevision(examID, studentID string, revision int) (*Revision, *Submission, error)
+
+	// SaveMark records mark as the current mark of a question of a
+	// student's current submission, setting mark.Revision
+	SaveMark(examID, studentID string, mark *QuestionMark) error
+	// ListMarks returns the current mark of every marked question of a
+	// student's submission
+	ListMarks(examID, studentID string) ([]QuestionMark, error)
+	// ListExamMarks returns the current marks of the submissions of the
+	// given students to an exam, keyed by student ID
+	ListExamMarks(examID string, studentIDs []string) (map[string][]QuestionMark, error)
+	// MarkHistory returns every mark given to a question, oldest first
+	MarkHistory(examID, studentID, questionKey string) ([]QuestionMark, error)
+
+	// SaveSuspicion replaces the suspicion of questions of a student's
+	// current submission, by question key
+	SaveSuspicion(examID, studentID string, suspicions map[string]Suspicion) error
+
+	// SaveExam creates or replaces an exam definition
+	SaveExam(exam *Exam) error
+	// GetExam returns an exam by id
+	GetExam(id string) (*Exam, error)
+	// ListExams returns every exam ordered by id
+	ListExams() ([]Exam, error)
+	// DeleteExam removes an exam without submissions, or returns ErrInUse
+	DeleteExam(id string) error
+
+	// CreateExamSession registers a server-started exam session
+	CreateExamSession(session *ExamSession) error
+	// GetExamSession returns a registered exam session by id
+	GetExamSession(id string) (*ExamSession, error)
+	// FirstExamSession returns the earliest started exam session of a
+	// student for an exam
+	FirstExamSession(examID, studentID string) (*ExamSession, error)
+	// ExamSessionSubmitted reports whether an answer was submitted in an
+	// exam session
+	ExamSessionSubmitted(id string) (bool, error)
+	// SaveChunk stores an autosaved chunk of an exam session, or returns
+	// ErrExists when its question already has a chunk with its Seq
+	SaveChunk(chunk *Chunk) error
+	// ListChunks returns the autosaved chunks of an exam session ordered
+	// by question index and Seq
+	ListChunks(sessionID string) ([]Chunk, error)
+	// ListQuestionChunks returns the autosaved chunks of a question of an
+	// exam session ordered by Seq
+	ListQuestionChunks(sessionID string, questionIndex int) ([]Chunk, error)
+	// LastChunk returns the autosaved chunk with the highest Seq of a
+	// question of an exam session, or ErrNotFound before its first chunk
+	LastChunk(sessionID string, questionIndex int) (*Chunk, error)
+
+	// SaveQuestion stores q as the next version of question q.ID, setting
+	// q.Version; new questions are active
+	SaveQuestion(q *BankQuestion) error
+	// GetQuestion returns one version of a question, or the current one
+	// when version is 0
+	GetQuestion(id, version int) (*BankQuestion, error)
+	// ListQuestions returns the current version of every question by ID
+	ListQuestions() ([]BankQuestion, error)
+	// QuestionVersions returns every version of a question, oldest first
+	QuestionVersions(id int) ([]BankQuestion, error)
+	// SetQuestionActive retires or restores a question
+	SetQuestionActive(id int, active bool) error
+
+	// CreateUser stores a new user, or returns ErrExists
+	CreateUser(user *User) error
+	// GetUser returns a user by username
+	GetUser(username string) (*User, error)
+	// ListUsers returns every user ordered by username
+	ListUsers() ([]User, error)
+	// UpdateUser replaces a user's password hash and role and ends its sessions
+	UpdateUser(user *User) error
+	// DeleteUser removes a user and its sessions
+	DeleteUser(username string) error
+
+	// CreateSession stores a session for session.User
+	CreateSession(session *Session) error
+	// GetSession returns an unexpired session by token hash
+	GetSession(tokenHash string) (*Session, error)
+	// DeleteSession removes a session
+	DeleteSession(tokenHash string) error
+
+	// Migrate applies pending schema migrations and returns the ones applied
+	Migrate() ([]MigrationStatus, error)
+	// Migrations reports every known migration and whether it was applied
+	Migrations() ([]MigrationStatus, error)
+
+	Close() error
+}
+
+// SubmissionFilter selects submissions; zero fields match everything
+type SubmissionFilter struct {
+	ExamID    string
+	StudentID string
+	// StudentIDAfter selects submissions whose student ID sorts after this
+	// one, for reading an exam page by page in SortStudentID order
+	StudentIDAfter string
+	// NameContains matches a substring of the student name, ignoring case
+	NameContains string
+	// SubmittedFrom (inclusive) and SubmittedTo (exclusive) bound the
+	// client-reported submission time
+	SubmittedFrom time.Time
+	SubmittedTo   time.Time
+	// PasteUsed selects submissions with (true) or without (false) a
+	// paste in any question
+	PasteUsed *bool
+	// Verdict selects submissions with at least one question of this
+	// integrity verdict
+	Verdict eventlog.Verdict
+	// Mark selects submissions with at least one question whose current
+	// evaluator mark is this one
+	Mark Mark
+	// Timing selects submissions whose current revision has this timing
+	// flag, or any flag for TimingAny
+	Timing TimingFlag
+	// MinSuspicion selects submissions with at least one question whose
+	// suspicion score is at least this one
+	MinSuspicion float64
+}
+
+// SortField orders listed submissions
+type SortField string
+
+// Sort fields accepted by Page
+const (
+	SortSubmissionTime SortField = "submissionTime"
+	SortReceivedAt     SortField = "receivedAt"
+	SortStudentName    SortField = "studentName"
+	SortStudentID      SortField = "studentId"
+	SortExamID         SortField = "examId"
+	// SortSuspicion orders by the highest suspicion score of any question
+	SortSuspicion SortField = "suspicion"
+)
+
+// SortFields lists the valid sort fields
+var SortFields = []SortField{SortSubmissionTime, SortReceivedAt, SortStudentName, SortStudentID, SortExamID, SortSuspicion}
+
+// Page selects the order and window of listed submissions
+type Page struct {
+	// Sort defaults to SortSubmissionTime
+	Sort       SortField
+	Descending bool
+	// Limit caps the number of submissions returned; 0 returns all of
+	// them and ignores Offset
+	Limit  int
+	Offset int
+}
+
+// Summary is the listing view of a submission's current revision,
+// read without decoding its payload
+type Summary struct {
+	ExamID         string
+	StudentID      string
+	StudentName    string
+	SubmissionTime time.Time
+	Revision       int
+	ReceivedAt     time.Time
+	// Integrity holds the replay verdict per question key
+	Integrity map[string]eventlog.Integrity
+	// Stats holds the typing statistics per question key
+	Stats map[string]eventlog.Stats
+	// Suspicion holds the suspicion score per question key
+	Suspicion map[string]Suspicion
+	// Marks holds the current evaluator mark per marked question key
+	Marks map[string]QuestionMark
+	// TimingFlags lists the exam limits the revision was received outside of
+	TimingFlags []TimingFlag
+}
+
+// PasteUsed reports whether any question of the summary contains a paste
+func (s *Summary) PasteUsed() bool {
+	for _, stats := range s.Stats {
+		if stats.PasteUsed() {
+			return true
+		}
+	}
+	return false
+}
+
+// Open opens the store for driver without touching its schema. dsn is the
+// database file for sqlite and the connection URL for postgres; the
+// memory store ignores it.
+func Open(driver, dsn string) (Store, error) {
+	switch driver {
+	case DriverSQLite:
+		return OpenSQLiteStorage(dsn)
+	case DriverPostgres:
+		return OpenPostgresStorage(dsn)
+	case DriverMemory:
+		return NewMemoryStorage(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/eventlog"
+)
+
+// Payload versions accepted by /submit
+const (
+	// VersionLegacy carries questions as sibling keys q1, q2, ...
+	VersionLegacy = 1
+	// VersionQuestions carries questions as an ordered "questions" array
+	VersionQuestions = 2
+)
+
+// Submission represents the exam submission data
+type Submission struct {
+	Version        int
+	ExamID         string
+	StudentID      string
+	SubmissionTime time.Time
+	Metadata       map[string]string
+	Questions      []Question
+
+	// Receipt of the stored revision, set by the server and never read
+	// from the payload
+	Revision   int
+	ReceivedAt time.Time
+	ClientIP   string
+	// SessionID is the exam session the revision was submitted in, and
+	// StartedAt the server time that session started; both are empty for
+	// submissions made without a session
+	SessionID string
+	StartedAt time.Time
+	// TimingFlags lists the exam limits the revision was received outside of
+	TimingFlags []TimingFlag
+}
+
+// Question holds one tracked answer and the event log that produced it
+type Question struct {
+	// Key identifies the question within the submission: the payload key
+	// (q1, q2, ...) for legacy payloads, the questionId otherwise
+	Key           string       `json:"-"`
+	QuestionID    string       `json:"questionId,omitempty"`
+	QuestionIndex int          `json:"questionIndex"`
+	QuestionTitle string       `json:"questionTitle"`
+	Question      string       `json:"question"`
+	FinalAnswer   string       `json:"finalAnswer"`
+	StartTimeMs   float64      `json:"startTime_ms"`
+	EndTimeMs     float64      `json:"endTime_ms"`
+	EventLog      eventlog.Log `json:"eventLog"`
+
+	// Integrity, Stats and Suspicion are computed by the server and never
+	// read from the payload
+	Integrity eventlog.Integrity `json:"-"`
+	Stats     eventlog.Stats     `json:"-"`
+	Suspicion Suspicion          `json:"-"`
+}
+
+// StudentName returns the student name from the metadata
+func (s *Submission) StudentName() string {
+	return s.Metadata["studentName"]
+}
+
+// Question returns the question submitted under key, or nil
+func (s *Submission) Question(key string) *Question {
+	for i := range s.Questions {
+		if s.Questions[i].Key == key {
+			return &s.Questions[i]
+		}
+	}
+	return nil
+}
+
+// IsQuestionKey reports whether a legacy payload key holds a question
+// (q1, q2, ..., q10, ...)
+func IsQuestionKey(key string) bool {
+	return questionNumber(key) > 0
+}
+
+// questionNumber returns n for a legacy key "qn", or 0 if key is not one
+func questionNumber(key string) int {
+	if len(key) < 2 || key[0] != 'q' || key[1] == '0' {
+		return 0
+	}
+	n, err := strconv.Atoi(key[1:])
+	if err != nil || n <= 0 || strconv.Itoa(n) != key[1:] {
+		return 0
+	}
+	return n
+}
+
+// UnmarshalJSON decodes a submission payload strictly: unknown top-level
+// keys, unknown question fields and malformed events are rejected
+func (s *Submission) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if fields == nil {
+		return fmt.Errorf("submission must be an object")
+	}
+
+	sub := Submission{Version: VersionLegacy}
+	if raw, ok := fields["version"]; ok {
+		if err := strictUnmarshal(raw, &sub.Version); err != nil {
+			return fmt.Errorf("version: %w", err)
+		}
+		if sub.Version != VersionLegacy && sub.Version != VersionQuestions {
+			return fmt.Errorf("version: unsupported payload version %d", sub.Version)
+		}
+	}
+
+	for key, raw := range fields {
+		var err error
+		switch key {
+		case "version":
+			continue
+		case "questions":
+			if sub.Version != VersionQuestions {
+				return fmt.Errorf("questions: requires version %d", VersionQuestions)
+			}
+			err = sub.unmarshalQuestions(raw)
+		case "examId":
+			err = strictUnmarshal(raw, &sub.ExamID)
+		case "studentId":
+			err = strictUnmarshal(raw, &sub.StudentID)
+		case "submissionTime":
+			err = strictUnmarshal(raw, &sub.SubmissionTime)
+		case "metadata":
+			err = strictUnmarshal(raw, &sub.Metadata)
+		default:
+			if !IsQuestionKey(key) {
+				return fmt.Errorf("unknown field %q", key)
+			}
+			if sub.Version != VersionLegacy {
+				return fmt.Errorf("%s: question keys are only accepted in version %d payloads", key, VersionLegacy)
+			}
+			var question Question
+			err = strictUnmarshal(raw, &question)
+			question.Key = key
+			sub.Questions = append(sub.Questions, question)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	// Legacy keys carry the order in their number (q1, q2, ..., q10)
+	if sub.Version == VersionLegacy {
+		sort.Slice(sub.Questions, func(i, j int) bool {
+			return questionNumber(sub.Questions[i].Key) < questionNumber(sub.Questions[j].Key)
+		})
+	}
+
+	*s = sub
+	return nil
+}
+
+// unmarshalQuestions decodes the ordered "questions" array, keying each
+// question by its required, unique questionId
+func (s *Submission) unmarshalQuestions(raw json.RawMessage) error {
+	var questions []Question
+	if err := strictUnmarshal(raw, &questions); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(questions))
+	for i := range questions {
+		id := questions[i].QuestionID
+		if id == "" {
+			return fmt.Errorf("question %d: questionId is required", i)
+		}
+		if strings.Contains(id, "/") {
+			return fmt.Errorf("question %d: questionId must not contain '/'", i)
+		}
+		if seen[id] {
+			return fmt.Errorf("question %d: duplicate questionId %q", i, id)
+		}
+		seen[id] = true
+		questions[i].Key = id
+	}
+
+	s.Questions = questions
+	return nil
+}
+
+// MarshalJSON encodes the submission in the payload layout it was received in
+func (s Submission) MarshalJSON() ([]byte, error) {
+	return s.MarshalJSONWith(func(question Question) interface{} { return question })
+}
+
+// MarshalJSONWith encodes the submission like MarshalJSON, encoding each
+// question as the value returned by encode
+func (s Submission) MarshalJSONWith(encode func(Question) interface{}) ([]byte, error) {
+	payload := map[string]interface{}{
+		"examId":         s.ExamID,
+		"studentId":      s.StudentID,
+		"submissionTime": s.SubmissionTime,
+		"metadata":       s.Metadata,
+	}
+
+	if s.Version == VersionQuestions {
+		payload["version"] = s.Version
+		questions := make([]interface{}, 0, len(s.Questions))
+		for _, question := range s.Questions {
+			questions = append(questions, encode(question))
+		}
+		payload["questions"] = questions
+	} else {
+		for _, question := range s.Questions {
+			payload[question.Key] = encode(question)
+		}
+	}
+
+	return json.Marshal(payload)
+}
+
+// UnmarshalJSON decodes a question strictly, requiring finalAnswer and eventLog
+func (q *Question) UnmarshalJSON(data []byte) error {
+	type wireQuestion struct {
+		QuestionID    string        `json:"questionId"`
+		QuestionIndex *int          `json:"questionIndex"`
+		QuestionTitle string        `json:"questionTitle"`
+		Question      string        `json:"question"`
+		FinalAnswer   *string       `json:"finalAnswer"`
+		StartTimeMs   float64       `json:"startTime_ms"`
+		EndTimeMs     float64       `json:"endTime_ms"`
+		EventLog      *eventlog.Log `json:"eventLog"`
+	}
+
+	var w wireQuestion
+	if err := strictUnmarshal(data, &w); err != nil {
+		return err
+	}
+	if w.QuestionIndex == nil {
+		return fmt.Errorf("questionIndex is required")
+	}
+	if w.FinalAnswer == nil {
+		return fmt.Errorf("finalAnswer is required")
+	}
+	if w.EventLog == nil {
+		return fmt.Errorf("eventLog is required")
+	}
+
+	*q = Question{
+		QuestionID:    w.QuestionID,
+		QuestionIndex: *w.QuestionIndex,
+		QuestionTitle: w.QuestionTitle,
+		Question:      w.Question,
+		FinalAnswer:   *w.FinalAnswer,
+		StartTimeMs:   w.StartTimeMs,
+		EndTimeMs:     w.EndTimeMs,
+		EventLog:      *w.EventLog,
+	}
+	return nil
+}
+
+// strictUnmarshal decodes data into v, rejecting unknown object fields
+func strictUnmarshal(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Suspicion is the score of an answer under the suspicion rules and the
+// names of the rules it triggered
+type Suspicion struct {
+	Score float64  `json:"score"`
+	Rules []string `json:"rules"`
+	// Unscored is set for answers no rules were applied to: those stored
+	// without a rules file or before scoring existed, until drkka rescore.
+	// Their score is 0 but they match no minSuspicion filter and sort
+	// below every scored answer.
+	Unscored bool `json:"unscored,omitempty"`
+}
+
+// column returns the suspicion_score column value, NULL when unscored
+func (s Suspicion) column() interface{} {
+	if s.Unscored {
+		return nil
+	}
+	return s.Score
+}
+
+// SaveSuspicion replaces the suspicion of questions of a student's current
+// submission, by question key, e.g. after the rules changed
+func (s *sqlStore) SaveSuspicion(examID, studentID string, suspicions map[string]Suspicion) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var submissionID int64
+	err = tx.QueryRow(s.dialect.rebind(`SELECT id FROM submissions WHERE exam_id = ? AND student_id = ?`),
+		examID, studentID).Scan(&submissionID)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find submission: %w", err)
+	}
+
+	for key, suspicion := range suspicions {
+		result, err := tx.Exec(s.dialect.rebind(`
+		UPDATE submission_questions SET suspicion_score = ?, suspicion_rules = ?
+		WHERE submission_id = ? AND question_key = ?
+		`), suspicion.column(), strings.Join(suspicion.Rules, ","), submissionID, key)
+		if err != nil {
+			return fmt.Errorf("failed to save suspicion of question %s: %w", key, err)
+		}
+		if n, err := result.RowsAffected(); err == nil && n == 0 {
+			return ErrNotFound
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit suspicion: %w", err)
+	}
+	return nil
+}
+
+// splitRules decodes the suspicion_rules column
+func splitRules(column string) []string {
+	if column == "" {
+		return []string{}
+	}
+	return strings.Split(column, ",")
+}
@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Role decides what a user may do
+type Role string
+
+// Roles a user can have
+const (
+	// RoleAdmin manages users and may do everything an evaluator does
+	RoleAdmin Role = "admin"
+	// RoleEvaluator lists, reads and marks submissions
+	RoleEvaluator Role = "evaluator"
+	// RoleStudent may only submit
+	RoleStudent Role = "student"
+)
+
+// Roles lists the valid roles
+var Roles = []Role{RoleAdmin, RoleEvaluator, RoleStudent}
+
+// Valid reports whether r is one of Roles
+func (r Role) Valid() bool {
+	for _, known := range Roles {
+		if r == known {
+			return true
+		}
+	}
+	return false
+}
+
+// User is a local account
+type User struct {
+	Username string `json:"username"`
+	// PasswordHash is the bcrypt hash of the password
+	PasswordHash string    `json:"-"`
+	Role         Role      `json:"role"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// Session is a signed-in user. Only the SHA-256 hash of its token is
+// stored, so a leaked database does not leak usable tokens.
+type Session struct {
+	TokenHash string
+	User      User
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// CreateUser stores a new user, returning ErrExists if the username is taken
+func (s *sqlStore) CreateUser(user *User) error {
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now().UTC()
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow(s.dialect.rebind(`SELECT EXISTS (SELECT 1 FROM users WHERE username = ?)`), user.Username).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check user: %w", err)
+	}
+	if exists {
+		return ErrExists
+	}
+
+	_, err = tx.Exec(s.dialect.rebind(`
+	INSERT INTO users (username, password_hash, role, created_at)
+	VALUES (?, ?, ?, ?)
+	`), user.Username, user.PasswordHash, string(user.Role), user.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save user: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user: %w", err)
+	}
+	return nil
+}
+
+// GetUser retrieves a user by username
+func (s *sqlStore) GetUser(username string) (*User, error) {
+	var user User
+	var role string
+	err := s.db.QueryRow(s.dialect.rebind(`
+	SELECT username, password_hash, role, created_at FROM users WHERE username = ?
+	`), username).Scan(&user.Username, &user.PasswordHash, &role, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	user.Role = Role(role)
+	return &user, nil
+}
+
+// ListUsers retrieves every user ordered by username
+func (s *sqlStore) ListUsers() ([]User, error) {
+	rows, err := s.db.Query(`SELECT username, password_hash, role, created_at FROM users ORDER BY username`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var user User
+		var role string
+		if err := rows.Scan(&user.Username, &user.PasswordHash, &role, &user.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		user.Role = Role(role)
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// UpdateUser replaces the password hash and role of an existing user and
+// signs it out everywhere
+func (s *sqlStore) UpdateUser(user *User) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(s.dialect.rebind(`UPDATE users SET password_hash = ?, role = ? WHERE username = ?`),
+		user.PasswordHash, string(user.Role), user.Username)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if updated == 0 {
+		return ErrNotFound
+	}
+
+	_, err = tx.Exec(s.dialect.rebind(`
+	DELETE FROM sessions WHERE user_id = (SELECT id FROM users WHERE username = ?)
+	`), user.Username)
+	if err != nil {
+		return fmt.Errorf("failed to clear sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user: %w", err)
+	}
+	return nil
+}
+
+// DeleteUser removes a user together with its sessions
+func (s *sqlStore) DeleteUser(username string) error {
+	result, err := s.db.Exec(s.dialect.rebind(`DELETE FROM users WHERE username = ?`), username)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreateSession stores a session for session.User, discarding every
+// expired session on the way
+func (s *sqlStore) CreateSession(session *Session) error {
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now().UTC()
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM sessions WHERE expires_at <= ?`), session.CreatedAt.UTC()); err != nil {
+		return fmt.Errorf("failed to clear expired sessions: %w", err)
+	}
+
+	result, err := tx.Exec(s.dialect.rebind(`
+	INSERT INTO sessions (token_hash, user_id, created_at, expires_at)
+	SELECT ?, id, ?, ? FROM users WHERE username = ?
+	`), session.TokenHash, session.CreatedAt.UTC(), session.ExpiresAt.UTC(), session.User.Username)
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	if inserted == 0 {
+		return ErrNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit session: %w", err)
+	}
+	return nil
+}
+
+// GetSession retrieves an unexpired session and its user by token hash
+func (s *sqlStore) GetSession(tokenHash string) (*Session, error) {
+	var session Session
+	var role string
+	err := s.db.QueryRow(s.dialect.rebind(`
+	SELECT se.token_hash, se.created_at, se.expires_at, u.username, u.password_hash, u.role, u.created_at
+	FROM sessions se
+	JOIN users u ON u.id = se.user_id
+	WHERE se.token_hash = ? AND se.expires_at > ?
+	`), tokenHash, time.Now().UTC()).Scan(&session.TokenHash, &session.CreatedAt, &session.ExpiresAt,
+		&session.User.Username, &session.User.PasswordHash, &role, &session.User.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve session: %w", err)
+	}
+	session.User.Role = Role(role)
+	return &session, nil
+}
+
+// DeleteSession removes a session; removing an unknown one is not an error
+func (s *sqlStore) DeleteSession(tokenHash string) error {
+	if _, err := s.db.Exec(s.dialect.rebind(`DELETE FROM sessions WHERE token_hash = ?`), tokenHash); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+// Package suspicion scores submitted answers with configurable rules, so
+// evaluators can start with the replays most likely to show copying. The
+// rules, their thresholds and weights come from a JSON file; the score of
+// an answer is the sum of the weights of the rules it triggers.
+package suspicion
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"backend/internal/storage"
+)
+
+// Kind names a check a rule applies
+type Kind string
+
+// Rule kinds and the parameters each one requires
+const (
+	// KindLargePaste: a single paste of at least minChars characters
+	// makes up at least minShare of the final answer
+	KindLargePaste Kind = "large_paste"
+	// KindUniformIntervals: at least minKeystrokes keystrokes whose gaps
+	// vary less than maxVariation (standard deviation over mean)
+	KindUniformIntervals Kind = "uniform_intervals"
+	// KindNoCorrections: at least minTypedChars characters typed with a
+	// correction ratio of at most maxCorrectionRatio
+	KindNoCorrections Kind = "no_corrections"
+	// KindIdleBurst: after a pause of at least minIdleMs, at least
+	// minBurstChars characters typed or pasted within burstMs
+	KindIdleBurst Kind = "idle_burst"
+	// KindNotReproducible: replaying the event log does not produce the
+	// final answer
+	KindNotReproducible Kind = "not_reproducible"
+)
+
+// params lists the parameters of each kind
+var params = map[Kind][]string{
+	KindLargePaste:       {"minChars", "minShare"},
+	KindUniformIntervals: {"minKeystrokes", "maxVariation"},
+	KindNoCorrections:    {"minTypedChars", "maxCorrectionRatio"},
+	KindIdleBurst:        {"minIdleMs", "minBurstChars", "burstMs"},
+	KindNotReproducible:  {},
+}
+
+// Rule is one entry of the rules file
+type Rule struct {
+	// Name identifies the rule in stored scores; it may not contain commas
+	Name   string             `json:"name"`
+	Kind   Kind               `json:"kind"`
+	Weight float64            `json:"weight"`
+	Params map[string]float64 `json:"params"`
+}
+
+// Rules is the rules file
+type Rules struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Load reads and validates a rules file
+func Load(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates rules: names must be unique, kinds known,
+// weights positive and every parameter of the kind given, and no other
+func Parse(data []byte) (*Rules, error) {
+	var rules Rules
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("failed to parse suspicion rules: %w", err)
+	}
+
+	seen := make(map[string]bool, len(rules.Rules))
+	for i, rule := range rules.Rules {
+		if rule.Name == "" || strings.Contains(rule.Name, ",") {
+			return nil, fmt.Errorf("rule %d: name must be non-empty and without commas", i)
+		}
+		if seen[rule.Name] {
+			return nil, fmt.Errorf("rule %s: duplicate name", rule.Name)
+		}
+		seen[rule.Name] = true
+
+		names, ok := params[rule.Kind]
+		if !ok {
+			return nil, fmt.Errorf("rule %s: unknown kind %q, expected one of %s", rule.Name, rule.Kind, strings.Join(Kinds(), ", "))
+		}
+		if rule.Weight <= 0 {
+			return nil, fmt.Errorf("rule %s: weight must be positive", rule.Name)
+		}
+		for _, name := range names {
+			if _, ok := rule.Params[name]; !ok {
+				return nil, fmt.Errorf("rule %s: missing parameter %s", rule.Name, name)
+			}
+		}
+		if len(rule.Params) != len(names) {
+			return nil, fmt.Errorf("rule %s: %s rules take the parameters %s", rule.Name, rule.Kind, strings.Join(names, ", "))
+		}
+	}
+	return &rules, nil
+}
+
+// MaxScore is the score of an answer triggering every rule
+func (r *Rules) MaxScore() float64 {
+	total := 0.0
+	for _, rule := range r.Rules {
+		total += rule.Weight
+	}
+	return total
+}
+
+// Score applies the rules to a question whose integrity verdict is set.
+// Triggered rule names are listed in the order of the file; nil rules
+// leave the question unscored.
+func (r *Rules) Score(question *storage.Question) storage.Suspicion {
+	suspicion := storage.Suspicion{Rules: []string{}}
+	if r == nil {
+		suspicion.Unscored = true
+		return suspicion
+	}
+
+	signals := measure(question)
+	for _, rule := range r.Rules {
+		if signals.triggers(rule) {
+			suspicion.Score += rule.Weight
+			suspicion.Rules = append(suspicion.Rules, rule.Name)
+		}
+	}
+	return suspicion
+}
+
+// Kinds lists the rule kinds, for messages
+func Kinds() []string {
+	kinds := make([]string, 0, len(params))
+	for kind := range params {
+		kinds = append(kinds, string(kind))
+	}
+	sort.Strings(kinds)
+	return kinds
+}
@@ -0,0 +1,190 @@
+package suspicion
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"backend/internal/eventlog"
+	"backend/internal/storage"
+)
+
+func TestLoadShippedRules(t *testing.T) {
+	rules, err := Load("../../suspicion_rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := map[Kind]bool{}
+	for _, rule := range rules.Rules {
+		kinds[rule.Kind] = true
+	}
+	if len(kinds) != len(params) {
+		t.Errorf("shipped rules use kinds %v, want all of %v", kinds, Kinds())
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		// err is part of the expected error, empty when the rules are valid
+		err string
+	}{
+		{"large paste", `{"rules": [{"name": "r", "kind": "large_paste", "weight": 1, "params": {"minChars": 40, "minShare": 0.5}}]}`, ""},
+		{"uniform intervals", `{"rules": [{"name": "r", "kind": "uniform_intervals", "weight": 1, "params": {"minKeystrokes": 50, "maxVariation": 0.15}}]}`, ""},
+		{"no corrections", `{"rules": [{"name": "r", "kind": "no_corrections", "weight": 1, "params": {"minTypedChars": 200, "maxCorrectionRatio": 0}}]}`, ""},
+		{"idle burst", `{"rules": [{"name": "r", "kind": "idle_burst", "weight": 1, "params": {"minIdleMs": 60000, "minBurstChars": 100, "burstMs": 10000}}]}`, ""},
+		{"not reproducible", `{"rules": [{"name": "r", "kind": "not_reproducible", "weight": 1, "params": {}}]}`, ""},
+		{"no rules", `{"rules": []}`, ""},
+
+		{"unknown kind", `{"rules": [{"name": "r", "kind": "typing_speed", "weight": 1, "params": {}}]}`, "unknown kind"},
+		{"missing parameter", `{"rules": [{"name": "r", "kind": "large_paste", "weight": 1, "params": {"minChars": 40}}]}`, "missing parameter minShare"},
+		{"extra parameter", `{"rules": [{"name": "r", "kind": "not_reproducible", "weight": 1, "params": {"minChars": 40}}]}`, "take the parameters"},
+		{"zero weight", `{"rules": [{"name": "r", "kind": "not_reproducible", "weight": 0, "params": {}}]}`, "weight must be positive"},
+		{"empty name", `{"rules": [{"name": "", "kind": "not_reproducible", "weight": 1, "params": {}}]}`, "name must be non-empty"},
+		{"comma in name", `{"rules": [{"name": "a,b", "kind": "not_reproducible", "weight": 1, "params": {}}]}`, "without commas"},
+		{"duplicate name", `{"rules": [
+			{"name": "r", "kind": "not_reproducible", "weight": 1, "params": {}},
+			{"name": "r", "kind": "not_reproducible", "weight": 2, "params": {}}
+		]}`, "duplicate name"},
+		{"unknown field", `{"rules": [{"name": "r", "kind": "not_reproducible", "weight": 1, "params": {}, "enabled": true}]}`, "unknown field"},
+		{"not JSON", `rules: []`, "failed to parse"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rules, err := Parse([]byte(tc.json))
+			switch {
+			case tc.err == "" && err != nil:
+				t.Errorf("Parse() error = %v", err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Errorf("Parse() = %+v, %v; want an error containing %q", rules, err, tc.err)
+			}
+		})
+	}
+}
+
+// typedAt types s one character every intervalMs
+func typedAt(s string, intervalMs float64) eventlog.Event {
+	return &eventlog.Compressed{String: s, IntervalMs: intervalMs}
+}
+
+func TestScore(t *testing.T) {
+	rule := func(kind Kind, params map[string]float64) *Rules {
+		return &Rules{Rules: []Rule{{Name: "r", Kind: kind, Weight: 10, Params: params}}}
+	}
+	largePaste := rule(KindLargePaste, map[string]float64{"minChars": 10, "minShare": 0.5})
+	uniform := rule(KindUniformIntervals, map[string]float64{"minKeystrokes": 10, "maxVariation": 0.15})
+	noCorrections := rule(KindNoCorrections, map[string]float64{"minTypedChars": 10, "maxCorrectionRatio": 0})
+	idleBurst := rule(KindIdleBurst, map[string]float64{"minIdleMs": 60000, "minBurstChars": 10, "burstMs": 1000})
+	notReproducible := rule(KindNotReproducible, map[string]float64{})
+
+	// uneven types 12 characters with gaps of 100 and 300 ms
+	uneven := eventlog.Log{typedAt("abc", 100), typedAt("def", 300), typedAt("ghi", 100), typedAt("jkl", 300)}
+
+	tests := []struct {
+		name      string
+		rules     *Rules
+		question  storage.Question
+		triggered bool
+	}{
+		{"large paste", largePaste, storage.Question{
+			FinalAnswer: "ab0123456789",
+			EventLog:    eventlog.Log{typedAt("ab", 100), &eventlog.RawPaste{Content: "0123456789"}},
+		}, true},
+		{"short paste", largePaste, storage.Question{
+			FinalAnswer: "abcdefghijklmnopqrst012",
+			EventLog:    eventlog.Log{typedAt("abcdefghijklmnopqrst", 100), &eventlog.RawPaste{Content: "012"}},
+		}, false},
+		{"paste of a small share", largePaste, storage.Question{
+			FinalAnswer: "abcdefghijklmnopqrstuvwxyz0123456789",
+			EventLog:    eventlog.Log{typedAt("abcdefghijklmnopqrstuvwxyz", 100), &eventlog.RawPaste{Content: "0123456789"}},
+		}, false},
+
+		{"uniform intervals", uniform, storage.Question{
+			FinalAnswer: "abcdefghijkl",
+			EventLog:    eventlog.Log{typedAt("abcdefghijkl", 120)},
+		}, true},
+		{"uneven intervals", uniform, storage.Question{FinalAnswer: "abcdefghijkl", EventLog: uneven}, false},
+		{"too few keystrokes", uniform, storage.Question{
+			FinalAnswer: "abc",
+			EventLog:    eventlog.Log{typedAt("abc", 120)},
+		}, false},
+
+		{"no corrections", noCorrections, storage.Question{FinalAnswer: "abcdefghijkl", EventLog: uneven}, true},
+		{"corrected", noCorrections, storage.Question{
+			FinalAnswer: "abcdefghijkl",
+			EventLog:    eventlog.Log{typedAt("abcdefx\bghijkl", 100)},
+		}, false},
+		{"too little typed", noCorrections, storage.Question{
+			FinalAnswer: "abc",
+			EventLog:    eventlog.Log{typedAt("abc", 100)},
+		}, false},
+
+		{"idle then burst", idleBurst, storage.Question{
+			FinalAnswer: "a0123456789",
+			EventLog:    eventlog.Log{typedAt("a", 100), &eventlog.RawPaste{Content: "0123456789", LatencyMs: 90000}},
+		}, true},
+		{"idle then slow typing", idleBurst, storage.Question{
+			FinalAnswer: "a0123456789",
+			EventLog:    eventlog.Log{typedAt("a", 100), &eventlog.Compressed{String: "0123456789", LatencyMs: 90000, IntervalMs: 500}},
+		}, false},
+		{"burst without idling", idleBurst, storage.Question{
+			FinalAnswer: "a0123456789",
+			EventLog:    eventlog.Log{typedAt("a", 100), &eventlog.RawPaste{Content: "0123456789", LatencyMs: 5000}},
+		}, false},
+
+		{"mismatch", notReproducible, storage.Question{Integrity: eventlog.Integrity{Verdict: eventlog.VerdictMismatch}}, true},
+		{"unreplayable", notReproducible, storage.Question{Integrity: eventlog.Integrity{Verdict: eventlog.VerdictUnreplayable}}, true},
+		{"match", notReproducible, storage.Question{Integrity: eventlog.Integrity{Verdict: eventlog.VerdictMatch}}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.rules.Score(&tc.question)
+			want := storage.Suspicion{Rules: []string{}}
+			if tc.triggered {
+				want = storage.Suspicion{Score: 10, Rules: []string{"r"}}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Score() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestScoreSumsRulesInFileOrder(t *testing.T) {
+	rules := &Rules{Rules: []Rule{
+		{Name: "not-reproducible", Kind: KindNotReproducible, Weight: 20, Params: map[string]float64{}},
+		{Name: "large-paste", Kind: KindLargePaste, Weight: 30, Params: map[string]float64{"minChars": 40, "minShare": 0.5}},
+		{Name: "no-corrections", Kind: KindNoCorrections, Weight: 15, Params: map[string]float64{"minTypedChars": 1, "maxCorrectionRatio": 0}},
+	}}
+	question := storage.Question{
+		FinalAnswer: "abd",
+		EventLog:    eventlog.Log{typedAt("abc", 100)},
+		Integrity:   eventlog.Integrity{Verdict: eventlog.VerdictMismatch},
+	}
+
+	got := rules.Score(&question)
+	want := storage.Suspicion{Score: 35, Rules: []string{"not-reproducible", "no-corrections"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Score() = %+v, want %+v", got, want)
+	}
+	if rules.MaxScore() != 65 {
+		t.Errorf("MaxScore() = %v, want 65", rules.MaxScore())
+	}
+}
+
+// TestScoreUnscored tells an answer scored without rules from one that
+// triggered none: only the first is unscored
+func TestScoreUnscored(t *testing.T) {
+	question := storage.Question{FinalAnswer: "abc", EventLog: eventlog.Log{typedAt("abc", 100)}}
+
+	var none *Rules
+	if got := none.Score(&question); !got.Unscored || got.Score != 0 || len(got.Rules) != 0 {
+		t.Errorf("Score() with nil rules = %+v, want unscored", got)
+	}
+	if got := (&Rules{}).Score(&question); got.Unscored || got.Score != 0 || got.Rules == nil {
+		t.Errorf("Score() with no rules = %+v, want scored 0", got)
+	}
+}
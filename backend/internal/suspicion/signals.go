@@ -0,0 +1,86 @@
+package suspicion
+
+import (
+	"backend/internal/analytics"
+	"backend/internal/eventlog"
+	"backend/internal/storage"
+)
+
+// signals are the measurements of an answer the rules compare with their
+// thresholds
+type signals struct {
+	question *storage.Question
+	features analytics.Features
+	actions  []eventlog.Action
+	// largestPaste is the length of the longest paste, in characters
+	largestPaste int
+}
+
+// measure replays a question once for every rule
+func measure(question *storage.Question) *signals {
+	s := &signals{
+		question: question,
+		features: analytics.Compute(question.EventLog),
+		actions:  eventlog.Expand(question.EventLog),
+	}
+	for _, action := range s.actions {
+		if action.Op == eventlog.OpPaste {
+			if n := len([]rune(action.Text)); n > s.largestPaste {
+				s.largestPaste = n
+			}
+		}
+	}
+	return s
+}
+
+// triggers reports whether the answer breaks the rule
+func (s *signals) triggers(rule Rule) bool {
+	p := rule.Params
+	switch rule.Kind {
+	case KindLargePaste:
+		length := len([]rune(s.question.FinalAnswer))
+		return s.largestPaste > 0 && float64(s.largestPaste) >= p["minChars"] &&
+			length > 0 && float64(s.largestPaste)/float64(length) >= p["minShare"]
+
+	case KindUniformIntervals:
+		latency := s.features.Latency
+		return float64(latency.Count) >= p["minKeystrokes"] && latency.MeanMs > 0 &&
+			latency.StdDevMs/latency.MeanMs <= p["maxVariation"]
+
+	case KindNoCorrections:
+		return float64(s.features.TypedChars) >= p["minTypedChars"] &&
+			s.features.CorrectionRatio <= p["maxCorrectionRatio"]
+
+	case KindIdleBurst:
+		return s.idleBurst(p["minIdleMs"], p["minBurstChars"], p["burstMs"])
+
+	case KindNotReproducible:
+		verdict := s.question.Integrity.Verdict
+		return verdict == eventlog.VerdictMismatch || verdict == eventlog.VerdictUnreplayable
+	}
+	return false
+}
+
+// idleBurst reports whether a pause of at least minIdle is followed by at
+// least minChars characters typed or pasted within burst milliseconds
+func (s *signals) idleBurst(minIdle, minChars, burst float64) bool {
+	for i := 1; i < len(s.actions); i++ {
+		if s.actions[i].At-s.actions[i-1].At < minIdle {
+			continue
+		}
+		start := s.actions[i].At
+		chars := 0
+		for _, action := range s.actions[i:] {
+			if action.At-start > burst {
+				break
+			}
+			if action.Op == eventlog.OpInsert || action.Op == eventlog.OpPaste {
+				chars += len([]rune(action.Text))
+			}
+		}
+		if float64(chars) >= minChars {
+			return true
+		}
+	}
+	return false
+}
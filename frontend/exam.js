@@ -1,5 +1,6 @@
 // Global state
 let selectedQuestion = null
+let examSession = null    // Session started by the server (token, examId, studentId)
 let isSubmitted = false  // Track if already submitted
 
 // Global state for event capture
@@ -9,23 +10,67 @@ const captureData = {
   lastSelection: { start: 0, end: 0 }  // Track last selection state
 }
 
-// Load and select random question on page load
-async function loadRandomQuestion() {
+// sessionStorage key remembering the exam session across reloads
+const EXAM_SESSION_KEY = 'drkka_exam_session'
+
+// How often typed events are autosaved to the server
+const AUTOSAVE_INTERVAL_MS = 5000
+
+// Autosave state: events up to rawEvents[sent] are stored on the server
+// as chunks 1 .. nextSeq - 1. A chunk is kept in inflight until the server
+// acknowledges it, so a retry resends exactly the same chunk.
+const autosave = {
+  sent: 0,
+  nextSeq: 1,
+  lastTimestamp: null,   // Timestamp of the last event sent
+  inflight: null,        // { seq, events, count, lastTimestamp }
+  busy: null,            // Promise of the running flush
+  resumed: false,        // Answer restored from an earlier page load
+  disabled: false        // Server refused a chunk; submit the full log instead
+}
+
+// Resume the exam session of this tab, so reloading shows the same
+// question, or start one on page load; the server assigns the question
+async function startExamSession() {
   try {
-    const response = await fetch('questions.json')
-    const questions = await response.json()  // Array of questions
+    examSession = await resumeExamSession()
+    if (!examSession) {
+      const response = await fetch('/exam-sessions', {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json'
+        },
+        body: JSON.stringify({ examId: DEFAULT_EXAM_ID })
+      })
+      if (response.status === 401) {
+        // Students sign in before starting when AUTH_STUDENT_LOGIN is set
+        window.location.href = 'login.html?next=exam.html'
+        return
+      }
+      if (!response.ok) {
+        throw new Error('Server returned error: ' + response.status)
+      }
+      examSession = await response.json()
+      sessionStorage.setItem(EXAM_SESSION_KEY, JSON.stringify({
+        sessionId: examSession.sessionId,
+        token: examSession.token
+      }))
+    }
 
-    // Select random question
-    const randomIndex = Math.floor(Math.random() * questions.length)
+    const question = examSession.questions[0]
     selectedQuestion = {
-      index: randomIndex,
-      title: questions[randomIndex].question_title,
-      text: questions[randomIndex].question  // Use 'question' field only
+      index: question.questionIndex,
+      title: question.questionTitle,
+      text: question.question  // Use 'question' field only
     }
 
     // Display only the question text
     document.getElementById('question-text').textContent = selectedQuestion.text
 
+    // Continue from the autosaved answer, then keep autosaving
+    await restoreAutosave()
+    setInterval(flushAutosave, AUTOSAVE_INTERVAL_MS)
+
   } catch (error) {
     console.error('Error loading questions:', error)
     // Show error message with retry option
@@ -35,7 +80,7 @@ async function loadRandomQuestion() {
         ⚠️ Failed to load question: ${error.message}
       </div>
       <button
-        onclick="loadRandomQuestion()"
+        onclick="startExamSession()"
         class="px-4 py-2 bg-blue-500 text-white rounded hover:bg-blue-600 transition-colors"
       >
         🔄 Retry Loading Question
@@ -44,6 +89,111 @@ async function loadRandomQuestion() {
   }
 }
 
+// Fetch the remembered exam session, or return null if there is none or
+// it can no longer be used (e.g. it expired)
+async function resumeExamSession() {
+  const saved = JSON.parse(sessionStorage.getItem(EXAM_SESSION_KEY) || 'null')
+  if (!saved) {
+    return null
+  }
+
+  const response = await fetch('/exam-sessions/' + encodeURIComponent(saved.sessionId), {
+    headers: { 'X-Exam-Session': saved.token }
+  })
+  if (!response.ok) {
+    sessionStorage.removeItem(EXAM_SESSION_KEY)
+    return null
+  }
+  const session = await response.json()
+  session.token = saved.token
+  return session
+}
+
+// Restore the answer autosaved for this exam session before a reload, as
+// the server replays it, and continue numbering chunks after it
+async function restoreAutosave() {
+  const response = await fetch('/exam-sessions/' + encodeURIComponent(examSession.sessionId) + '/autosave', {
+    headers: { 'X-Exam-Session': examSession.token }
+  })
+  if (!response.ok) {
+    return
+  }
+  const saved = (await response.json()).questions.find(q => q.questionIndex === selectedQuestion.index)
+  if (!saved || saved.eventCount === 0) {
+    return
+  }
+
+  const answerField = document.getElementById('answer-field')
+  answerField.value = saved.text
+  answerField.setSelectionRange(saved.selectionStart, saved.selectionEnd)
+  captureData.lastSelection = { start: saved.selectionStart, end: saved.selectionEnd }
+  captureData.startTime_ms = performance.now()
+
+  autosave.nextSeq = saved.nextSeq
+  autosave.lastTimestamp = performance.now()  // Time since the reload counts as a pause
+  autosave.resumed = true
+}
+
+// Send the events typed since the last chunk; returns whether every
+// captured event is stored on the server
+function flushAutosave(options = {}) {
+  if (!autosave.busy) {
+    autosave.busy = sendChunk(options).finally(() => { autosave.busy = null })
+  }
+  return autosave.busy
+}
+
+// Send the pending chunk, or build one from the unsent events
+async function sendChunk({ keepalive = false } = {}) {
+  if (!examSession || !selectedQuestion || isSubmitted || autosave.disabled) {
+    return false
+  }
+
+  if (!autosave.inflight) {
+    const events = captureData.rawEvents.slice(autosave.sent)
+    if (events.length === 0) {
+      return true
+    }
+    autosave.inflight = {
+      seq: autosave.nextSeq,
+      events: compressEvents(events, autosave.lastTimestamp),
+      count: events.length,
+      lastTimestamp: events[events.length - 1].timestamp
+    }
+  }
+  const chunk = autosave.inflight
+
+  try {
+    const response = await fetch('/exam-sessions/' + encodeURIComponent(examSession.sessionId) + '/chunks', {
+      method: 'POST',
+      keepalive: keepalive,
+      headers: {
+        'Content-Type': 'application/json',
+        'X-Exam-Session': examSession.token
+      },
+      body: JSON.stringify({ questionIndex: selectedQuestion.index, seq: chunk.seq, events: chunk.events })
+    })
+    if (response.status === 409 || response.status === 403) {
+      // Out of step with the server or the session ended: stop autosaving
+      console.warn('Autosave stopped:', (await response.text()).trim())
+      autosave.disabled = true
+      return false
+    }
+    if (!response.ok) {
+      return false  // Retried with the same chunk on the next flush
+    }
+  } catch (error) {
+    console.warn('Autosave failed, retrying later:', error)
+    return false
+  }
+
+  autosave.sent += chunk.count
+  autosave.nextSeq = chunk.seq + 1
+  autosave.lastTimestamp = chunk.lastTimestamp
+  autosave.inflight = null
+  return autosave.sent === captureData.rawEvents.length || sendChunk({ keepalive })
+}
+
 // Set start time on first interaction
 function setStartTime() {
   if (!captureData.startTime_ms) {
@@ -78,7 +228,7 @@ function handleKeydown(e) {
       key: key,
       timestamp: now
     })
-  } else if (['Backspace', 'Delete', 'Enter', 'ArrowLeft', 'ArrowRight', 'ArrowUp', 'ArrowDown'].includes(key)) {
+  } else if (['Backspace', 'Delete', 'Enter', 'ArrowLeft', 'ArrowRight', 'ArrowUp', 'ArrowDown', 'Home', 'End'].includes(key)) {
     // Special keys
     captureData.rawEvents.push({
       type: 'special',
@@ -156,7 +306,7 @@ async function handleSubmit(e) {
   }
 
   // Validate has events
-  if (captureData.rawEvents.length === 0) {
+  if (captureData.rawEvents.length === 0 && !autosave.resumed) {
     alert('No typing activity detected. Please type your answer.')
     answerField.focus()
     return
@@ -170,6 +320,14 @@ async function handleSubmit(e) {
   submitBtn.classList.add('opacity-50', 'cursor-not-allowed')
 
   try {
+    // Store the last events; the server then finalizes the answer from
+    // its autosave. An answer restored after a reload can only be
+    // finalized that way.
+    const autosaved = await flushAutosave()
+    if (!autosaved && autosave.resumed) {
+      throw new Error('Your answer could not be saved. Check your connection and try again.')
+    }
+
     // Call process_and_pack.js function
     const payload = processAndPack({
       rawEvents: captureData.rawEvents,
@@ -178,6 +336,8 @@ async function handleSubmit(e) {
       questionIndex: selectedQuestion.index,
       questionTitle: selectedQuestion.title,
       questionText: selectedQuestion.text,
+      examId: examSession.examId,
+      studentId: examSession.studentId,
       metadata: {
         studentName: nameInput.value.trim()
       }
@@ -187,13 +347,16 @@ async function handleSubmit(e) {
     const response = await fetch('/submit', {
       method: 'POST',
       headers: {
-        'Content-Type': 'application/json'
+        'Content-Type': 'application/json',
+        'X-Exam-Session': examSession.token
       },
-      body: JSON.stringify(payload)
+      body: JSON.stringify(autosaved ? withoutEventLog(payload) : payload)
     })
 
     if (!response.ok) {
-      throw new Error('Server returned error: ' + response.status)
+      // Session problems (e.g. an expired session) come back as text
+      const message = (await response.text()).trim()
+      throw new Error('Server returned error: ' + response.status + (message ? ' - ' + message : ''))
     }
 
     const result = await response.json()
@@ -225,6 +388,12 @@ async function handleSubmit(e) {
   }
 }
 
+// Drop the event log of an autosaved answer, so the server finalizes it
+// from the stored chunks
+function withoutEventLog(payload) {
+  return { ...payload, q1: { ...payload.q1, eventLog: [] } }
+}
+
 // Display JSON on page
 function displayJSON(payload) {
   const outputSection = document.getElementById('output-section')
@@ -258,13 +427,20 @@ window.addEventListener('DOMContentLoaded', () => {
   // Submit
   submitBtn.addEventListener('click', handleSubmit)
 
-  // Load question
-  loadRandomQuestion()
+  // Start the exam session and load its question
+  startExamSession()
+
+  // Save what was typed when the tab is hidden or closed
+  document.addEventListener('visibilitychange', () => {
+    if (document.visibilityState === 'hidden') {
+      flushAutosave({ keepalive: true })
+    }
+  })
 })
 
 // Warn user before leaving if they have unsaved work
 window.addEventListener('beforeunload', (e) => {
-  if (captureData.rawEvents.length > 0 && !isSubmitted) {
+  if (captureData.rawEvents.length > autosave.sent && !isSubmitted) {
     e.preventDefault()
     e.returnValue = ''
     return ''
@@ -242,7 +242,9 @@ function extractSegment(rawEvents, startIdx) {
 // }
 
 // NEW: Compress events based on threshold method
-function compressEvents(rawEvents) {
+// previousTimestamp is the timestamp of the event before rawEvents[0], when
+// compressing a log in chunks (autosave); the first latency is 0 without it
+function compressEvents(rawEvents, previousTimestamp = null) {
   const compressed = []
   let i = 0
 
@@ -250,8 +252,9 @@ function compressEvents(rawEvents) {
     const event = rawEvents[i]
 
     // Calculate latency from previous event
-    const latency_ms = i === 0 ? 0 :
-      Math.round(event.timestamp - rawEvents[i-1].timestamp)
+    const previous = i === 0 ? previousTimestamp : rawEvents[i-1].timestamp
+    const latency_ms = previous === null ? 0 :
+      Math.round(event.timestamp - previous)
 
     // Try to compress 'key' or compressible 'special' events
     if (event.type === 'key' || event.type === 'special') {
@@ -345,8 +348,8 @@ function processAndPack(data) {
 
   // 3. Build final JSON
   return {
-    examId: DEFAULT_EXAM_ID,
-    studentId: generateUUID(),
+    examId: data.examId || DEFAULT_EXAM_ID,
+    studentId: data.studentId || generateUUID(),
     submissionTime: new Date().toISOString(),
     metadata: data.metadata,
     q1: {
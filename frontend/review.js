@@ -19,6 +19,14 @@ const reviewState = {
 // JSON LOADING & VALIDATION
 // ============================================
 
+// Get the first question of a submission (questions array or legacy q1)
+function getPrimaryQuestion(submission) {
+  if (Array.isArray(submission.questions)) {
+    return submission.questions[0]
+  }
+  return submission.q1
+}
+
 function loadSubmission() {
   try {
     const jsonInput = document.getElementById('json-input').value.trim()
@@ -30,11 +38,12 @@ function loadSubmission() {
     const submission = JSON.parse(jsonInput)
 
     // Validate required fields
-    if (!submission.q1) {
-      throw new Error('Invalid submission format: missing q1')
+    const question = getPrimaryQuestion(submission)
+    if (!question) {
+      throw new Error('Invalid submission format: missing question')
     }
 
-    if (!submission.q1.eventLog || !Array.isArray(submission.q1.eventLog)) {
+    if (!question.eventLog || !Array.isArray(question.eventLog)) {
       throw new Error('Invalid submission format: missing or invalid eventLog')
     }
 
@@ -43,7 +52,7 @@ function loadSubmission() {
     }
 
     reviewState.submission = submission
-    reviewState.events = submission.q1.eventLog
+    reviewState.events = question.eventLog
 
     // Display submission info
     displaySubmissionInfo(submission)
@@ -63,6 +72,8 @@ function loadSubmission() {
 }
 
 function displaySubmissionInfo(submission) {
+  const question = getPrimaryQuestion(submission)
+
   // Helper to safely set text content
   const setTextContent = (id, value) => {
     const element = document.getElementById(id)
@@ -77,12 +88,12 @@ function displaySubmissionInfo(submission) {
   setTextContent('student-name', submission.metadata?.studentName || 'N/A')
   setTextContent('exam-id', submission.examId || 'N/A')
   setTextContent('submission-time', new Date(submission.submissionTime).toLocaleString() || 'N/A')
-  setTextContent('question-text', submission.q1?.question || 'N/A')
-  setTextContent('final-answer', submission.q1?.finalAnswer || '')
+  setTextContent('question-text', question?.question || 'N/A')
+  setTextContent('final-answer', question?.finalAnswer || '')
 
   // Calculate duration
-  if (submission.q1?.startTime_ms && submission.q1?.endTime_ms) {
-    const duration = submission.q1.endTime_ms - submission.q1.startTime_ms
+  if (question?.startTime_ms && question?.endTime_ms) {
+    const duration = question.endTime_ms - question.startTime_ms
     const seconds = Math.round(duration / 1000)
     setTextContent('duration', `${seconds} seconds`)
   } else {
@@ -308,6 +319,16 @@ function applySpecialKey(key) {
     case 'ArrowDown':
       // Complex to implement accurately, skip for now
       break
+
+    case 'Home':
+      reviewState.cursorPosition = text.lastIndexOf('\n', pos - 1) + 1
+      break
+
+    case 'End': {
+      const lineEnd = text.indexOf('\n', pos)
+      reviewState.cursorPosition = lineEnd === -1 ? text.length : lineEnd
+      break
+    }
   }
 }
 
@@ -520,11 +541,12 @@ function updatePlayPauseButton() {
 window.loadSubmissionData = function(submission) {
   try {
     // Validate required fields
-    if (!submission.q1) {
-      throw new Error('Invalid submission format: missing q1')
+    const question = getPrimaryQuestion(submission)
+    if (!question) {
+      throw new Error('Invalid submission format: missing question')
     }
 
-    if (!submission.q1.eventLog || !Array.isArray(submission.q1.eventLog)) {
+    if (!question.eventLog || !Array.isArray(question.eventLog)) {
       throw new Error('Invalid submission format: missing or invalid eventLog')
     }
 
@@ -533,7 +555,7 @@ window.loadSubmissionData = function(submission) {
     }
 
     reviewState.submission = submission
-    reviewState.events = submission.q1.eventLog
+    reviewState.events = question.eventLog
 
     // Display submission info (new review.html structure)
     displaySubmissionInfoNew(submission)
@@ -556,6 +578,8 @@ window.loadSubmissionData = function(submission) {
  * Display submission info for new review.html structure
  */
 window.displaySubmissionInfoNew = function(submission) {
+  const question = getPrimaryQuestion(submission)
+
   // Helper to safely set text content
   const setTextContent = (id, value) => {
     const element = document.getElementById(id)
@@ -571,15 +595,15 @@ window.displaySubmissionInfoNew = function(submission) {
   setTextContent('exam-id', submission.examId || 'N/A')
 
   // Question
-  setTextContent('question-title', submission.q1?.questionTitle || 'Question')
-  setTextContent('question-text', submission.q1?.question || 'N/A')
+  setTextContent('question-title', question?.questionTitle || 'Question')
+  setTextContent('question-text', question?.question || 'N/A')
 
   // Final answer
-  setTextContent('final-answer-text', submission.q1?.finalAnswer || '')
+  setTextContent('final-answer-text', question?.finalAnswer || '')
 
   // Calculate duration
-  if (submission.q1?.startTime_ms && submission.q1?.endTime_ms) {
-    const duration = submission.q1.endTime_ms - submission.q1.startTime_ms
+  if (question?.startTime_ms && question?.endTime_ms) {
+    const duration = question.endTime_ms - question.startTime_ms
     const seconds = Math.round(duration / 1000)
     setTextContent('duration', `${seconds} seconds`)
   } else {
@@ -587,7 +611,7 @@ window.displaySubmissionInfoNew = function(submission) {
   }
 
   // Event count
-  const eventCount = submission.q1?.eventLog ? submission.q1.eventLog.length : 0
+  const eventCount = question?.eventLog ? question.eventLog.length : 0
   setTextContent('event-count', String(eventCount))
 }
 